@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 var CurrentDatabase *sql.DB = nil
@@ -80,3 +82,30 @@ func QueryRowsFromFile(ctx context.Context, relativePath string, args ...any) (*
 	}
 	return CurrentDatabase.QueryContext(ctx, query, args...)
 }
+
+// RunMigrations executes every file in queries/migraciones in name order.
+// Today that only covers moving Pedido.entregado to Pedido.estado; later
+// schema changes (new Pedido columns, new tables) have no migration and need
+// init.sql. No record of applied migrations is kept, so every file runs again
+// on each call and has to stay idempotent forever.
+func RunMigrations(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join("queries", "migraciones"))
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".sql") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		if _, err := ExecFromFile(ctx, filepath.Join("migraciones", name)); err != nil {
+			return names[:i], fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,101 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	pageWidth  = 612 // US Letter, in points
+	pageHeight = 792
+	margin     = 50
+	fontSize   = 10
+	leading    = 14
+)
+
+// LinesPerPage is how many text lines fit on one page.
+const LinesPerPage = (pageHeight - 2*margin) / leading
+
+// WriteText writes a plain PDF document with the given lines in a monospaced
+// font, breaking into pages as needed. Characters outside Latin-1 are replaced
+// with '?', which covers Spanish text.
+func WriteText(w io.Writer, lines []string) error {
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+
+	pages := [][]string{}
+	for start := 0; start < len(lines); start += LinesPerPage {
+		end := min(start+LinesPerPage, len(lines))
+		pages = append(pages, lines[start:end])
+	}
+
+	// Object layout: 1 catalog, 2 page tree, 3 font, then a page and a content
+	// stream per page.
+	objects := []string{}
+	kids := []string{}
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 4+2*i))
+	}
+	objects = append(objects, "<< /Type /Catalog /Pages 2 0 R >>")
+	objects = append(objects, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		content := pageContent(page)
+		objects = append(objects, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*i))
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func pageContent(lines []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", fontSize, leading, margin, pageHeight-margin)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "(%s) '\n", escape(line))
+	}
+	b.WriteString("ET")
+	return b.String()
+}
+
+// escape converts a line to WinAnsi bytes and escapes PDF string delimiters.
+func escape(line string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimRight(line, "\r") {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r == '\t':
+			b.WriteString("    ")
+		case r < 32:
+			continue
+		case r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -5,7 +5,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"mime"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -20,6 +23,18 @@ import (
 const (
 	defaultServer = "localhost"
 	defaultDB     = "Tienda"
+	stockAlertLog = "alertas_stock.log"
+	consoleActor  = "consola"
+	invoiceDir    = "facturas"
+	mediaDir      = "medios"
+	wishlistLog   = "avisos_deseos.log"
+	// Carpeta donde quedan los correos de recordatorio de carritos
+	cartReminderDir = "recordatorios"
+
+	// Cada cuanto corren los jobs de fondo (precios programados, avisos, ...)
+	jobInterval = time.Minute
+	// Sin cambios por este tiempo, un carrito con articulos esta abandonado
+	abandonedCartIdle = 24 * time.Hour
 )
 
 const (
@@ -40,11 +55,72 @@ func main() {
 	internal.Check(err, "No se pudo inicializar la base de datos")
 	defer conn.Close()
 	db.SetDatabase(conn)
+	models.SetStockAlertSink(models.NewLogFileAlertSink(stockAlertLog))
+	models.SetPaymentGateway(models.NewFakeGateway())
+	models.RegisterCarrier(models.NewFakeCarrier())
+	models.SetBlobStore(models.NewLocalBlobStore(mediaDir))
+
+	models.SetWishlistSink(models.NewLogFileWishlistSink(wishlistLog))
+	models.SetCartReminderSink(models.NewEmailCartReminderSink(cartReminderDir))
+
+	skus := models.NewSKUManager(db.CurrentDatabase)
+	wishlists := models.NewListaDeseosManager(db.CurrentDatabase)
+	carts := models.NewCarritoManager(db.CurrentDatabase)
+	stopJobs := startBackgroundJobs(
+		backgroundJob{"precios programados", func(ctx context.Context) error {
+			_, err := skus.ApplyScheduledPrices(ctx)
+			return err
+		}},
+		// Despues de los precios, para avisar las bajas en la misma vuelta
+		backgroundJob{"avisos de listas de deseos", func(ctx context.Context) error {
+			_, err := wishlists.CheckNotifications(ctx)
+			return err
+		}},
+		backgroundJob{"carritos de invitado vencidos", func(ctx context.Context) error {
+			_, err := carts.ExpireGuestCarts(ctx)
+			return err
+		}},
+		backgroundJob{"recordatorios de carritos abandonados", func(ctx context.Context) error {
+			_, err := carts.RemindAbandoned(ctx, abandonedCartIdle)
+			return err
+		}},
+	)
+	defer stopJobs()
 
 	mainMenu()
 	fmt.Println("Hasta luego")
 }
 
+// backgroundJob es una tarea de mantenimiento que corre periodicamente.
+type backgroundJob struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// startBackgroundJobs corre los jobs en orden al iniciar y despues cada
+// jobInterval, hasta que se llame a la funcion devuelta. Los errores se
+// muestran por stderr y el job se reintenta en la siguiente vuelta.
+func startBackgroundJobs(jobs ...backgroundJob) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(jobInterval)
+		defer ticker.Stop()
+		for {
+			for _, job := range jobs {
+				if err := job.run(ctx); err != nil && ctx.Err() == nil {
+					fmt.Fprintf(os.Stderr, "%sJob %s: %v%s\n", colorRed, job.name, err, colorReset)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return cancel
+}
+
 func printBanner() {
 	fmt.Println(string(colorCyan) + "╔══════════════════════════════════════════════╗")
 	fmt.Println("║            TIENDA ONLINE CONSOLE             ║")
@@ -107,8 +183,18 @@ func mainMenu() {
 		fmt.Println("[8] Direcciones")
 		fmt.Println("[9] Pedidos")
 		fmt.Println("[10] Devoluciones")
+		fmt.Println("[11] Almacenes")
+		fmt.Println("[12] Pagos")
+		fmt.Println("[13] Facturas")
+		fmt.Println("[14] Impuestos")
+		fmt.Println("[15] Envios")
+		fmt.Println("[16] Cupones")
+		fmt.Println("[17] Promociones")
+		fmt.Println("[18] Imagenes de productos")
+		fmt.Println("[19] Listas de deseos")
 		fmt.Println("[I] Re-ejecutar init.sql")
 		fmt.Println("[D] Insertar datos de prueba (init_data.sql)")
+		fmt.Println("[M] Migrar Pedido.entregado a estado (queries/migraciones)")
 		fmt.Println("[Q] Salir")
 
 		choice := readLine("Elige una opcion: ")
@@ -133,10 +219,30 @@ func mainMenu() {
 			menuPedidos()
 		case "10":
 			menuDevoluciones()
+		case "11":
+			menuAlmacenes()
+		case "12":
+			menuPagos()
+		case "13":
+			menuFacturas()
+		case "14":
+			menuImpuestos()
+		case "15":
+			menuEnvios()
+		case "16":
+			menuCupones()
+		case "17":
+			menuPromociones()
+		case "18":
+			menuImagenes()
+		case "19":
+			menuListasDeseos()
 		case "i":
 			runInit()
 		case "d":
 			runInitData()
+		case "m":
+			runMigrations()
 		case "q":
 			return
 		default:
@@ -163,6 +269,18 @@ func runInitData() {
 	}
 }
 
+func runMigrations() {
+	applied, err := db.RunMigrations(context.Background())
+	for _, name := range applied {
+		fmt.Printf("- %s\n", name)
+	}
+	if err != nil {
+		fmt.Printf("%sError: %v%s\n", colorRed, err, colorReset)
+	} else {
+		fmt.Printf("%sMigraciones aplicadas.%s\n", colorGreen, colorReset)
+	}
+}
+
 // ===== Menus por entidad =====
 
 func menuClientes() {
@@ -175,6 +293,8 @@ func menuClientes() {
 		fmt.Println("[4] Actualizar")
 		fmt.Println("[5] Cambiar contraseña")
 		fmt.Println("[6] Eliminar")
+		fmt.Println("[7] Iniciar sesion")
+		fmt.Println("[8] Desbloquear acceso")
 		fmt.Println("[B] Volver")
 		c := readLine("Opcion: ")
 		switch strings.ToLower(c) {
@@ -212,6 +332,30 @@ func menuClientes() {
 			if confirm("¿Seguro? (s/N): ") {
 				handleErr(m.Delete(context.Background(), id))
 			}
+		case "7":
+			login := readLine("Correo o telefono: ")
+			pass := readLine("Contraseña: ")
+			client, err := m.Authenticate(context.Background(), login, pass)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("%sBienvenido, %s%s\n", colorGreen, client.Nombre, colorReset)
+			// El carrito que armo como invitado pasa a su cuenta
+			token := readLine("Token de carrito de invitado (opcional): ")
+			if token == "" {
+				break
+			}
+			adjusted, err := models.NewCarritoManager(db.CurrentDatabase).MergeGuest(context.Background(), token, client.IdUsuario)
+			if handleErr(err) {
+				break
+			}
+			if len(adjusted) > 0 {
+				fmt.Println("Lineas ajustadas por stock o maximo por linea:")
+				internal.ListItems(adjusted)
+			}
+		case "8":
+			id := readInt("ID: ")
+			handleErr(m.Unlock(context.Background(), id))
 		case "b":
 			return
 		default:
@@ -229,6 +373,9 @@ func menuCategorias() {
 		fmt.Println("[3] Crear")
 		fmt.Println("[4] Actualizar")
 		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Arbol")
+		fmt.Println("[7] Mover")
+		fmt.Println("[8] Productos (incluye subcategorias)")
 		fmt.Println("[B] Volver")
 		c := readLine("Opcion: ")
 		switch strings.ToLower(c) {
@@ -245,18 +392,41 @@ func menuCategorias() {
 				break
 			}
 			fmt.Printf("%+v\n", item)
+			if path, err := m.Breadcrumb(context.Background(), id); err == nil {
+				fmt.Println("Ruta:", path)
+			}
 		case "3":
 			name := readLine("Nombre: ")
-			handleErr(m.Create(context.Background(), name))
+			parent := readOptionalInt("ID Categoria padre (0 = raiz): ")
+			handleErr(m.Create(context.Background(), name, parent))
 		case "4":
 			id := readInt("ID: ")
 			name := readLine("Nombre: ")
 			handleErr(m.Update(context.Background(), id, name))
 		case "5":
 			id := readInt("ID: ")
-			if confirm("¿Seguro? (s/N): ") {
+			if confirm("Las subcategorias pasan al padre. ¿Seguro? (s/N): ") {
 				handleErr(m.Delete(context.Background(), id))
 			}
+		case "6":
+			items, err := m.Tree(context.Background())
+			if handleErr(err) {
+				break
+			}
+			for _, node := range items {
+				fmt.Println(node.String())
+			}
+		case "7":
+			id := readInt("ID: ")
+			parent := readOptionalInt("ID nuevo padre (0 = raiz): ")
+			handleErr(m.Move(context.Background(), id, parent))
+		case "8":
+			id := readInt("ID: ")
+			items, err := models.NewProductoManager(db.CurrentDatabase).ListByCategoria(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
 		case "b":
 			return
 		default:
@@ -267,6 +437,7 @@ func menuCategorias() {
 
 func menuProductos() {
 	m := models.NewProductoManager(db.CurrentDatabase)
+	attributes := models.NewProductoAtributoManager(db.CurrentDatabase)
 	for {
 		fmt.Println(colorCyan + "\n-- Productos --" + colorReset)
 		fmt.Println("[1] Listar")
@@ -274,6 +445,13 @@ func menuProductos() {
 		fmt.Println("[3] Crear")
 		fmt.Println("[4] Actualizar")
 		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Atributos de variante")
+		fmt.Println("[7] Agregar atributo")
+		fmt.Println("[8] Actualizar atributo")
+		fmt.Println("[9] Eliminar atributo")
+		fmt.Println("[10] Buscar")
+		fmt.Println("[11] Calificacion")
+		fmt.Println("[12] Recalcular calificaciones")
 		fmt.Println("[B] Volver")
 		c := readLine("Opcion: ")
 		switch strings.ToLower(c) {
@@ -304,6 +482,60 @@ func menuProductos() {
 			if confirm("¿Seguro? (s/N): ") {
 				handleErr(m.Delete(context.Background(), id))
 			}
+		case "6":
+			pid := readInt("ID Producto: ")
+			items, err := attributes.ListByProducto(context.Background(), pid)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "7":
+			pid := readInt("ID Producto: ")
+			name := readLine("Nombre (ej. Talla): ")
+			order := readOptionalInt("Orden en la etiqueta (vacio = 0): ")
+			handleErr(attributes.Create(context.Background(), pid, name, order))
+		case "8":
+			id := readInt("ID Atributo: ")
+			name := readLine("Nombre: ")
+			order := readOptionalInt("Orden en la etiqueta (vacio = 0): ")
+			handleErr(attributes.Update(context.Background(), id, name, order))
+		case "9":
+			id := readInt("ID Atributo: ")
+			if confirm("Se borra el valor en todos los SKUs. ¿Seguro? (s/N): ") {
+				handleErr(attributes.Delete(context.Background(), id))
+			}
+		case "10":
+			filter := models.FiltroBusqueda{
+				Texto:           readLine("Texto (vacio = todo): "),
+				IdCategoria:     readOptionalInt("ID Categoria (vacio = todas): "),
+				PrecioMin:       readOptionalFloat("Precio minimo (vacio = sin minimo): "),
+				PrecioMax:       readOptionalFloat("Precio maximo (vacio = sin maximo): "),
+				SoloEnStock:     confirm("¿Solo con stock? (s/N): "),
+				CalificacionMin: readOptionalFloat("Calificacion minima 1-5 (vacio = cualquiera): "),
+				Orden:           readLine("Orden (relevancia/precio/precio_desc/calificacion/resenas): "),
+			}
+			items, err := m.Search(context.Background(), filter)
+			if handleErr(err) {
+				break
+			}
+			if len(items) == 0 {
+				fmt.Println("(sin resultados)")
+				break
+			}
+			internal.ListItems(items)
+		case "11":
+			id := readInt("ID: ")
+			item, err := m.Rating(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			fmt.Println(item.String())
+		case "12":
+			n, err := m.RebuildRatings(context.Background())
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Calificaciones recalculadas para %d productos\n", n)
 		case "b":
 			return
 		default:
@@ -321,6 +553,18 @@ func menuSKUs() {
 		fmt.Println("[3] Crear")
 		fmt.Println("[4] Actualizar")
 		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Punto de reorden")
+		fmt.Println("[7] Reporte de stock bajo")
+		fmt.Println("[8] Peso")
+		fmt.Println("[9] Buscar por codigo")
+		fmt.Println("[10] Buscar por codigo de barras")
+		fmt.Println("[11] Valores de atributos")
+		fmt.Println("[12] Fijar valor de atributo")
+		fmt.Println("[13] Historial de precios")
+		fmt.Println("[14] Precio en una fecha")
+		fmt.Println("[15] Programar precio")
+		fmt.Println("[16] Cancelar precio programado")
+		fmt.Println("[17] Aplicar precios programados")
 		fmt.Println("[B] Volver")
 		c := readLine("Opcion: ")
 		switch strings.ToLower(c) {
@@ -339,20 +583,98 @@ func menuSKUs() {
 			fmt.Printf("%+v\n", item)
 		case "3":
 			pid := readInt("ID Producto: ")
+			code := readLine("Codigo: ")
 			price := readFloat("Precio: ")
-			stock := readInt("Stock: ")
-			handleErr(m.Create(context.Background(), pid, price, stock))
+			stock := readInt("Stock inicial: ")
+			barcode := readLine("Codigo de barras EAN/UPC (vacio = ninguno): ")
+			handleErr(m.Create(context.Background(), pid, code, price, stock, barcode))
 		case "4":
 			id := readInt("ID: ")
 			pid := readInt("ID Producto: ")
+			code := readLine("Codigo: ")
 			price := readFloat("Precio: ")
-			stock := readInt("Stock: ")
-			handleErr(m.Update(context.Background(), id, pid, price, stock))
+			barcode := readLine("Codigo de barras EAN/UPC (vacio = ninguno): ")
+			handleErr(m.Update(context.Background(), id, pid, code, price, barcode))
 		case "5":
 			id := readInt("ID: ")
 			if confirm("¿Seguro? (s/N): ") {
 				handleErr(m.Delete(context.Background(), id))
 			}
+		case "6":
+			id := readInt("ID: ")
+			point := readInt("Punto de reorden (0 desactiva): ")
+			qty := readInt("Cantidad a reabastecer: ")
+			handleErr(m.SetReorder(context.Background(), id, point, qty))
+		case "7":
+			items, err := m.ListLowStock(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "8":
+			id := readInt("ID: ")
+			kg := readFloat("Peso por unidad (kg): ")
+			handleErr(m.SetWeight(context.Background(), id, kg))
+		case "9":
+			item, err := m.GetByCode(context.Background(), readLine("Codigo: "))
+			if handleErr(err) {
+				break
+			}
+			fmt.Println(item.String())
+		case "10":
+			item, err := m.GetByBarcode(context.Background(), readLine("Codigo de barras: "))
+			if handleErr(err) {
+				break
+			}
+			fmt.Println(item.String())
+		case "11":
+			id := readInt("ID: ")
+			items, err := models.NewProductoAtributoManager(db.CurrentDatabase).Values(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "12":
+			id := readInt("ID: ")
+			attributeId := readInt("ID Atributo: ")
+			value := readLine("Valor (vacio lo quita): ")
+			handleErr(models.NewProductoAtributoManager(db.CurrentDatabase).SetValue(context.Background(), id, attributeId, value))
+		case "13":
+			id := readInt("ID: ")
+			items, err := m.PriceHistory(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "14":
+			id := readInt("ID: ")
+			at := readOptionalDateTime("Fecha (YYYY-MM-DD HH:MM, vacio = ahora): ")
+			if !at.Valid {
+				at.Time = time.Now()
+			}
+			item, err := m.PriceAt(context.Background(), id, at.Time)
+			if handleErr(err) {
+				break
+			}
+			fmt.Println(item.String())
+		case "15":
+			id := readInt("ID: ")
+			price := readFloat("Precio: ")
+			from := readOptionalDateTime("Desde (YYYY-MM-DD HH:MM, vacio = ahora): ")
+			priceId, err := m.SchedulePrice(context.Background(), id, price, from.Time)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Precio #%d registrado\n", priceId)
+		case "16":
+			id := readInt("ID Precio: ")
+			handleErr(m.CancelScheduledPrice(context.Background(), id))
+		case "17":
+			n, err := m.ApplyScheduledPrices(context.Background())
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("%d SKUs con precio actualizado\n", n)
 		case "b":
 			return
 		default:
@@ -371,6 +693,20 @@ func menuCarritos() {
 		fmt.Println("[3] Crear")
 		fmt.Println("[4] Actualizar")
 		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Aplicar cupon")
+		fmt.Println("[7] Quitar cupon")
+		fmt.Println("[8] Promociones aplicables")
+		fmt.Println("[9] Agregar SKU al carrito de un cliente")
+		fmt.Println("[10] Cambiar cantidad de un SKU")
+		fmt.Println("[11] Quitar SKU")
+		fmt.Println("[12] Vaciar carrito de un cliente")
+		fmt.Println("[13] Crear carrito de invitado")
+		fmt.Println("[14] Agregar SKU a un carrito de invitado")
+		fmt.Println("[15] Pasar carrito de invitado a un cliente")
+		fmt.Println("[16] Borrar carritos de invitado vencidos")
+		fmt.Println("[17] Enviar recordatorios de carritos abandonados")
+		fmt.Println("[18] Recordatorios de un carrito")
+		fmt.Println("[19] Recuperacion de carritos abandonados")
 		fmt.Println("[B] Volver")
 		c := readLine("Opcion: ")
 		switch strings.ToLower(c) {
@@ -406,20 +742,11 @@ func menuCarritos() {
 				break
 			}
 			fmt.Println(item.String())
-			if confirm("Mostrar detalles de este carrito? (s/N): ") {
-				details, err := detailManager.ListByCarrito(context.Background(), item.IdCarrito)
-				if err != nil {
-					fmt.Printf("%sError: %v%s\n", colorRed, err, colorReset)
-					break
-				}
-				if len(details) == 0 {
-					fmt.Println("(sin detalles)")
-				} else {
-					for _, d := range details {
-						fmt.Printf("- %s\n", d.String())
-					}
-				}
+			totals, err := m.Totals(context.Background(), item.IdCarrito)
+			if handleErr(err) {
+				break
 			}
+			printCartTotals(totals)
 		case "3":
 			uid := readInt("ID Usuario: ")
 			handleErr(m.Create(context.Background(), uid))
@@ -432,6 +759,107 @@ func menuCarritos() {
 			if confirm("¿Seguro? (s/N): ") {
 				handleErr(m.Delete(context.Background(), id))
 			}
+		case "6":
+			id := readInt("ID: ")
+			code := readLine("Codigo: ")
+			discount, err := m.ApplyCoupon(context.Background(), id, code)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Descuento actual: %.2f\n", discount)
+		case "7":
+			id := readInt("ID: ")
+			handleErr(m.RemoveCoupon(context.Background(), id))
+		case "8":
+			id := readInt("ID: ")
+			applied, err := models.NewPromocionManager(db.CurrentDatabase).Evaluate(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			if len(applied) == 0 {
+				fmt.Println("(ninguna promocion aplica)")
+				break
+			}
+			internal.ListItems(applied)
+		case "9":
+			uid := readInt("ID Usuario: ")
+			sku := readInt("ID SKU: ")
+			qty := readInt("Cantidad: ")
+			total, err := m.AddItem(context.Background(), uid, sku, qty)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Cantidad en el carrito: %d\n", total)
+		case "10":
+			uid := readInt("ID Usuario: ")
+			sku := readInt("ID SKU: ")
+			qty := readInt("Cantidad (0 lo quita): ")
+			handleErr(m.SetQuantity(context.Background(), uid, sku, qty))
+		case "11":
+			uid := readInt("ID Usuario: ")
+			sku := readInt("ID SKU: ")
+			handleErr(m.RemoveItem(context.Background(), uid, sku))
+		case "12":
+			uid := readInt("ID Usuario: ")
+			if confirm("¿Seguro? (s/N): ") {
+				handleErr(m.Clear(context.Background(), uid))
+			}
+		case "13":
+			token, err := m.CreateGuest(context.Background())
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Token: %s\n", token)
+		case "14":
+			token := readLine("Token: ")
+			sku := readInt("ID SKU: ")
+			qty := readInt("Cantidad: ")
+			total, err := m.AddGuestItem(context.Background(), token, sku, qty)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Cantidad en el carrito: %d\n", total)
+		case "15":
+			token := readLine("Token: ")
+			uid := readInt("ID Usuario: ")
+			adjusted, err := m.MergeGuest(context.Background(), token, uid)
+			if handleErr(err) {
+				break
+			}
+			if len(adjusted) > 0 {
+				fmt.Println("Lineas ajustadas por stock o maximo por linea:")
+				internal.ListItems(adjusted)
+			}
+			fmt.Println("Carrito de invitado pasado al cliente")
+		case "16":
+			n, err := m.ExpireGuestCarts(context.Background())
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("%d carritos de invitado borrados\n", n)
+		case "17":
+			n, err := m.RemindAbandoned(context.Background(), abandonedCartIdle)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("%d recordatorios enviados a %s\n", n, cartReminderDir)
+		case "18":
+			id := readInt("ID: ")
+			items, err := m.Reminders(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "19":
+			from := readDate("Desde (YYYY-MM-DD): ")
+			to := readDate("Hasta (YYYY-MM-DD): ")
+			report, err := m.RecoveryReport(context.Background(),
+				time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local),
+				time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, time.Local))
+			if handleErr(err) {
+				break
+			}
+			fmt.Println(report.String())
 		case "b":
 			return
 		default:
@@ -498,6 +926,10 @@ func menuResenas() {
 		fmt.Println("[3] Crear")
 		fmt.Println("[4] Actualizar")
 		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Cola de moderacion")
+		fmt.Println("[7] Aprobar")
+		fmt.Println("[8] Rechazar")
+		fmt.Println("[9] Publicadas de un producto")
 		fmt.Println("[B] Volver")
 		c := readLine("Opcion: ")
 		switch strings.ToLower(c) {
@@ -532,6 +964,22 @@ func menuResenas() {
 			if confirm("¿Seguro? (s/N): ") {
 				handleErr(m.Delete(context.Background(), id))
 			}
+		case "6":
+			moderateQueue(m)
+		case "7":
+			id := readInt("ID: ")
+			handleErr(m.Approve(context.Background(), id, consoleActor))
+		case "8":
+			id := readInt("ID: ")
+			reason := readLine("Motivo: ")
+			handleErr(m.Reject(context.Background(), id, reason, consoleActor))
+		case "9":
+			pid := readInt("ID Producto: ")
+			items, err := m.ListByProducto(context.Background(), pid)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
 		case "b":
 			return
 		default:
@@ -540,6 +988,29 @@ func menuResenas() {
 	}
 }
 
+// moderateQueue recorre las reseñas pendientes una por una.
+func moderateQueue(m *models.ResenaManager) {
+	items, err := m.ListPending(context.Background())
+	if handleErr(err) {
+		return
+	}
+	if len(items) == 0 {
+		fmt.Println("No hay reseñas pendientes")
+		return
+	}
+	for i, r := range items {
+		fmt.Printf("(%d/%d) %s\n", i+1, len(items), r.String())
+		switch strings.ToLower(readLine("[A]probar, [R]echazar, [S]altar, [T]erminar: ")) {
+		case "a":
+			handleErr(m.Approve(context.Background(), r.IdReseña, consoleActor))
+		case "r":
+			handleErr(m.Reject(context.Background(), r.IdReseña, readLine("Motivo: "), consoleActor))
+		case "t":
+			return
+		}
+	}
+}
+
 func menuDirecciones() {
 	m := models.NewDireccionManager(db.CurrentDatabase)
 	for {
@@ -567,15 +1038,17 @@ func menuDirecciones() {
 			fmt.Printf("%+v\n", item)
 		case "3":
 			uid := readInt("ID Usuario: ")
-			tipo := readLine("Tipo (Envio/Facturacion): ")
+			tipo := readLine("Tipo (Envío/Facturación): ")
 			detalle := readLine("Detalle (opcional): ")
-			handleErr(m.Create(context.Background(), uid, tipo, detalle))
+			region := readLine("Region (opcional): ")
+			handleErr(m.Create(context.Background(), uid, tipo, detalle, region))
 		case "4":
 			id := readInt("ID: ")
 			uid := readInt("ID Usuario: ")
-			tipo := readLine("Tipo (Envio/Facturacion): ")
+			tipo := readLine("Tipo (Envío/Facturación): ")
 			detalle := readLine("Detalle (opcional): ")
-			handleErr(m.Update(context.Background(), id, uid, tipo, detalle))
+			region := readLine("Region (opcional): ")
+			handleErr(m.Update(context.Background(), id, uid, tipo, detalle, region))
 		case "5":
 			id := readInt("ID: ")
 			if confirm("¿Seguro? (s/N): ") {
@@ -598,6 +1071,9 @@ func menuPedidos() {
 		fmt.Println("[3] Crear")
 		fmt.Println("[4] Actualizar")
 		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Cambiar estado")
+		fmt.Println("[7] Historial de estados")
+		fmt.Println("[8] Cotizar carrito")
 		fmt.Println("[B] Volver")
 		c := readLine("Opcion: ")
 		switch strings.ToLower(c) {
@@ -614,20 +1090,93 @@ func menuPedidos() {
 				break
 			}
 			fmt.Printf("%+v\n", item)
+			lines, err := m.Items(context.Background(), id)
+			if err != nil {
+				fmt.Printf("%sError cargando lineas: %v%s\n", colorRed, err, colorReset)
+				break
+			}
+			internal.ListItems(lines)
+			taxes, err := m.Taxes(context.Background(), id)
+			if err != nil {
+				fmt.Printf("%sError cargando impuestos: %v%s\n", colorRed, err, colorReset)
+				break
+			}
+			internal.ListItems(taxes)
+			promotions, err := m.Promotions(context.Background(), id)
+			if err != nil {
+				fmt.Printf("%sError cargando promociones: %v%s\n", colorRed, err, colorReset)
+				break
+			}
+			internal.ListItems(promotions)
+			shipments, err := models.NewEnvioManager(db.CurrentDatabase).ListByPedido(context.Background(), id)
+			if err != nil {
+				fmt.Printf("%sError cargando envios: %v%s\n", colorRed, err, colorReset)
+				break
+			}
+			internal.ListItems(shipments)
 		case "3":
 			uid := readInt("ID Usuario: ")
-			del := confirm("¿Entregado? (s/N): ")
-			handleErr(m.Create(context.Background(), uid, del))
+			ship := readInt("ID Direccion de envio: ")
+			bill := readOptionalInt("ID Direccion de facturacion (0 para ninguna): ")
+			method := readInt("ID Metodo de envio: ")
+			id, err := m.Create(context.Background(), uid, ship, bill, method, consoleActor)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Pedido #%d creado a partir del carrito\n", id)
 		case "4":
 			id := readInt("ID: ")
 			uid := readInt("ID Usuario: ")
-			del := confirm("¿Entregado? (s/N): ")
-			handleErr(m.Update(context.Background(), id, uid, del))
+			handleErr(m.Update(context.Background(), id, uid))
 		case "5":
 			id := readInt("ID: ")
 			if confirm("¿Seguro? (s/N): ") {
 				handleErr(m.Delete(context.Background(), id))
 			}
+		case "6":
+			id := readInt("ID: ")
+			item, err := m.Get(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			if item.Estado.Final() {
+				fmt.Printf("El pedido esta en %s y ya no puede cambiar\n", item.Estado)
+				break
+			}
+			fmt.Printf("Estado actual: %s. Siguientes: %v\n", item.Estado, item.Estado.Siguientes())
+			to, err := models.ParseEstadoPedido(readLine("Nuevo estado: "))
+			if handleErr(err) {
+				break
+			}
+			// Cancelar tambien libera el pago del pedido
+			if to == models.PedidoCancelado {
+				handleErr(m.Cancel(context.Background(), id, consoleActor))
+				break
+			}
+			handleErr(m.Transition(context.Background(), id, to, consoleActor))
+		case "7":
+			id := readInt("ID: ")
+			items, err := m.History(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "8":
+			uid := readInt("ID Usuario: ")
+			ship := readInt("ID Direccion de envio: ")
+			method := readInt("ID Metodo de envio: ")
+			quote, err := m.Quote(context.Background(), uid, ship, method)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(quote.Items)
+			fmt.Printf("Peso: %.3f kg\n", quote.PesoKg)
+			internal.ListItems(quote.Promociones)
+			if quote.Cupon != nil {
+				fmt.Printf("Cupon: %s (-%.2f)\n", quote.Cupon.Codigo, quote.DescuentoCupon)
+			}
+			internal.ListItems(quote.Impuestos.Desglose)
+			printTotals(quote.Totales, quote.Impuestos.Incluido)
 		case "b":
 			return
 		default:
@@ -642,9 +1191,11 @@ func menuDevoluciones() {
 		fmt.Println(colorCyan + "\n-- Devoluciones --" + colorReset)
 		fmt.Println("[1] Listar")
 		fmt.Println("[2] Ver por ID")
-		fmt.Println("[3] Crear")
-		fmt.Println("[4] Actualizar")
+		fmt.Println("[3] Solicitar")
+		fmt.Println("[4] Actualizar descripcion")
 		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Cambiar estado")
+		fmt.Println("[7] Historial de estados")
 		fmt.Println("[B] Volver")
 		c := readLine("Opcion: ")
 		switch strings.ToLower(c) {
@@ -661,33 +1212,806 @@ func menuDevoluciones() {
 				break
 			}
 			fmt.Printf("%+v\n", item)
+			lines, err := m.Lines(context.Background(), id)
+			if err != nil {
+				fmt.Printf("%sError cargando lineas: %v%s\n", colorRed, err, colorReset)
+				break
+			}
+			internal.ListItems(lines)
 		case "3":
 			oid := readInt("ID Pedido: ")
-			fecha := readDate("Fecha (YYYY-MM-DD): ")
-			estado := readLine("Estado (opcional): ")
-			desc := readLine("Descripcion (opcional): ")
-			res := readLine("Resolucion (opcional): ")
-			handleErr(m.Create(context.Background(), oid, fecha, estado, desc, res))
+			available, err := m.Returnable(context.Background(), oid)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(available)
+			lines := []models.LineaDevolucion{}
+			for {
+				lineId := readOptionalInt("ID Linea a devolver (vacio para terminar): ")
+				if lineId == 0 {
+					break
+				}
+				qty := readInt("Cantidad: ")
+				lines = append(lines, models.LineaDevolucion{IdPedidoDetalle: lineId, Cantidad: qty})
+			}
+			desc := readLine("Motivo (opcional): ")
+			id, err := m.Create(context.Background(), oid, lines, desc, consoleActor)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Devolucion #%d solicitada\n", id)
 		case "4":
 			id := readInt("ID: ")
-			oid := readInt("ID Pedido: ")
-			fecha := readDate("Fecha (YYYY-MM-DD): ")
-			estado := readLine("Estado (opcional): ")
 			desc := readLine("Descripcion (opcional): ")
-			res := readLine("Resolucion (opcional): ")
-			handleErr(m.Update(context.Background(), id, oid, fecha, estado, desc, res))
+			handleErr(m.Update(context.Background(), id, desc))
 		case "5":
 			id := readInt("ID: ")
 			if confirm("¿Seguro? (s/N): ") {
 				handleErr(m.Delete(context.Background(), id))
 			}
-		case "b":
-			return
-		default:
-			fmt.Println("Opcion no valida")
-		}
-	}
-}
+		case "6":
+			id := readInt("ID: ")
+			item, err := m.Get(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			if item.Estado.Final() {
+				fmt.Printf("La devolucion esta %s y ya no puede cambiar\n", item.Estado)
+				break
+			}
+			fmt.Printf("Estado actual: %s. Siguientes: %v\n", item.Estado, item.Estado.Siguientes())
+			to, err := models.ParseEstadoDevolucion(readLine("Nuevo estado: "))
+			if handleErr(err) {
+				break
+			}
+			note := readLine("Nota de resolucion (opcional salvo rechazo): ")
+			handleErr(m.Transition(context.Background(), id, to, note, consoleActor))
+		case "7":
+			id := readInt("ID: ")
+			items, err := m.History(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuAlmacenes() {
+	m := models.NewAlmacenManager(db.CurrentDatabase)
+	for {
+		fmt.Println(colorCyan + "\n-- Almacenes --" + colorReset)
+		fmt.Println("[1] Listar")
+		fmt.Println("[2] Ver por ID")
+		fmt.Println("[3] Crear")
+		fmt.Println("[4] Actualizar")
+		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Stock de un SKU por almacen")
+		fmt.Println("[7] Fijar stock de un SKU en un almacen")
+		fmt.Println("[8] Transferir stock entre almacenes")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			items, err := m.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			id := readInt("ID: ")
+			item, err := m.Get(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("%+v\n", item)
+		case "3":
+			name := readLine("Nombre: ")
+			priority := readInt("Prioridad (1 = primero): ")
+			handleErr(m.Create(context.Background(), name, priority))
+		case "4":
+			id := readInt("ID: ")
+			name := readLine("Nombre: ")
+			priority := readInt("Prioridad (1 = primero): ")
+			active := confirm("¿Activo? (s/N): ")
+			handleErr(m.Update(context.Background(), id, name, priority, active))
+		case "5":
+			id := readInt("ID: ")
+			if confirm("¿Seguro? (s/N): ") {
+				handleErr(m.Delete(context.Background(), id))
+			}
+		case "6":
+			sid := readInt("ID SKU: ")
+			items, err := m.ListStock(context.Background(), sid)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "7":
+			sid := readInt("ID SKU: ")
+			wid := readInt("ID Almacen: ")
+			stock := readInt("Stock: ")
+			handleErr(m.SetStock(context.Background(), sid, wid, stock))
+		case "8":
+			sid := readInt("ID SKU: ")
+			from := readInt("ID Almacen origen: ")
+			to := readInt("ID Almacen destino: ")
+			qty := readInt("Cantidad: ")
+			handleErr(m.Transfer(context.Background(), sid, from, to, qty))
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuPagos() {
+	m := models.NewPagoManager(db.CurrentDatabase)
+	for {
+		fmt.Println(colorCyan + "\n-- Pagos --" + colorReset)
+		fmt.Println("[1] Listar")
+		fmt.Println("[2] Ver por ID")
+		fmt.Println("[3] Pagos de un pedido")
+		fmt.Println("[4] Autorizar pago de un pedido")
+		fmt.Println("[5] Capturar")
+		fmt.Println("[6] Anular")
+		fmt.Println("[7] Reembolsar")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			items, err := m.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			id := readInt("ID: ")
+			item, err := m.Get(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("%+v\n", item)
+		case "3":
+			oid := readInt("ID Pedido: ")
+			items, err := m.ListByPedido(context.Background(), oid)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "4":
+			oid := readInt("ID Pedido: ")
+			token := readLine("Token del medio de pago: ")
+			id, err := m.Authorize(context.Background(), oid, token)
+			if id > 0 {
+				fmt.Printf("Pago #%d registrado\n", id)
+			}
+			handleErr(err)
+		case "5":
+			id := readInt("ID Pago: ")
+			handleErr(m.Capture(context.Background(), id, consoleActor))
+		case "6":
+			id := readInt("ID Pago: ")
+			if confirm("¿Seguro? (s/N): ") {
+				handleErr(m.Void(context.Background(), id))
+			}
+		case "7":
+			id := readInt("ID Pago: ")
+			amount := readFloat("Monto a reembolsar: ")
+			handleErr(m.Refund(context.Background(), id, amount))
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuFacturas() {
+	m := models.NewFacturaManager(db.CurrentDatabase)
+	for {
+		fmt.Println(colorCyan + "\n-- Facturas --" + colorReset)
+		fmt.Println("[1] Listar")
+		fmt.Println("[2] Ver por ID")
+		fmt.Println("[3] Emitir factura de un pedido")
+		fmt.Println("[4] Generar HTML y PDF")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			items, err := m.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			id := readInt("ID: ")
+			item, err := m.Get(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			fmt.Println(item.String())
+			lines, err := m.Lines(context.Background(), id)
+			if err != nil {
+				fmt.Printf("%sError cargando lineas: %v%s\n", colorRed, err, colorReset)
+				break
+			}
+			internal.ListItems(lines)
+		case "3":
+			oid := readInt("ID Pedido: ")
+			series := readLine(fmt.Sprintf("Serie (vacio = %s): ", models.DefaultInvoiceSeries))
+			id, err := m.Issue(context.Background(), oid, series)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Factura #%d emitida\n", id)
+		case "4":
+			id := readInt("ID: ")
+			htmlPath, pdfPath, err := m.Render(context.Background(), id, invoiceDir)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Generados %s y %s\n", htmlPath, pdfPath)
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuImpuestos() {
+	m := models.NewImpuestoManager(db.CurrentDatabase)
+	for {
+		mode := "sin impuesto (se suma al total)"
+		if models.PricesIncludeTax() {
+			mode = "con impuesto incluido"
+		}
+		fmt.Println(colorCyan + "\n-- Impuestos --" + colorReset)
+		fmt.Printf("Precios: %s\n", mode)
+		fmt.Println("[1] Listar reglas")
+		fmt.Println("[2] Crear regla")
+		fmt.Println("[3] Actualizar regla")
+		fmt.Println("[4] Eliminar regla")
+		fmt.Println("[5] Cambiar modo de precios")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			items, err := m.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			name := readLine("Nombre: ")
+			rate := readFloat("Tasa (0.13 = 13%): ")
+			cid := readOptionalInt("ID Categoria (vacio = todas): ")
+			region := readLine("Region (vacio = todas): ")
+			handleErr(m.Create(context.Background(), name, rate, cid, region))
+		case "3":
+			id := readInt("ID: ")
+			name := readLine("Nombre: ")
+			rate := readFloat("Tasa (0.13 = 13%): ")
+			cid := readOptionalInt("ID Categoria (vacio = todas): ")
+			region := readLine("Region (vacio = todas): ")
+			active := confirm("¿Activa? (s/N): ")
+			handleErr(m.Update(context.Background(), id, name, rate, cid, region, active))
+		case "4":
+			id := readInt("ID: ")
+			if confirm("¿Seguro? (s/N): ") {
+				handleErr(m.Delete(context.Background(), id))
+			}
+		case "5":
+			models.SetPricesIncludeTax(confirm("¿Los precios incluyen impuesto? (s/N): "))
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuEnvios() {
+	carriers := models.NewTransportistaManager(db.CurrentDatabase)
+	methods := models.NewMetodoEnvioManager(db.CurrentDatabase)
+	m := models.NewEnvioManager(db.CurrentDatabase)
+	for {
+		fmt.Println(colorCyan + "\n-- Envios --" + colorReset)
+		fmt.Println("[1] Listar transportistas")
+		fmt.Println("[2] Crear transportista")
+		fmt.Println("[3] Listar metodos")
+		fmt.Println("[4] Crear metodo")
+		fmt.Println("[5] Actualizar metodo")
+		fmt.Println("[6] Tarifas de un metodo")
+		fmt.Println("[7] Agregar tarifa")
+		fmt.Println("[8] Eliminar tarifa")
+		fmt.Println("[9] Zonas y regiones")
+		fmt.Println("[10] Crear zona")
+		fmt.Println("[11] Asignar region a zona")
+		fmt.Println("[12] Listar envios")
+		fmt.Println("[13] Generar guia de un pedido")
+		fmt.Println("[14] Rastrear envio")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			items, err := carriers.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			name := readLine("Nombre: ")
+			code := readLine("Codigo del integrador (ej. fake): ")
+			handleErr(carriers.Create(context.Background(), name, code))
+		case "3":
+			items, err := methods.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "4":
+			cid := readInt("ID Transportista: ")
+			name := readLine("Nombre: ")
+			days := readInt("Dias estimados: ")
+			free := readFloat("Envio gratis desde (0 = nunca): ")
+			handleErr(methods.Create(context.Background(), cid, name, days, free))
+		case "5":
+			id := readInt("ID: ")
+			cid := readInt("ID Transportista: ")
+			name := readLine("Nombre: ")
+			days := readInt("Dias estimados: ")
+			free := readFloat("Envio gratis desde (0 = nunca): ")
+			active := confirm("¿Activo? (s/N): ")
+			handleErr(methods.Update(context.Background(), id, cid, name, days, free, active))
+		case "6":
+			id := readInt("ID Metodo: ")
+			items, err := methods.Rates(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "7":
+			id := readInt("ID Metodo: ")
+			zone := readOptionalInt("ID Zona (vacio = todas): ")
+			minW := readFloat("Peso minimo (kg): ")
+			maxW := readFloat("Peso maximo (kg, 0 = sin limite): ")
+			minA := readFloat("Monto minimo: ")
+			maxA := readFloat("Monto maximo (0 = sin limite): ")
+			price := readFloat("Precio: ")
+			handleErr(methods.AddRate(context.Background(), id, zone, minW, maxW, minA, maxA, price))
+		case "8":
+			id := readInt("ID Tarifa: ")
+			if confirm("¿Seguro? (s/N): ") {
+				handleErr(methods.DeleteRate(context.Background(), id))
+			}
+		case "9":
+			zones, err := methods.ListZones(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(zones)
+			regions, err := methods.ListZoneRegions(context.Background())
+			if err != nil {
+				fmt.Printf("%sError cargando regiones: %v%s\n", colorRed, err, colorReset)
+				break
+			}
+			internal.ListItems(regions)
+		case "10":
+			handleErr(methods.CreateZone(context.Background(), readLine("Nombre: ")))
+		case "11":
+			zone := readInt("ID Zona: ")
+			region := readLine("Region: ")
+			handleErr(methods.AssignRegion(context.Background(), zone, region))
+		case "12":
+			items, err := m.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "13":
+			oid := readInt("ID Pedido: ")
+			id, err := m.Ship(context.Background(), oid, consoleActor)
+			if handleErr(err) {
+				break
+			}
+			item, err := m.Get(context.Background(), id)
+			if err == nil {
+				fmt.Println(item.String())
+			}
+		case "14":
+			id := readInt("ID Envio: ")
+			status, err := m.Track(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Estado: %s\n", status)
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuCupones() {
+	m := models.NewCuponManager(db.CurrentDatabase)
+	for {
+		fmt.Println(colorCyan + "\n-- Cupones --" + colorReset)
+		fmt.Println("[1] Listar")
+		fmt.Println("[2] Ver por ID")
+		fmt.Println("[3] Crear")
+		fmt.Println("[4] Actualizar")
+		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Historial de canjes")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			items, err := m.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			id := readInt("ID: ")
+			item, err := m.Get(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			fmt.Println(item.String())
+		case "3":
+			handleErr(m.Create(context.Background(), readCupon()))
+		case "4":
+			id := readInt("ID: ")
+			coupon := readCupon()
+			coupon.IdCupon = id
+			coupon.Activo = confirm("¿Activo? (s/N): ")
+			handleErr(m.Update(context.Background(), coupon))
+		case "5":
+			id := readInt("ID: ")
+			if confirm("¿Seguro? (s/N): ") {
+				handleErr(m.Delete(context.Background(), id))
+			}
+		case "6":
+			id := readInt("ID: ")
+			items, err := m.Redemptions(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuPromociones() {
+	m := models.NewPromocionManager(db.CurrentDatabase)
+	for {
+		fmt.Println(colorCyan + "\n-- Promociones --" + colorReset)
+		fmt.Println("[1] Listar")
+		fmt.Println("[2] Ver por ID")
+		fmt.Println("[3] Crear")
+		fmt.Println("[4] Actualizar")
+		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Escalones")
+		fmt.Println("[7] Reemplazar escalones")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			items, err := m.List(context.Background())
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			id := readInt("ID: ")
+			item, err := m.Get(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			fmt.Println(item.String())
+		case "3":
+			handleErr(m.Create(context.Background(), readPromocion()))
+		case "4":
+			id := readInt("ID: ")
+			promo := readPromocion()
+			promo.IdPromocion = id
+			promo.Activo = confirm("¿Activa? (s/N): ")
+			handleErr(m.Update(context.Background(), promo))
+		case "5":
+			id := readInt("ID: ")
+			if confirm("¿Seguro? (s/N): ") {
+				handleErr(m.Delete(context.Background(), id))
+			}
+		case "6":
+			id := readInt("ID: ")
+			items, err := m.Tiers(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "7":
+			id := readInt("ID: ")
+			tiers := []models.PromocionEscalon{}
+			for {
+				minimum := readLine("Monto minimo (vacio para terminar): ")
+				if minimum == "" {
+					break
+				}
+				amount, err := strconv.ParseFloat(minimum, 64)
+				if err != nil {
+					fmt.Println("Ingresa un numero valido")
+					continue
+				}
+				tiers = append(tiers, models.PromocionEscalon{MontoMinimo: amount, Porcentaje: readFloat("Porcentaje: ")})
+			}
+			handleErr(m.SetTiers(context.Background(), id, tiers))
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuImagenes() {
+	m := models.NewProductoImagenManager(db.CurrentDatabase)
+	for {
+		fmt.Println(colorCyan + "\n-- Imagenes de productos --" + colorReset)
+		fmt.Println("[1] Listar por producto")
+		fmt.Println("[2] Subir desde archivo")
+		fmt.Println("[3] Editar texto alternativo y orden")
+		fmt.Println("[4] Marcar como principal")
+		fmt.Println("[5] Eliminar")
+		fmt.Println("[6] Exportar a archivo")
+		fmt.Println("[7] Limpiar archivos huerfanos")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			pid := readInt("ID Producto: ")
+			items, err := m.ListByProducto(context.Background(), pid)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			pid := readInt("ID Producto: ")
+			path := readLine("Ruta del archivo: ")
+			data, err := os.ReadFile(path)
+			if handleErr(err) {
+				break
+			}
+			contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+			id, err := m.Upload(context.Background(), pid, contentType, data, readLine("Texto alternativo: "))
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Imagen #%d subida\n", id)
+		case "3":
+			id := readInt("ID: ")
+			alt := readLine("Texto alternativo: ")
+			order := readInt("Orden: ")
+			handleErr(m.Update(context.Background(), id, alt, order))
+		case "4":
+			id := readInt("ID: ")
+			handleErr(m.SetPrimary(context.Background(), id))
+		case "5":
+			id := readInt("ID: ")
+			if confirm("¿Seguro? (s/N): ") {
+				handleErr(m.Delete(context.Background(), id))
+			}
+		case "6":
+			id := readInt("ID: ")
+			thumbnail := confirm("¿Solo la miniatura? (s/N): ")
+			dest := readLine("Ruta de destino: ")
+			handleErr(exportImagen(m, id, thumbnail, dest))
+		case "7":
+			removed, err := m.CleanupOrphans(context.Background())
+			if handleErr(err) {
+				break
+			}
+			for _, key := range removed {
+				fmt.Printf("- %s\n", key)
+			}
+			fmt.Printf("%d archivos huerfanos eliminados\n", len(removed))
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func menuListasDeseos() {
+	m := models.NewListaDeseosManager(db.CurrentDatabase)
+	for {
+		fmt.Println(colorCyan + "\n-- Listas de deseos --" + colorReset)
+		fmt.Println("[1] Listas de un cliente")
+		fmt.Println("[2] Crear lista")
+		fmt.Println("[3] Renombrar lista")
+		fmt.Println("[4] Eliminar lista")
+		fmt.Println("[5] Ver items")
+		fmt.Println("[6] Agregar producto")
+		fmt.Println("[7] Agregar SKU")
+		fmt.Println("[8] Quitar item")
+		fmt.Println("[9] Pasar item al carrito")
+		fmt.Println("[10] Guardar linea del carrito para despues")
+		fmt.Println("[11] Revisar avisos ahora")
+		fmt.Println("[B] Volver")
+		c := readLine("Opcion: ")
+		switch strings.ToLower(c) {
+		case "1":
+			uid := readInt("ID Usuario: ")
+			items, err := m.ListByUser(context.Background(), uid)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "2":
+			uid := readInt("ID Usuario: ")
+			id, err := m.Create(context.Background(), uid, readLine("Nombre: "))
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("Lista #%d creada\n", id)
+		case "3":
+			id := readInt("ID Lista: ")
+			handleErr(m.Rename(context.Background(), id, readLine("Nombre: ")))
+		case "4":
+			id := readInt("ID Lista: ")
+			if confirm("Se borran todos sus items. ¿Seguro? (s/N): ") {
+				handleErr(m.Delete(context.Background(), id))
+			}
+		case "5":
+			id := readInt("ID Lista: ")
+			items, err := m.Items(context.Background(), id)
+			if handleErr(err) {
+				break
+			}
+			internal.ListItems(items)
+		case "6":
+			id := readInt("ID Lista: ")
+			pid := readInt("ID Producto: ")
+			handleErr(m.AddProduct(context.Background(), id, pid))
+		case "7":
+			id := readInt("ID Lista: ")
+			sid := readInt("ID SKU: ")
+			handleErr(m.AddSKU(context.Background(), id, sid))
+		case "8":
+			id := readInt("ID Item: ")
+			handleErr(m.RemoveItem(context.Background(), id))
+		case "9":
+			id := readInt("ID Item: ")
+			sid := readOptionalInt("ID SKU (vacio = el del item): ")
+			qty := readInt("Cantidad: ")
+			handleErr(m.MoveToCart(context.Background(), id, sid, qty))
+		case "10":
+			did := readInt("ID Detalle de carrito: ")
+			id := readInt("ID Lista: ")
+			handleErr(m.SaveForLater(context.Background(), did, id))
+		case "11":
+			n, err := m.CheckNotifications(context.Background())
+			if handleErr(err) {
+				break
+			}
+			fmt.Printf("%d avisos enviados a %s\n", n, wishlistLog)
+		case "b":
+			return
+		default:
+			fmt.Println("Opcion no valida")
+		}
+	}
+}
+
+func exportImagen(m *models.ProductoImagenManager, id int, thumbnail bool, dest string) error {
+	r, _, err := m.Open(context.Background(), id, thumbnail)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readPromocion pide solo los campos que usa el tipo elegido.
+func readPromocion() models.Promocion {
+	promo := models.Promocion{
+		Nombre:     readLine("Nombre: "),
+		Tipo:       readLine("Tipo (CompraXLlevaY/PorcentajeCategoria/Escalonada/PrecioFlash): "),
+		Prioridad:  readInt("Prioridad (menor se evalua primero): "),
+		Acumulable: confirm("¿Acumulable con otras promociones? (s/N): "),
+	}
+	optional := func(value int) sql.NullInt32 {
+		return sql.NullInt32{Int32: int32(value), Valid: value > 0}
+	}
+	switch strings.ToLower(promo.Tipo) {
+	case strings.ToLower(models.PromoCompraXLlevaY):
+		promo.IdSKU = optional(readInt("ID SKU: "))
+		promo.CantidadCompra = optional(readInt("Cantidad a comprar: "))
+		promo.CantidadGratis = optional(readInt("Cantidad gratis: "))
+	case strings.ToLower(models.PromoPorcentajeCategoria):
+		promo.IdCategoria = optional(readInt("ID Categoria: "))
+		promo.Porcentaje = sql.NullFloat64{Float64: readFloat("Porcentaje: "), Valid: true}
+	case strings.ToLower(models.PromoPrecioFlash):
+		promo.IdSKU = optional(readInt("ID SKU: "))
+		promo.PrecioEspecial = sql.NullFloat64{Float64: readFloat("Precio especial: "), Valid: true}
+	}
+	promo.VigenteDesde = readOptionalDate("Valida desde (YYYY-MM-DD, vacio = ya): ")
+	promo.VigenteHasta = readOptionalDate("Valida hasta (YYYY-MM-DD, vacio = sin vencimiento): ")
+	return promo
+}
+
+func readCupon() models.Cupon {
+	coupon := models.Cupon{
+		Codigo:      readLine("Codigo: "),
+		Tipo:        readLine("Tipo (Porcentaje/Monto): "),
+		Valor:       readFloat("Valor: "),
+		MontoMinimo: readFloat("Pedido minimo (0 = sin minimo): "),
+	}
+	if uses := readOptionalInt("Usos maximos (vacio = sin limite): "); uses > 0 {
+		coupon.UsosMaximos = sql.NullInt32{Int32: int32(uses), Valid: true}
+	}
+	if uses := readOptionalInt("Usos por cliente (vacio = sin limite): "); uses > 0 {
+		coupon.UsosPorCliente = sql.NullInt32{Int32: int32(uses), Valid: true}
+	}
+	coupon.VigenteDesde = readOptionalDate("Valido desde (YYYY-MM-DD, vacio = ya): ")
+	coupon.VigenteHasta = readOptionalDate("Valido hasta (YYYY-MM-DD, vacio = sin vencimiento): ")
+	if cid := readOptionalInt("ID Categoria (vacio = todas): "); cid > 0 {
+		coupon.IdCategoria = sql.NullInt32{Int32: int32(cid), Valid: true}
+	}
+	return coupon
+}
+
+func printTotals(t models.TotalesPedido, taxIncluded bool) {
+	fmt.Printf("Subtotal:  %10.2f\n", t.Subtotal)
+	fmt.Printf("Descuento: %10.2f\n", t.Descuento)
+	if taxIncluded {
+		fmt.Printf("Impuesto:  %10.2f (incluido)\n", t.Impuesto)
+	} else {
+		fmt.Printf("Impuesto:  %10.2f\n", t.Impuesto)
+	}
+	fmt.Printf("Envio:     %10.2f\n", t.Envio)
+	fmt.Printf("Total:     %10.2f\n", t.Total)
+}
+
+func printCartTotals(t *models.TotalesCarrito) {
+	if len(t.Lineas) == 0 {
+		fmt.Println("(carrito vacio)")
+		return
+	}
+	internal.ListItems(t.Lineas)
+	if problems := t.ConProblemas(); len(problems) > 0 {
+		fmt.Printf("%s%d linea(s) con problemas: revisar antes de comprar%s\n", colorRed, len(problems), colorReset)
+	}
+	internal.ListItems(t.Promociones)
+	if t.Cupon != nil {
+		fmt.Printf("Cupon: %s (-%.2f)\n", t.Cupon.Codigo, t.DescuentoCupon)
+	}
+	if t.AvisoCupon != "" {
+		fmt.Printf("%sCupon sin aplicar: %s%s\n", colorRed, t.AvisoCupon, colorReset)
+	}
+	internal.ListItems(t.Impuestos.Desglose)
+	printTotals(t.Totales, t.Impuestos.Incluido)
+}
 
 // ===== Helpers de entrada =====
 
@@ -742,6 +2066,21 @@ func readFloat(prompt string) float64 {
 	}
 }
 
+func readOptionalFloat(prompt string) float64 {
+	for {
+		val := readLine(prompt)
+		if val == "" {
+			return 0
+		}
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			fmt.Println("Ingresa un numero valido")
+			continue
+		}
+		return f
+	}
+}
+
 func readDate(prompt string) time.Time {
 	for {
 		val := readLine(prompt)
@@ -754,6 +2093,36 @@ func readDate(prompt string) time.Time {
 	}
 }
 
+func readOptionalDate(prompt string) sql.NullTime {
+	for {
+		val := readLine(prompt)
+		if val == "" {
+			return sql.NullTime{}
+		}
+		t, err := time.ParseInLocation("2006-01-02", val, time.Local)
+		if err != nil {
+			fmt.Println("Formato invalido, usa YYYY-MM-DD")
+			continue
+		}
+		return sql.NullTime{Time: t, Valid: true}
+	}
+}
+
+func readOptionalDateTime(prompt string) sql.NullTime {
+	for {
+		val := readLine(prompt)
+		if val == "" {
+			return sql.NullTime{}
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04", val, time.Local)
+		if err != nil {
+			fmt.Println("Formato invalido, usa YYYY-MM-DD HH:MM")
+			continue
+		}
+		return sql.NullTime{Time: t, Valid: true}
+	}
+}
+
 func confirm(prompt string) bool {
 	val := strings.ToLower(readLine(prompt))
 	return val == "s" || val == "si" || val == "sí"
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertaStock se emite cuando el stock de un SKU cruza su punto de reorden.
+type AlertaStock struct {
+	IdSKU           int
+	IdProducto      int
+	StockAnterior   int
+	StockActual     int
+	PuntoReorden    int
+	CantidadReorden int
+	Fecha           time.Time
+}
+
+func (a AlertaStock) String() string {
+	return fmt.Sprintf("[ Alerta stock | SKU #%d | ProductoID:%d | %d -> %d | Reorden en: %d | Pedir: %d ]",
+		a.IdSKU, a.IdProducto, a.StockAnterior, a.StockActual, a.PuntoReorden, a.CantidadReorden)
+}
+
+// AlertaStockSink recibe las alertas de stock bajo (archivo de log, correo, etc.).
+type AlertaStockSink interface {
+	Notify(ctx context.Context, alerta AlertaStock) error
+}
+
+var (
+	stockAlertMu   sync.RWMutex
+	stockAlertSink AlertaStockSink
+)
+
+// SetStockAlertSink define a donde se envian las alertas de stock; nil las desactiva.
+func SetStockAlertSink(sink AlertaStockSink) {
+	stockAlertMu.Lock()
+	defer stockAlertMu.Unlock()
+	stockAlertSink = sink
+}
+
+func currentStockAlertSink() AlertaStockSink {
+	stockAlertMu.RLock()
+	defer stockAlertMu.RUnlock()
+	return stockAlertSink
+}
+
+// cruzaPuntoReorden indica si el stock paso de estar por encima a estar en o por
+// debajo del punto de reorden. Un punto en 0 significa que el SKU no se vigila.
+func cruzaPuntoReorden(before, after, reorderPoint int) bool {
+	return reorderPoint > 0 && before > reorderPoint && after <= reorderPoint
+}
+
+// notifyStockChange compara el SKU antes del cambio con su estado actual y
+// avisa al sink si se cruzo el punto de reorden.
+func notifyStockChange(ctx context.Context, before *SKU) error {
+	sink := currentStockAlertSink()
+	if sink == nil || before == nil {
+		return nil
+	}
+	after, err := NewSKUManager(nil).Get(ctx, before.IdSKU)
+	if err != nil {
+		return err
+	}
+	if !cruzaPuntoReorden(before.Stock, after.Stock, after.PuntoReorden) {
+		return nil
+	}
+	return sink.Notify(ctx, AlertaStock{
+		IdSKU:           after.IdSKU,
+		IdProducto:      after.IdProducto,
+		StockAnterior:   before.Stock,
+		StockActual:     after.Stock,
+		PuntoReorden:    after.PuntoReorden,
+		CantidadReorden: after.CantidadReorden,
+		Fecha:           time.Now(),
+	})
+}
+
+// LogFileAlertSink agrega cada alerta como una linea en un archivo de texto.
+type LogFileAlertSink struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewLogFileAlertSink(path string) *LogFileAlertSink {
+	return &LogFileAlertSink{Path: path}
+}
+
+func (s *LogFileAlertSink) Notify(ctx context.Context, alerta AlertaStock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("abriendo log de alertas %s: %w", s.Path, err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s %s\n", alerta.Fecha.Format(time.RFC3339), alerta.String())
+	return err
+}
+
+// EmailAlertSink es un sustituto de correo: escribe cada alerta como un
+// mensaje en una carpeta de salida en vez de enviarlo por SMTP.
+type EmailAlertSink struct {
+	To        string
+	OutboxDir string
+}
+
+func NewEmailAlertSink(to, outboxDir string) *EmailAlertSink {
+	return &EmailAlertSink{To: to, OutboxDir: outboxDir}
+}
+
+func (s *EmailAlertSink) Notify(ctx context.Context, alerta AlertaStock) error {
+	if err := os.MkdirAll(s.OutboxDir, 0o755); err != nil {
+		return fmt.Errorf("creando carpeta de salida %s: %w", s.OutboxDir, err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s\n", s.To)
+	fmt.Fprintf(&b, "Date: %s\n", alerta.Fecha.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Subject: Stock bajo SKU #%d\n\n", alerta.IdSKU)
+	fmt.Fprintf(&b, "El SKU #%d (producto %d) bajo de %d a %d unidades.\n",
+		alerta.IdSKU, alerta.IdProducto, alerta.StockAnterior, alerta.StockActual)
+	fmt.Fprintf(&b, "Punto de reorden: %d. Cantidad sugerida a pedir: %d.\n",
+		alerta.PuntoReorden, alerta.CantidadReorden)
+
+	name := fmt.Sprintf("alerta_sku%d_%d.eml", alerta.IdSKU, alerta.Fecha.UnixNano())
+	return os.WriteFile(filepath.Join(s.OutboxDir, name), []byte(b.String()), 0o644)
+}
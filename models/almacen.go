@@ -0,0 +1,263 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// Almacen es un punto desde el que se despachan SKUs.
+type Almacen struct {
+	IdAlmacen int
+	Nombre    string
+	Prioridad int
+	Activo    bool
+}
+
+func (a Almacen) String() string {
+	estado := "Activo"
+	if !a.Activo {
+		estado = "Inactivo"
+	}
+	return fmt.Sprintf("[ Almacen #%d | %s | Prioridad: %d | %s ]", a.IdAlmacen, a.Nombre, a.Prioridad, estado)
+}
+
+// StockAlmacen son las existencias de un SKU dentro de un almacen.
+type StockAlmacen struct {
+	IdSKU     int
+	IdAlmacen int
+	Almacen   string
+	Prioridad int
+	Stock     int
+	Codigo    string
+	Variante  sql.NullString
+}
+
+func (s StockAlmacen) String() string {
+	return fmt.Sprintf("[ %s | Almacen #%d %s | Stock: %d ]", etiquetaSKU(s.IdSKU, s.Codigo, s.Variante), s.IdAlmacen, s.Almacen, s.Stock)
+}
+
+// AsignacionAlmacen indica cuantas unidades salen de cada almacen al despachar.
+type AsignacionAlmacen struct {
+	IdAlmacen int
+	Cantidad  int
+}
+
+type AlmacenManager struct {
+	db *sql.DB
+}
+
+func NewAlmacenManager(database *sql.DB) *AlmacenManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &AlmacenManager{db: database}
+}
+
+func (m *AlmacenManager) List(ctx context.Context) ([]Almacen, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/almacen.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Almacen](rows)
+}
+
+func (m *AlmacenManager) Get(ctx context.Context, id int) (*Almacen, error) {
+	if err := requirePositive("idAlmacen", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/almacen_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Almacen](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("almacen %d no encontrado", id)
+	}
+	return &items[0], nil
+}
+
+func (m *AlmacenManager) Create(ctx context.Context, name string, priority int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	if err := requirePositive("prioridad", priority); err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "añadir/almacen.sql",
+		sql.Named("name", name),
+		sql.Named("priority", priority),
+	)
+	return err
+}
+
+func (m *AlmacenManager) Update(ctx context.Context, id int, name string, priority int, active bool) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idAlmacen", id); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	if err := requirePositive("prioridad", priority); err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/almacen.sql",
+		sql.Named("id", id),
+		sql.Named("name", name),
+		sql.Named("priority", priority),
+		sql.Named("active", active),
+	)
+	return err
+}
+
+// Delete borra un almacen; falla si todavia tiene existencias de algun SKU.
+func (m *AlmacenManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idAlmacen", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/almacen.sql", sql.Named("id", id))
+	return err
+}
+
+// ListStock obtiene las existencias de un SKU en los almacenes activos.
+func (m *AlmacenManager) ListStock(ctx context.Context, skuId int) ([]StockAlmacen, error) {
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_almacen_por_sku.sql", sql.Named("skuId", skuId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[StockAlmacen](rows)
+}
+
+// SetStock fija las existencias de un SKU en un almacen y recalcula SKU.stock.
+// Si el total cruza el punto de reorden se notifica al sink de alertas.
+func (m *AlmacenManager) SetStock(ctx context.Context, skuId, warehouseId, stock int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return err
+	}
+	if err := requirePositive("idAlmacen", warehouseId); err != nil {
+		return err
+	}
+	if stock < 0 {
+		return fmt.Errorf("stock no puede ser negativo")
+	}
+	before, err := NewSKUManager(m.db).Get(ctx, skuId)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/sku_almacen.sql",
+		sql.Named("skuId", skuId),
+		sql.Named("warehouseId", warehouseId),
+		sql.Named("stock", stock),
+	)
+	if err != nil {
+		return err
+	}
+	return notifyStockChange(ctx, before)
+}
+
+// Transfer mueve unidades de un SKU de un almacen a otro.
+func (m *AlmacenManager) Transfer(ctx context.Context, skuId, fromId, toId, quantity int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return err
+	}
+	if err := requirePositive("almacen origen", fromId); err != nil {
+		return err
+	}
+	if err := requirePositive("almacen destino", toId); err != nil {
+		return err
+	}
+	if fromId == toId {
+		return fmt.Errorf("el almacen de origen y destino deben ser distintos")
+	}
+	if err := requirePositive("cantidad", quantity); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "editar/sku_almacen_transferir.sql",
+		sql.Named("skuId", skuId),
+		sql.Named("fromId", fromId),
+		sql.Named("toId", toId),
+		sql.Named("quantity", quantity),
+	)
+	return err
+}
+
+// Allocate decide de que almacenes saldria la cantidad pedida de un SKU, sin
+// tocar el stock. Las reglas (un solo almacen si alcanza, si no repartir por
+// prioridad) viven en el procedimiento AsignarStockSKU de init.sql.
+func (m *AlmacenManager) Allocate(ctx context.Context, skuId, quantity int) ([]AsignacionAlmacen, error) {
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return nil, err
+	}
+	if err := requirePositive("cantidad", quantity); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_almacen_asignacion.sql",
+		sql.Named("skuId", skuId),
+		sql.Named("quantity", quantity),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[AsignacionAlmacen](rows)
+}
+
+// Dispatch asigna almacenes para la cantidad pedida y descuenta el stock de
+// todos en una sola transaccion: si falta stock no se descuenta nada.
+func (m *AlmacenManager) Dispatch(ctx context.Context, skuId, quantity int) ([]AsignacionAlmacen, error) {
+	if err := ensureDB(m.db); err != nil {
+		return nil, err
+	}
+	if err := requirePositive("cantidad", quantity); err != nil {
+		return nil, err
+	}
+	before, err := NewSKUManager(m.db).Get(ctx, skuId)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "editar/sku_almacen_descontar.sql",
+		sql.Named("skuId", skuId),
+		sql.Named("quantity", quantity),
+	)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := sqlutil.ParseRow[AsignacionAlmacen](rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := notifyStockChange(ctx, before); err != nil {
+		return plan, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BlobStore guarda archivos binarios por clave ("productos/3/ab12.jpg"). Las
+// claves usan "/" sin importar el sistema operativo.
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete no falla si la clave no existe
+	Delete(ctx context.Context, key string) error
+	// List devuelve las claves que empiezan con prefix
+	List(ctx context.Context, prefix string) ([]InfoBlob, error)
+}
+
+// InfoBlob es una clave guardada y cuando se escribio por ultima vez.
+type InfoBlob struct {
+	Clave      string
+	Modificado time.Time
+}
+
+var (
+	blobStoreMu sync.RWMutex
+	blobStore   BlobStore
+)
+
+// SetBlobStore define donde se guardan los archivos de medios.
+func SetBlobStore(store BlobStore) {
+	blobStoreMu.Lock()
+	defer blobStoreMu.Unlock()
+	blobStore = store
+}
+
+func currentBlobStore() (BlobStore, error) {
+	blobStoreMu.RLock()
+	defer blobStoreMu.RUnlock()
+	if blobStore == nil {
+		return nil, fmt.Errorf("no hay almacen de archivos configurado")
+	}
+	return blobStore, nil
+}
+
+// validarClaveBlob evita claves vacias, absolutas o que salgan de la raiz con "..".
+func validarClaveBlob(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return fmt.Errorf("clave de archivo invalida: %q", key)
+	}
+	if path.Clean(key) != key || key == "." || strings.HasPrefix(key, "../") || key == ".." {
+		return fmt.Errorf("clave de archivo invalida: %q", key)
+	}
+	return nil
+}
+
+// LocalBlobStore guarda cada clave como un archivo bajo Root.
+type LocalBlobStore struct {
+	Root string
+}
+
+func NewLocalBlobStore(root string) *LocalBlobStore {
+	return &LocalBlobStore{Root: root}
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if err := validarClaveBlob(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(key)), nil
+}
+
+// Put escribe primero en un temporal y lo renombra, para que nunca quede un
+// archivo a medias con la clave final.
+func (s *LocalBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	dest, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("creando carpeta para %s: %w", key, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".subiendo-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("guardando %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	src, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(src)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("archivo %s no encontrado", key)
+	}
+	return f, err
+}
+
+func (s *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	target, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) List(ctx context.Context, prefix string) ([]InfoBlob, error) {
+	blobs := []InfoBlob{}
+	err := filepath.WalkDir(s.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		// Los temporales de Put no son claves
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".subiendo-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// Borrado mientras se recorria
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		blobs = append(blobs, InfoBlob{Clave: key, Modificado: info.ModTime()})
+		return nil
+	})
+	return blobs, err
+}
@@ -0,0 +1,256 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// Ordenes aceptados por FiltroBusqueda.Orden.
+const (
+	OrdenRelevancia   = "relevancia"
+	OrdenPrecioAsc    = "precio"
+	OrdenPrecioDesc   = "precio_desc"
+	OrdenCalificacion = "calificacion"
+	OrdenResenas      = "resenas"
+)
+
+var ordenesBusqueda = []string{OrdenRelevancia, OrdenPrecioAsc, OrdenPrecioDesc, OrdenCalificacion, OrdenResenas}
+
+// limiteBusqueda es la cantidad de resultados cuando el filtro no pide otra.
+const limiteBusqueda = 50
+
+// FiltroBusqueda son los criterios de una busqueda; los campos en cero no filtran.
+type FiltroBusqueda struct {
+	Texto string
+	// Incluye las subcategorias
+	IdCategoria     int
+	PrecioMin       float64
+	PrecioMax       float64
+	SoloEnStock     bool
+	CalificacionMin float64
+	// Uno de ordenesBusqueda; vacio = relevancia
+	Orden  string
+	Limite int
+}
+
+// ResultadoBusqueda es un producto encontrado con el resumen de sus SKUs y
+// reseñas. Precio y stock solo cuentan los SKUs que pasaron los filtros.
+type ResultadoBusqueda struct {
+	IdProducto   int
+	Descripcion  string
+	IdCategoria  sql.NullInt32
+	PrecioMin    float64
+	PrecioMax    float64
+	Stock        int
+	Calificacion sql.NullFloat64
+	Resenas      int
+	// Calculada en la aplicacion a partir del texto buscado
+	Relevancia float64
+}
+
+func (r ResultadoBusqueda) String() string {
+	precio := fmt.Sprintf("%.2f", r.PrecioMin)
+	if r.PrecioMax > r.PrecioMin {
+		precio = fmt.Sprintf("%.2f - %.2f", r.PrecioMin, r.PrecioMax)
+	}
+	rating := "sin reseñas"
+	if r.Calificacion.Valid {
+		rating = fmt.Sprintf("%.1f/5 (%d)", r.Calificacion.Float64, r.Resenas)
+	}
+	return fmt.Sprintf("[ Producto #%d | %s | Precio: %s | Stock: %d | %s ]",
+		r.IdProducto, r.Descripcion, precio, r.Stock, rating)
+}
+
+// palabrasVacias no se buscan: aparecen en casi cualquier descripcion.
+var palabrasVacias = map[string]bool{
+	"de": true, "del": true, "la": true, "las": true, "el": true, "los": true,
+	"y": true, "o": true, "en": true, "con": true, "para": true, "por": true,
+	"un": true, "una": true, "a": true,
+}
+
+// normalizarTexto pasa a minusculas y quita acentos, igual que la comparacion
+// Latin1_General_CI_AI que usa la consulta.
+func normalizarTexto(text string) string {
+	replacer := strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+	return replacer.Replace(strings.ToLower(text))
+}
+
+// palabras separa el texto normalizado en palabras (letras y digitos).
+func palabras(text string) []string {
+	return strings.FieldsFunc(normalizarTexto(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// terminosBusqueda obtiene los terminos a buscar, sin repetidos ni palabras vacias.
+func terminosBusqueda(text string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range palabras(text) {
+		if palabrasVacias[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// escaparLike protege los comodines de LIKE; la consulta usa ESCAPE '\'.
+func escaparLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "[", `\[`).Replace(term)
+}
+
+// relevancia puntua la descripcion contra los terminos: una palabra igual vale
+// 3, una palabra que empieza con el termino 2 y el termino dentro de una
+// palabra 1. Si la descripcion empieza con el primer termino suma 1, y las
+// descripciones largas pierden un poco para que gane la coincidencia mas precisa.
+func relevancia(description string, terms []string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	words := palabras(description)
+	score := 0.0
+	for _, term := range terms {
+		best := 0.0
+		for _, word := range words {
+			switch {
+			case word == term:
+				best = max(best, 3)
+			case strings.HasPrefix(word, term):
+				best = max(best, 2)
+			case strings.Contains(word, term):
+				best = max(best, 1)
+			}
+		}
+		score += best
+	}
+	if len(words) > 0 && strings.HasPrefix(words[0], terms[0]) {
+		score++
+	}
+	return score / (1 + 0.05*float64(len(words)))
+}
+
+// ordenarResultados aplica el orden pedido; los empates se resuelven por
+// calificacion y luego por ID para que el resultado sea estable.
+func ordenarResultados(results []ResultadoBusqueda, orden string) {
+	rating := func(r ResultadoBusqueda) float64 {
+		if !r.Calificacion.Valid {
+			return 0
+		}
+		return r.Calificacion.Float64
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		switch orden {
+		case OrdenPrecioAsc:
+			if a.PrecioMin != b.PrecioMin {
+				return a.PrecioMin < b.PrecioMin
+			}
+		case OrdenPrecioDesc:
+			if a.PrecioMin != b.PrecioMin {
+				return a.PrecioMin > b.PrecioMin
+			}
+		case OrdenCalificacion:
+			if rating(a) != rating(b) {
+				return rating(a) > rating(b)
+			}
+			if a.Resenas != b.Resenas {
+				return a.Resenas > b.Resenas
+			}
+		case OrdenResenas:
+			if a.Resenas != b.Resenas {
+				return a.Resenas > b.Resenas
+			}
+		default:
+			if a.Relevancia != b.Relevancia {
+				return a.Relevancia > b.Relevancia
+			}
+		}
+		if rating(a) != rating(b) {
+			return rating(a) > rating(b)
+		}
+		return a.IdProducto < b.IdProducto
+	})
+}
+
+// validarFiltroBusqueda normaliza el orden y revisa los rangos.
+func validarFiltroBusqueda(f *FiltroBusqueda) error {
+	if f.IdCategoria < 0 {
+		return fmt.Errorf("idCategoria no puede ser negativo")
+	}
+	if f.PrecioMin < 0 || f.PrecioMax < 0 {
+		return fmt.Errorf("precio no puede ser negativo")
+	}
+	if f.PrecioMax > 0 && f.PrecioMin > f.PrecioMax {
+		return fmt.Errorf("el precio minimo no puede ser mayor al maximo")
+	}
+	if f.CalificacionMin < 0 || f.CalificacionMin > 5 {
+		return fmt.Errorf("calificacion minima debe estar entre 0 y 5")
+	}
+	f.Orden = strings.ToLower(strings.TrimSpace(f.Orden))
+	if f.Orden == "" {
+		f.Orden = OrdenRelevancia
+	}
+	valid := false
+	for _, o := range ordenesBusqueda {
+		valid = valid || f.Orden == o
+	}
+	if !valid {
+		return fmt.Errorf("orden invalido: %q (usa %s)", f.Orden, strings.Join(ordenesBusqueda, ", "))
+	}
+	if f.Limite <= 0 {
+		f.Limite = limiteBusqueda
+	}
+	return nil
+}
+
+// Search busca productos por texto en la descripcion con los filtros del
+// FiltroBusqueda. Todos los terminos tienen que aparecer; sin texto solo se
+// aplican los filtros.
+func (m *ProductoManager) Search(ctx context.Context, filter FiltroBusqueda) ([]ResultadoBusqueda, error) {
+	if err := validarFiltroBusqueda(&filter); err != nil {
+		return nil, err
+	}
+	terms := terminosBusqueda(filter.Texto)
+	escaped := make([]string, 0, len(terms))
+	for _, term := range terms {
+		escaped = append(escaped, escaparLike(term))
+	}
+	data, err := json.Marshal(escaped)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/producto_busqueda.sql",
+		sql.Named("terms", string(data)),
+		sql.Named("categoryId", optionalInt(filter.IdCategoria)),
+		sql.Named("minPrice", optionalAmount(filter.PrecioMin)),
+		sql.Named("maxPrice", optionalAmount(filter.PrecioMax)),
+		sql.Named("inStock", filter.SoloEnStock),
+		sql.Named("minRating", optionalAmount(filter.CalificacionMin)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results, err := sqlutil.ParseRow[ResultadoBusqueda](rows)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Relevancia = relevancia(results[i].Descripcion, terms)
+	}
+	ordenarResultados(results, filter.Orden)
+	if len(results) > filter.Limite {
+		results = results[:filter.Limite]
+	}
+	return results, nil
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestEscaparLike(t *testing.T) {
+	tests := []struct {
+		term string
+		want string
+	}{
+		{"camisa", "camisa"},
+		{"100%", `100\%`},
+		{"con_guion", `con\_guion`},
+		{"[oferta]", `\[oferta]`},
+		{`c:\ruta`, `c:\\ruta`},
+		// La barra se escapa primero para no duplicar las que agregan los demas
+		{`\%`, `\\\%`},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			if got := escaparLike(tt.term); got != tt.want {
+				t.Errorf("escaparLike(%q) = %q, want %q", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelevancia(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		terms       []string
+		want        float64
+	}{
+		{"sin terminos", "Camisa azul", nil, 0},
+		{"sin descripcion", "", []string{"camisa"}, 0},
+		{"sin coincidencia", "Mesa", []string{"silla"}, 0},
+		{"palabra igual al inicio", "Camisa azul", []string{"camisa"}, 4 / 1.1},
+		{"prefijo al inicio", "Camiseta", []string{"camis"}, 3 / 1.05},
+		{"dentro de una palabra", "Polo azul", []string{"zu"}, 1 / 1.1},
+		{"ignora acentos y mayusculas", "CAFÉ", []string{"cafe"}, 4 / 1.05},
+		{"suma cada termino", "Camisa azul", []string{"azul", "camisa"}, 6 / 1.1},
+		{"cuenta la mejor coincidencia", "Azulejo azul", []string{"azul"}, 4 / 1.1},
+		{"descripcion larga pesa menos", "Camisa de algodon azul marino", []string{"camisa"}, 4 / 1.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := relevancia(tt.description, tt.terms)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("relevancia(%q, %q) = %v, want %v", tt.description, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrdenarResultados(t *testing.T) {
+	rating := func(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} }
+	results := []ResultadoBusqueda{
+		{IdProducto: 1, PrecioMin: 30, Calificacion: rating(4), Resenas: 2, Relevancia: 1},
+		{IdProducto: 2, PrecioMin: 10, Resenas: 0, Relevancia: 3},
+		{IdProducto: 3, PrecioMin: 20, Calificacion: rating(4), Resenas: 9, Relevancia: 1},
+		{IdProducto: 4, PrecioMin: 10, Calificacion: rating(5), Resenas: 1, Relevancia: 2},
+		{IdProducto: 5, PrecioMin: 20, Calificacion: rating(4), Resenas: 2, Relevancia: 1},
+	}
+	tests := []struct {
+		orden string
+		want  []int
+	}{
+		// Empates de relevancia: calificacion y luego ID
+		{OrdenRelevancia, []int{2, 4, 1, 3, 5}},
+		{"", []int{2, 4, 1, 3, 5}},
+		{OrdenPrecioAsc, []int{4, 2, 3, 5, 1}},
+		{OrdenPrecioDesc, []int{1, 3, 5, 4, 2}},
+		// Sin reseñas cuenta como 0; empates por cantidad de reseñas
+		{OrdenCalificacion, []int{4, 3, 1, 5, 2}},
+		{OrdenResenas, []int{3, 1, 5, 4, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.orden, func(t *testing.T) {
+			got := slices.Clone(results)
+			ordenarResultados(got, tt.orden)
+			ids := make([]int, len(got))
+			for i, r := range got {
+				ids[i] = r.IdProducto
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("orden %q = %v, want %v", tt.orden, ids, tt.want)
+			}
+		})
+	}
+}
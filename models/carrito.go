@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"tienda-online/internal/db"
 	sqlutil "tienda-online/internal/sql"
@@ -11,14 +12,30 @@ import (
 
 type Carrito struct {
 	IdCarrito int
-	IdUsuario int
+	// Sin valor en los carritos de invitado
+	IdUsuario   sql.NullInt32
+	CodigoCupon sql.NullString
+	// Token del carrito de invitado; sin valor en los de clientes
+	Token              sql.NullString
+	FechaActualizacion time.Time
+}
+
+// Invitado indica si el carrito es de un visitante sin cuenta.
+func (c Carrito) Invitado() bool {
+	return !c.IdUsuario.Valid
 }
 
 func (c Carrito) String() string {
-	userLabel := fmt.Sprintf("UsuarioID:%d", c.IdUsuario)
-	user, err := NewClienteManager(nil).Get(context.Background(), c.IdUsuario)
-	if err == nil && user != nil {
-		userLabel = user.String()
+	userLabel := "Invitado"
+	if !c.Invitado() {
+		userLabel = fmt.Sprintf("UsuarioID:%d", c.IdUsuario.Int32)
+		user, err := NewClienteManager(nil).Get(context.Background(), int(c.IdUsuario.Int32))
+		if err == nil && user != nil {
+			userLabel = user.String()
+		}
+	}
+	if c.CodigoCupon.Valid {
+		return fmt.Sprintf("[ Carrito #%d | %s | Cupon: %s ]", c.IdCarrito, userLabel, c.CodigoCupon.String)
 	}
 	return fmt.Sprintf("[ Carrito #%d | %s ]", c.IdCarrito, userLabel)
 }
@@ -62,6 +79,26 @@ func (m *CarritoManager) Get(ctx context.Context, id int) (*Carrito, error) {
 	return &items[0], nil
 }
 
+// GetByUser obtiene el carrito del usuario.
+func (m *CarritoManager) GetByUser(ctx context.Context, userId int) (*Carrito, error) {
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/carrito_por_usuario.sql", sql.Named("userId", userId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Carrito](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("el usuario %d no tiene carrito", userId)
+	}
+	return &items[0], nil
+}
+
 func (m *CarritoManager) Create(ctx context.Context, userId int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -90,6 +127,82 @@ func (m *CarritoManager) Update(ctx context.Context, id, userId int) error {
 	return err
 }
 
+// Items arma las lineas del carrito con el precio actual de cada SKU y
+// devuelve tambien el peso total en kg. Las lineas cuyo SKU se borro no se
+// pueden comprar y se saltean (Totals las marca).
+func (m *CarritoManager) Items(ctx context.Context, id int) ([]PedidoDetalle, float64, error) {
+	lines, err := NewCarritoDetalleManager(m.db).ListByCarrito(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	skus := NewSKUManager(m.db)
+	items := make([]PedidoDetalle, 0, len(lines))
+	weight := 0.0
+	for _, line := range lines {
+		if !line.IdSKU.Valid {
+			continue
+		}
+		sku, err := skus.Get(ctx, int(line.IdSKU.Int32))
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, PedidoDetalle{IdSKU: sku.IdSKU, Cantidad: line.Cantidad, PrecioUnitario: sku.Precio,
+			Codigo: sku.Codigo, Variante: sku.Variante})
+		weight += sku.Peso * float64(line.Cantidad)
+	}
+	return items, weight, nil
+}
+
+// ApplyCoupon deja el cupon en el carrito si hoy aplica a su contenido y
+// devuelve el descuento que daria. Se vuelve a validar al crear el pedido.
+func (m *CarritoManager) ApplyCoupon(ctx context.Context, id int, code string) (float64, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	cart, err := m.Get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	items, _, err := m.Items(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, fmt.Errorf("el carrito %d esta vacio", id)
+	}
+	// Los limites de uso de los cupones son por cliente
+	if cart.Invitado() {
+		return 0, fmt.Errorf("los cupones se aplican con una cuenta de cliente")
+	}
+	coupon, discount, err := NewCuponManager(m.db).Discount(ctx, code, int(cart.IdUsuario.Int32), items)
+	if err != nil {
+		return 0, err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/carrito_cupon.sql",
+		sql.Named("id", id),
+		sql.Named("code", coupon.Codigo),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return discount, nil
+}
+
+// RemoveCoupon quita el cupon del carrito.
+func (m *CarritoManager) RemoveCoupon(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idCarrito", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "editar/carrito_cupon.sql",
+		sql.Named("id", id),
+		sql.Named("code", sql.NullString{}),
+	)
+	return err
+}
+
 func (m *CarritoManager) Delete(ctx context.Context, id int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -0,0 +1,293 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+const (
+	// EsperaEntreRecordatorios es el minimo entre dos recordatorios al mismo
+	// carrito, aunque el cliente lo haya vuelto a tocar.
+	EsperaEntreRecordatorios = 7 * 24 * time.Hour
+	// VentanaRecuperacionCarrito es cuanto despues de un recordatorio un pedido
+	// cuenta como carrito recuperado.
+	VentanaRecuperacionCarrito = 7 * 24 * time.Hour
+)
+
+// RecordatorioCarrito le recuerda a un cliente que dejo articulos en el carrito.
+type RecordatorioCarrito struct {
+	IdCarrito int
+	IdUsuario int
+	Nombre    string
+	Correo    string
+	Unidades  int
+	Importe   float64
+	Lineas    []LineaCarrito
+	// Ultimo cambio del carrito
+	Inactivo time.Time
+	Fecha    time.Time
+}
+
+func (r RecordatorioCarrito) String() string {
+	return fmt.Sprintf("[ Recordatorio | Carrito #%d | UsuarioID:%d %s <%s> | %d unidades | %.2f | Sin cambios desde %s ]",
+		r.IdCarrito, r.IdUsuario, r.Nombre, r.Correo, r.Unidades, r.Importe, r.Inactivo.Local().Format("2006-01-02 15:04"))
+}
+
+// RecordatorioCarritoSink envia los recordatorios de carritos abandonados
+// (archivo de log, correo, etc.).
+type RecordatorioCarritoSink interface {
+	Notify(ctx context.Context, recordatorio RecordatorioCarrito) error
+}
+
+var (
+	cartReminderMu   sync.RWMutex
+	cartReminderSink RecordatorioCarritoSink
+)
+
+// SetCartReminderSink define por donde se envian los recordatorios de carritos
+// abandonados; nil los desactiva.
+func SetCartReminderSink(sink RecordatorioCarritoSink) {
+	cartReminderMu.Lock()
+	defer cartReminderMu.Unlock()
+	cartReminderSink = sink
+}
+
+func currentCartReminderSink() RecordatorioCarritoSink {
+	cartReminderMu.RLock()
+	defer cartReminderMu.RUnlock()
+	return cartReminderSink
+}
+
+// LogFileCartReminderSink agrega cada recordatorio como una linea en un archivo de texto.
+type LogFileCartReminderSink struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewLogFileCartReminderSink(path string) *LogFileCartReminderSink {
+	return &LogFileCartReminderSink{Path: path}
+}
+
+func (s *LogFileCartReminderSink) Notify(ctx context.Context, recordatorio RecordatorioCarrito) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("abriendo log de recordatorios %s: %w", s.Path, err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s %s\n", recordatorio.Fecha.Format(time.RFC3339), recordatorio.String())
+	return err
+}
+
+// EmailCartReminderSink es un sustituto de correo: escribe cada recordatorio
+// como un mensaje en una carpeta de salida en vez de enviarlo por SMTP.
+type EmailCartReminderSink struct {
+	OutboxDir string
+}
+
+func NewEmailCartReminderSink(outboxDir string) *EmailCartReminderSink {
+	return &EmailCartReminderSink{OutboxDir: outboxDir}
+}
+
+func (s *EmailCartReminderSink) Notify(ctx context.Context, recordatorio RecordatorioCarrito) error {
+	if err := os.MkdirAll(s.OutboxDir, 0o755); err != nil {
+		return fmt.Errorf("creando carpeta de salida %s: %w", s.OutboxDir, err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "To: %s <%s>\n", recordatorio.Nombre, recordatorio.Correo)
+	fmt.Fprintf(&b, "Date: %s\n", recordatorio.Fecha.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Subject: Dejaste articulos en tu carrito\n\n")
+	fmt.Fprintf(&b, "Hola %s, tu carrito te sigue esperando:\n\n", recordatorio.Nombre)
+	for _, l := range recordatorio.Lineas {
+		if l.Problema() == LineaSKUEliminado {
+			continue
+		}
+		fmt.Fprintf(&b, "- %d x %s %s: %.2f\n",
+			l.Cantidad, l.Codigo.String, l.Descripcion.String, l.Importe())
+	}
+	fmt.Fprintf(&b, "\nTotal aproximado: %.2f\n", recordatorio.Importe)
+
+	name := fmt.Sprintf("carrito%d_%d.eml", recordatorio.IdCarrito, recordatorio.Fecha.UnixNano())
+	return os.WriteFile(filepath.Join(s.OutboxDir, name), []byte(b.String()), 0o644)
+}
+
+// CarritoRecordatorio es un recordatorio ya enviado. IdPedido tiene valor si
+// el cliente compro dentro de VentanaRecuperacionCarrito.
+type CarritoRecordatorio struct {
+	IdRecordatorio int
+	IdCarrito      int
+	FechaCarrito   time.Time
+	FechaEnvio     time.Time
+	Importe        float64
+	IdPedido       sql.NullInt32
+}
+
+func (r CarritoRecordatorio) String() string {
+	estado := "Sin recuperar"
+	if r.IdPedido.Valid {
+		estado = fmt.Sprintf("Recuperado en pedido #%d", r.IdPedido.Int32)
+	}
+	return fmt.Sprintf("[ Recordatorio #%d | Carrito #%d | Enviado: %s | %.2f | %s ]",
+		r.IdRecordatorio, r.IdCarrito, r.FechaEnvio.Local().Format("2006-01-02 15:04"), r.Importe, estado)
+}
+
+// RecuperacionCarritos resume los recordatorios enviados en un periodo.
+type RecuperacionCarritos struct {
+	Desde             time.Time
+	Hasta             time.Time
+	Enviados          int
+	Recuperados       int
+	ImporteRecordado  float64
+	ImporteRecuperado float64
+}
+
+// Tasa es la fraccion de recordatorios que terminaron en pedido.
+func (r RecuperacionCarritos) Tasa() float64 {
+	if r.Enviados == 0 {
+		return 0
+	}
+	return float64(r.Recuperados) / float64(r.Enviados)
+}
+
+func (r RecuperacionCarritos) String() string {
+	return fmt.Sprintf("[ %s - %s | Enviados: %d | Recuperados: %d (%.1f%%) | Recordado: %.2f | Recuperado: %.2f ]",
+		r.Desde.Local().Format("2006-01-02"), r.Hasta.Local().Format("2006-01-02"),
+		r.Enviados, r.Recuperados, r.Tasa()*100, r.ImporteRecordado, r.ImporteRecuperado)
+}
+
+// carritoAbandonado es un carrito que merece recordatorio.
+type carritoAbandonado struct {
+	IdCarrito          int
+	IdUsuario          int
+	Nombre             string
+	Correo             string
+	FechaActualizacion time.Time
+	Unidades           int
+	Importe            float64
+}
+
+// RemindAbandoned envia un recordatorio por cada carrito de cliente con
+// articulos y sin cambios en idle, y devuelve cuantos envio. Un carrito recibe
+// uno por periodo de inactividad y nunca dos dentro de
+// EsperaEntreRecordatorios. Sin sink no se envia ni se registra nada. Se llama
+// periodicamente.
+//
+// Cada recordatorio se reserva en la base antes de enviarlo, asi dos
+// revisiones simultaneas no mandan el mismo; si el envio falla la reserva se
+// borra y se reintenta en la proxima revision.
+func (m *CarritoManager) RemindAbandoned(ctx context.Context, idle time.Duration) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	if idle <= 0 {
+		return 0, fmt.Errorf("el tiempo de inactividad debe ser mayor a cero")
+	}
+	sink := currentCartReminderSink()
+	if sink == nil {
+		return 0, nil
+	}
+	now := time.Now()
+	rows, err := db.QueryRowsFromFile(ctx, "leer/carrito_abandonado.sql",
+		sql.Named("idleSince", now.Add(-idle).UTC()),
+		sql.Named("remindedSince", now.Add(-EsperaEntreRecordatorios).UTC()),
+	)
+	if err != nil {
+		return 0, err
+	}
+	carts, err := sqlutil.ParseRow[carritoAbandonado](rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, cart := range carts {
+		lines, err := m.Lines(ctx, cart.IdCarrito)
+		if err != nil {
+			return sent, err
+		}
+		rows, err := db.QueryRowsFromFile(ctx, "añadir/carrito_recordatorio.sql",
+			sql.Named("cartId", cart.IdCarrito),
+			sql.Named("cartUpdatedAt", cart.FechaActualizacion),
+			sql.Named("amount", cart.Importe),
+		)
+		if err != nil {
+			return sent, err
+		}
+		claimed, err := sqlutil.ParseRow[struct{ IdRecordatorio sql.NullInt32 }](rows)
+		rows.Close()
+		if err != nil {
+			return sent, err
+		}
+		// Otra revision ya lo reservo
+		if len(claimed) == 0 || !claimed[0].IdRecordatorio.Valid {
+			continue
+		}
+		err = sink.Notify(ctx, RecordatorioCarrito{
+			IdCarrito: cart.IdCarrito,
+			IdUsuario: cart.IdUsuario,
+			Nombre:    cart.Nombre,
+			Correo:    cart.Correo,
+			Unidades:  cart.Unidades,
+			Importe:   cart.Importe,
+			Lineas:    lines,
+			Inactivo:  cart.FechaActualizacion,
+			Fecha:     now,
+		})
+		if err != nil {
+			reminderId := claimed[0].IdRecordatorio.Int32
+			if _, delErr := db.ExecFromFile(ctx, "remover/carrito_recordatorio.sql", sql.Named("id", reminderId)); delErr != nil {
+				return sent, fmt.Errorf("recordatorio %d sin enviar (%v), pero no se pudo liberar: %w", reminderId, err, delErr)
+			}
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Reminders obtiene los recordatorios enviados por un carrito, los mas nuevos primero.
+func (m *CarritoManager) Reminders(ctx context.Context, cartId int) ([]CarritoRecordatorio, error) {
+	if err := requirePositive("idCarrito", cartId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/carrito_recordatorio_por_carrito.sql", sql.Named("cartId", cartId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[CarritoRecordatorio](rows)
+}
+
+// RecoveryReport resume los recordatorios enviados en [from, to) y cuantos
+// terminaron en pedido.
+func (m *CarritoManager) RecoveryReport(ctx context.Context, from, to time.Time) (*RecuperacionCarritos, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("el fin del periodo debe ser posterior al inicio")
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/carrito_recordatorio_resumen.sql",
+		sql.Named("from", from.UTC()),
+		sql.Named("to", to.UTC()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[RecuperacionCarritos](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return &RecuperacionCarritos{Desde: from, Hasta: to}, nil
+	}
+	return &items[0], nil
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// MaxCantidadLinea es la cantidad maxima de un mismo SKU en un carrito.
+const MaxCantidadLinea = 99
+
+func validarCantidadLinea(quantity int) error {
+	if quantity <= 0 {
+		return fmt.Errorf("cantidad debe ser mayor a cero")
+	}
+	if quantity > MaxCantidadLinea {
+		return fmt.Errorf("la cantidad maxima por linea es %d", MaxCantidadLinea)
+	}
+	return nil
+}
+
+// ForUser obtiene el carrito del cliente y lo crea si todavia no tiene.
+func (m *CarritoManager) ForUser(ctx context.Context, userId int) (*Carrito, error) {
+	if err := ensureDB(m.db); err != nil {
+		return nil, err
+	}
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/carrito_por_usuario.sql", sql.Named("userId", userId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Carrito](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no se pudo obtener el carrito del usuario %d", userId)
+	}
+	return &items[0], nil
+}
+
+// duenoCarrito identifica el carrito por cliente o, si userId es 0, por el
+// token del invitado.
+type duenoCarrito struct {
+	userId int
+	token  string
+}
+
+func (d duenoCarrito) validar() error {
+	if d.userId == 0 {
+		_, err := requireNonEmpty("token", d.token)
+		return err
+	}
+	return requirePositive("idUsuario", d.userId)
+}
+
+func (d duenoCarrito) args(extra ...any) []any {
+	return append([]any{
+		sql.Named("userId", optionalInt(d.userId)),
+		sql.Named("token", optionalString(d.token)),
+	}, extra...)
+}
+
+func (d duenoCarrito) String() string {
+	if d.userId == 0 {
+		return "del invitado"
+	}
+	return fmt.Sprintf("del usuario %d", d.userId)
+}
+
+// AddItem agrega quantity unidades del SKU al carrito del cliente. Si el SKU ya
+// estaba se suma a esa linea. Devuelve la cantidad que quedo en la linea.
+func (m *CarritoManager) AddItem(ctx context.Context, userId, skuId, quantity int) (int, error) {
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return 0, err
+	}
+	return m.addItem(ctx, duenoCarrito{userId: userId}, skuId, quantity)
+}
+
+// SetQuantity deja exactamente quantity unidades del SKU en el carrito del
+// cliente; 0 lo quita.
+func (m *CarritoManager) SetQuantity(ctx context.Context, userId, skuId, quantity int) error {
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return err
+	}
+	return m.setQuantity(ctx, duenoCarrito{userId: userId}, skuId, quantity)
+}
+
+// RemoveItem quita el SKU del carrito del cliente.
+func (m *CarritoManager) RemoveItem(ctx context.Context, userId, skuId int) error {
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return err
+	}
+	return m.removeItem(ctx, duenoCarrito{userId: userId}, skuId)
+}
+
+// Clear vacia el carrito del cliente y le quita el cupon.
+func (m *CarritoManager) Clear(ctx context.Context, userId int) error {
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return err
+	}
+	return m.clear(ctx, duenoCarrito{userId: userId})
+}
+
+func (m *CarritoManager) addItem(ctx context.Context, owner duenoCarrito, skuId, quantity int) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	if err := owner.validar(); err != nil {
+		return 0, err
+	}
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return 0, err
+	}
+	if err := validarCantidadLinea(quantity); err != nil {
+		return 0, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/carrito_item.sql", owner.args(
+		sql.Named("skuId", skuId),
+		sql.Named("quantity", quantity),
+		sql.Named("maxQuantity", MaxCantidadLinea),
+	)...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	result, err := sqlutil.ParseRow[struct{ Cantidad int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, fmt.Errorf("no se obtuvo la cantidad de la linea")
+	}
+	return result[0].Cantidad, nil
+}
+
+func (m *CarritoManager) setQuantity(ctx context.Context, owner duenoCarrito, skuId, quantity int) error {
+	if quantity == 0 {
+		return m.removeItem(ctx, owner, skuId)
+	}
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := owner.validar(); err != nil {
+		return err
+	}
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return err
+	}
+	if err := validarCantidadLinea(quantity); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "editar/carrito_item_cantidad.sql", owner.args(
+		sql.Named("skuId", skuId),
+		sql.Named("quantity", quantity),
+	)...)
+	return err
+}
+
+func (m *CarritoManager) removeItem(ctx context.Context, owner duenoCarrito, skuId int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := owner.validar(); err != nil {
+		return err
+	}
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "remover/carrito_item.sql", owner.args(sql.Named("skuId", skuId))...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	result, err := sqlutil.ParseRow[struct{ Eliminados int }](rows)
+	if err != nil {
+		return err
+	}
+	if len(result) == 0 || result[0].Eliminados == 0 {
+		return fmt.Errorf("el SKU %d no esta en el carrito %s", skuId, owner)
+	}
+	return nil
+}
+
+func (m *CarritoManager) clear(ctx context.Context, owner duenoCarrito) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := owner.validar(); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/carrito_vaciar.sql", owner.args()...)
+	return err
+}
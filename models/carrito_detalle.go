@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"tienda-online/internal/db"
 	sqlutil "tienda-online/internal/sql"
@@ -12,8 +13,10 @@ import (
 type CarritoDetalle struct {
 	IdDetalle int
 	IdCarrito int
-	IdSKU     int
-	Cantidad  int
+	// Sin valor si el SKU se borro despues de agregarlo
+	IdSKU              sql.NullInt32
+	Cantidad           int
+	FechaActualizacion time.Time
 }
 
 func (c CarritoDetalle) String() string {
@@ -22,9 +25,12 @@ func (c CarritoDetalle) String() string {
 		carritoLabel = cart.String()
 	}
 
-	skuLabel := fmt.Sprintf("SKU:%d", c.IdSKU)
-	if sku, err := NewSKUManager(nil).Get(context.Background(), c.IdSKU); err == nil && sku != nil {
-		skuLabel = sku.String()
+	skuLabel := "SKU eliminado"
+	if c.IdSKU.Valid {
+		skuLabel = fmt.Sprintf("SKU:%d", c.IdSKU.Int32)
+		if sku, err := NewSKUManager(nil).Get(context.Background(), int(c.IdSKU.Int32)); err == nil && sku != nil {
+			skuLabel = sku.String()
+		}
 	}
 
 	return fmt.Sprintf("[ Detalle #%d | %s | %s | Cantidad:%d ]", c.IdDetalle, carritoLabel, skuLabel, c.Cantidad)
@@ -92,13 +98,14 @@ func (m *CarritoDetalleManager) Create(ctx context.Context, cartId, skuId, quant
 	if err := requirePositive("idSKU", skuId); err != nil {
 		return err
 	}
-	if quantity <= 0 {
-		return fmt.Errorf("cantidad debe ser mayor a cero")
+	if err := validarCantidadLinea(quantity); err != nil {
+		return err
 	}
 	_, err := db.ExecFromFile(ctx, "añadir/carrito_detalle.sql",
 		sql.Named("cartId", cartId),
 		sql.Named("skuId", skuId),
 		sql.Named("quantity", quantity),
+		sql.Named("maxQuantity", MaxCantidadLinea),
 	)
 	return err
 }
@@ -116,14 +123,15 @@ func (m *CarritoDetalleManager) Update(ctx context.Context, id, cartId, skuId, q
 	if err := requirePositive("idSKU", skuId); err != nil {
 		return err
 	}
-	if quantity <= 0 {
-		return fmt.Errorf("cantidad debe ser mayor a cero")
+	if err := validarCantidadLinea(quantity); err != nil {
+		return err
 	}
 	_, err := db.ExecFromFile(ctx, "editar/carrito_detalle.sql",
 		sql.Named("id", id),
 		sql.Named("cartId", cartId),
 		sql.Named("skuId", skuId),
 		sql.Named("quantity", quantity),
+		sql.Named("maxQuantity", MaxCantidadLinea),
 	)
 	return err
 }
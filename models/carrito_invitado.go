@@ -0,0 +1,141 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// VencimientoCarritoInvitado es cuanto dura un carrito de invitado sin cambios.
+const VencimientoCarritoInvitado = 30 * 24 * time.Hour
+
+// AjusteFusion es una linea del carrito de invitado que no entro completa al
+// carrito del cliente por falta de stock o por el maximo por linea.
+type AjusteFusion struct {
+	IdSKU      int
+	Solicitada int
+	Cantidad   int
+	Codigo     string
+	Variante   sql.NullString
+}
+
+func (a AjusteFusion) String() string {
+	return fmt.Sprintf("[ %s | Pedida: %d | Quedo: %d ]", etiquetaSKU(a.IdSKU, a.Codigo, a.Variante), a.Solicitada, a.Cantidad)
+}
+
+// nuevoTokenCarrito genera el token opaco de un carrito de invitado.
+func nuevoTokenCarrito() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(random), nil
+}
+
+// CreateGuest crea un carrito de invitado vacio y devuelve su token, que es lo
+// unico que lo identifica.
+func (m *CarritoManager) CreateGuest(ctx context.Context) (string, error) {
+	if err := ensureDB(m.db); err != nil {
+		return "", err
+	}
+	token, err := nuevoTokenCarrito()
+	if err != nil {
+		return "", err
+	}
+	if _, err := db.ExecFromFile(ctx, "añadir/carrito_invitado.sql", sql.Named("token", token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetByToken obtiene el carrito de invitado con ese token.
+func (m *CarritoManager) GetByToken(ctx context.Context, token string) (*Carrito, error) {
+	token, err := requireNonEmpty("token", token)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/carrito_por_token.sql", sql.Named("token", token))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Carrito](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("el carrito de invitado no existe o vencio")
+	}
+	return &items[0], nil
+}
+
+// AddGuestItem es AddItem para el carrito de invitado con ese token.
+func (m *CarritoManager) AddGuestItem(ctx context.Context, token string, skuId, quantity int) (int, error) {
+	return m.addItem(ctx, duenoCarrito{token: token}, skuId, quantity)
+}
+
+// SetGuestQuantity es SetQuantity para el carrito de invitado con ese token.
+func (m *CarritoManager) SetGuestQuantity(ctx context.Context, token string, skuId, quantity int) error {
+	return m.setQuantity(ctx, duenoCarrito{token: token}, skuId, quantity)
+}
+
+// RemoveGuestItem es RemoveItem para el carrito de invitado con ese token.
+func (m *CarritoManager) RemoveGuestItem(ctx context.Context, token string, skuId int) error {
+	return m.removeItem(ctx, duenoCarrito{token: token}, skuId)
+}
+
+// MergeGuest pasa el carrito de invitado al del cliente cuando inicia sesion y
+// borra el de invitado. Las cantidades del mismo SKU se suman hasta el stock y
+// MaxCantidadLinea, sin bajar lo que el cliente ya tenia; devuelve las lineas
+// que no entraron completas.
+func (m *CarritoManager) MergeGuest(ctx context.Context, token string, userId int) ([]AjusteFusion, error) {
+	if err := ensureDB(m.db); err != nil {
+		return nil, err
+	}
+	token, err := requireNonEmpty("token", token)
+	if err != nil {
+		return nil, err
+	}
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "editar/carrito_fusionar.sql",
+		sql.Named("token", token),
+		sql.Named("userId", userId),
+		sql.Named("maxQuantity", MaxCantidadLinea),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[AjusteFusion](rows)
+}
+
+// ExpireGuestCarts borra los carritos de invitado sin cambios en
+// VencimientoCarritoInvitado y devuelve cuantos borro. Se llama periodicamente.
+func (m *CarritoManager) ExpireGuestCarts(ctx context.Context) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "remover/carrito_invitado_vencido.sql",
+		sql.Named("before", time.Now().Add(-VencimientoCarritoInvitado).UTC()),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	result, err := sqlutil.ParseRow[struct{ Eliminados int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return result[0].Eliminados, nil
+}
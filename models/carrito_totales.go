@@ -0,0 +1,191 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"tienda-online/internal"
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// Problemas que puede tener una linea del carrito.
+const (
+	LineaSKUEliminado      = "SKU eliminado"
+	LineaSinStock          = "sin stock"
+	LineaStockInsuficiente = "stock insuficiente"
+)
+
+// LineaCarrito es una linea del carrito con el precio actual de su SKU. Los
+// datos del SKU quedan vacios si se borro.
+type LineaCarrito struct {
+	IdDetalle      int
+	IdSKU          sql.NullInt32
+	Codigo         sql.NullString
+	Variante       sql.NullString
+	Descripcion    sql.NullString
+	Cantidad       int
+	Stock          sql.NullInt32
+	PrecioUnitario sql.NullFloat64
+}
+
+// Importe es precio por cantidad; 0 si el SKU ya no existe.
+func (l LineaCarrito) Importe() float64 {
+	if !l.PrecioUnitario.Valid {
+		return 0
+	}
+	return redondear(l.PrecioUnitario.Float64 * float64(l.Cantidad))
+}
+
+// Problema explica por que la linea no se puede comprar tal cual; vacio si esta bien.
+func (l LineaCarrito) Problema() string {
+	switch {
+	case !l.IdSKU.Valid:
+		return LineaSKUEliminado
+	case l.Stock.Int32 <= 0:
+		return LineaSinStock
+	case int(l.Stock.Int32) < l.Cantidad:
+		return fmt.Sprintf("%s (quedan %d)", LineaStockInsuficiente, l.Stock.Int32)
+	}
+	return ""
+}
+
+func (l LineaCarrito) String() string {
+	if !l.IdSKU.Valid {
+		return fmt.Sprintf("[ Detalle #%d | %s | Cantidad: %d ]", l.IdDetalle, LineaSKUEliminado, l.Cantidad)
+	}
+	line := fmt.Sprintf("[ Detalle #%d | SKU:%d %s | %s | %d x %.2f = %.2f",
+		l.IdDetalle, l.IdSKU.Int32, etiquetaVariante(internal.NullString(l.Codigo), l.Variante),
+		internal.NullString(l.Descripcion), l.Cantidad, l.PrecioUnitario.Float64, l.Importe())
+	if problem := l.Problema(); problem != "" {
+		line += " | " + problem
+	}
+	return line + " ]"
+}
+
+// TotalesCarrito es lo que costaria hoy el carrito, sin envio: el envio
+// depende del metodo elegido y se calcula al cotizar el pedido.
+type TotalesCarrito struct {
+	IdCarrito   int
+	Lineas      []LineaCarrito
+	Promociones []AplicacionPromocion
+	// Cupon aplicado (nil si no tiene o si ya no aplica)
+	Cupon          *Cupon
+	DescuentoCupon float64
+	// Motivo por el que el cupon del carrito no aplica; vacio si aplica o no hay
+	AvisoCupon string
+	// Region con la que se estimaron los impuestos (la primera direccion de
+	// envio del cliente; vacia, como en los invitados, usa las reglas generales)
+	Region    string
+	Impuestos CalculoImpuesto
+	Totales   TotalesPedido
+}
+
+// ConProblemas devuelve las lineas que no se pueden comprar tal cual.
+func (t TotalesCarrito) ConProblemas() []LineaCarrito {
+	lines := []LineaCarrito{}
+	for _, l := range t.Lineas {
+		if l.Problema() != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// itemsCarrito convierte las lineas con SKU en items de pedido; las de un SKU
+// borrado quedan afuera.
+func itemsCarrito(lines []LineaCarrito) []PedidoDetalle {
+	items := make([]PedidoDetalle, 0, len(lines))
+	for _, l := range lines {
+		if !l.IdSKU.Valid {
+			continue
+		}
+		items = append(items, PedidoDetalle{
+			IdSKU:          int(l.IdSKU.Int32),
+			Cantidad:       l.Cantidad,
+			PrecioUnitario: l.PrecioUnitario.Float64,
+			Codigo:         l.Codigo.String,
+			Variante:       l.Variante,
+		})
+	}
+	return items
+}
+
+// Lines obtiene las lineas del carrito con precio y stock actuales.
+func (m *CarritoManager) Lines(ctx context.Context, id int) ([]LineaCarrito, error) {
+	if err := requirePositive("idCarrito", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/carrito_linea_por_carrito.sql", sql.Named("cartId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[LineaCarrito](rows)
+}
+
+// Totals calcula lo que cuesta el carrito con los precios de hoy: subtotal,
+// promociones, cupon, impuestos y total, y marca las lineas con problemas
+// (SKU borrado o sin stock suficiente). Un cupon que ya no aplica no es un
+// error: queda en AvisoCupon y no descuenta.
+func (m *CarritoManager) Totals(ctx context.Context, id int) (*TotalesCarrito, error) {
+	cart, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := m.Lines(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result := &TotalesCarrito{IdCarrito: id, Lineas: lines}
+	items := itemsCarrito(lines)
+
+	if len(items) > 0 {
+		result.Promociones, err = NewPromocionManager(m.db).Evaluate(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cart.CodigoCupon.Valid && !cart.Invitado() {
+		coupon, discount, err := NewCuponManager(m.db).Discount(ctx, cart.CodigoCupon.String, int(cart.IdUsuario.Int32), items)
+		if err != nil {
+			result.AvisoCupon = err.Error()
+		} else {
+			result.Cupon, result.DescuentoCupon = coupon, discount
+		}
+	}
+	discount := totalPromociones(result.Promociones) + result.DescuentoCupon
+
+	if !cart.Invitado() {
+		result.Region, err = m.regionEstimada(ctx, int(cart.IdUsuario.Int32))
+		if err != nil {
+			return nil, err
+		}
+	}
+	taxes, err := NewImpuestoManager(m.db).Calculate(ctx, items, result.Region, discount)
+	if err != nil {
+		return nil, err
+	}
+	result.Impuestos = *taxes
+	result.Totales = calcularTotalesPedido(items, discount, *taxes, 0)
+	return result, nil
+}
+
+// regionEstimada es la region de la primera direccion de envio del cliente,
+// o vacia si no tiene.
+func (m *CarritoManager) regionEstimada(ctx context.Context, userId int) (string, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/direccion_envio_por_usuario.sql", sql.Named("userId", userId))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	addresses, err := sqlutil.ParseRow[Direccion](rows)
+	if err != nil {
+		return "", err
+	}
+	if len(addresses) == 0 {
+		return "", nil
+	}
+	return regionDireccion(&addresses[0]), nil
+}
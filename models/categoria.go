@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
+	"strings"
 
 	"tienda-online/internal/db"
 	sqlutil "tienda-online/internal/sql"
@@ -12,10 +14,86 @@ import (
 type Categoria struct {
 	IdCategoria int
 	Nombre      string
+	// NULL = categoria raiz
+	IdPadre sql.NullInt32
 }
 
 func (c Categoria) String() string {
-	return fmt.Sprintf("[ Categoria #%d | %s ]", c.IdCategoria, c.Nombre)
+	padre := "raiz"
+	if c.IdPadre.Valid {
+		padre = fmt.Sprintf("PadreID:%d", c.IdPadre.Int32)
+	}
+	return fmt.Sprintf("[ Categoria #%d | %s | %s ]", c.IdCategoria, c.Nombre, padre)
+}
+
+// NodoCategoria es una categoria dentro del arbol, con su profundidad (0 = raiz)
+// y su ruta desde la raiz.
+type NodoCategoria struct {
+	Categoria
+	Nivel int
+	Ruta  string
+}
+
+func (n NodoCategoria) String() string {
+	return fmt.Sprintf("%s#%d %s", strings.Repeat("  ", n.Nivel), n.IdCategoria, n.Nombre)
+}
+
+// separadorRuta separa los niveles en las migas de pan.
+const separadorRuta = " > "
+
+// rutaCategorias une los nombres de la raiz a la hoja: "Ropa > Hombre > Camisas".
+func rutaCategorias(path []Categoria) string {
+	names := make([]string, 0, len(path))
+	for _, c := range path {
+		names = append(names, c.Nombre)
+	}
+	return strings.Join(names, separadorRuta)
+}
+
+// arbolCategorias ordena las categorias en profundidad (cada padre seguido de
+// sus hijos, hermanos por nombre). Una categoria cuyo padre no esta en la
+// lista se trata como raiz.
+func arbolCategorias(cats []Categoria) []NodoCategoria {
+	ids := map[int]bool{}
+	for _, c := range cats {
+		ids[c.IdCategoria] = true
+	}
+	children := map[int][]Categoria{}
+	for _, c := range cats {
+		parent := 0
+		if c.IdPadre.Valid && ids[int(c.IdPadre.Int32)] {
+			parent = int(c.IdPadre.Int32)
+		}
+		children[parent] = append(children[parent], c)
+	}
+	for _, list := range children {
+		sort.Slice(list, func(i, j int) bool {
+			if !strings.EqualFold(list[i].Nombre, list[j].Nombre) {
+				return strings.ToLower(list[i].Nombre) < strings.ToLower(list[j].Nombre)
+			}
+			return list[i].IdCategoria < list[j].IdCategoria
+		})
+	}
+
+	tree := make([]NodoCategoria, 0, len(cats))
+	visited := map[int]bool{}
+	var walk func(parent, level int, path string)
+	walk = func(parent, level int, path string) {
+		for _, c := range children[parent] {
+			if visited[c.IdCategoria] {
+				continue
+			}
+			visited[c.IdCategoria] = true
+			ruta := c.Nombre
+			if path != "" {
+				ruta = path + separadorRuta + c.Nombre
+			}
+			tree = append(tree, NodoCategoria{Categoria: c, Nivel: level, Ruta: ruta})
+			walk(c.IdCategoria, level+1, ruta)
+		}
+	}
+	walk(0, 0, "")
+	return tree
 }
 
 type CategoriaManager struct {
@@ -57,7 +135,46 @@ func (m *CategoriaManager) Get(ctx context.Context, id int) (*Categoria, error)
 	return &items[0], nil
 }
 
-func (m *CategoriaManager) Create(ctx context.Context, name string) error {
+// Tree lista todas las categorias en orden de arbol.
+func (m *CategoriaManager) Tree(ctx context.Context) ([]NodoCategoria, error) {
+	cats, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return arbolCategorias(cats), nil
+}
+
+// Path devuelve la categoria y sus ancestros, de la raiz a la categoria.
+func (m *CategoriaManager) Path(ctx context.Context, id int) ([]Categoria, error) {
+	if err := requirePositive("idCategoria", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/categoria_ruta.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Categoria](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("categoria %d no encontrada", id)
+	}
+	return items, nil
+}
+
+// Breadcrumb devuelve la ruta de la categoria como texto: "Ropa > Hombre > Camisas".
+func (m *CategoriaManager) Breadcrumb(ctx context.Context, id int) (string, error) {
+	path, err := m.Path(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return rutaCategorias(path), nil
+}
+
+// Create agrega una categoria; parentId en 0 la deja como raiz.
+func (m *CategoriaManager) Create(ctx context.Context, name string, parentId int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
 	}
@@ -65,7 +182,15 @@ func (m *CategoriaManager) Create(ctx context.Context, name string) error {
 	if err != nil {
 		return err
 	}
-	_, err = db.ExecFromFile(ctx, "añadir/categoria.sql", sql.Named("name", name))
+	if parentId > 0 {
+		if _, err := m.Get(ctx, parentId); err != nil {
+			return err
+		}
+	}
+	_, err = db.ExecFromFile(ctx, "añadir/categoria.sql",
+		sql.Named("name", name),
+		sql.Named("parentId", optionalInt(parentId)),
+	)
 	return err
 }
 
@@ -87,6 +212,32 @@ func (m *CategoriaManager) Update(ctx context.Context, id int, name string) erro
 	return err
 }
 
+// Move cuelga la categoria, con toda su rama, de parentId (0 = raiz). Falla si
+// parentId es la misma categoria o una de sus descendientes.
+func (m *CategoriaManager) Move(ctx context.Context, id, parentId int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idCategoria", id); err != nil {
+		return err
+	}
+	if parentId == id {
+		return fmt.Errorf("una categoria no puede ser su propio padre")
+	}
+	if parentId > 0 {
+		if _, err := m.Get(ctx, parentId); err != nil {
+			return err
+		}
+	}
+	_, err := db.ExecFromFile(ctx, "editar/categoria_padre.sql",
+		sql.Named("id", id),
+		sql.Named("parentId", optionalInt(parentId)),
+	)
+	return err
+}
+
+// Delete borra la categoria; sus subcategorias suben a su padre y sus
+// productos quedan sin categoria.
 func (m *CategoriaManager) Delete(ctx context.Context, id int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -18,6 +18,10 @@ type Cliente struct {
 	Correo       sql.NullString
 	PasswordHash []byte
 	PasswordSalt []byte
+	// Intentos fallidos seguidos desde el ultimo acceso o bloqueo
+	IntentosFallidos int
+	BloqueadoHasta   sql.NullTime
+	UltimoAcceso     sql.NullTime
 }
 
 func (c Cliente) String() string {
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+const (
+	// MaxIntentosAcceso es cuantas contraseñas incorrectas seguidas bloquean la cuenta.
+	MaxIntentosAcceso = 5
+	// BloqueoAcceso es cuanto dura el bloqueo despues de MaxIntentosAcceso fallos.
+	BloqueoAcceso = 15 * time.Minute
+)
+
+// credencialesCliente es lo necesario para verificar un intento de acceso.
+type credencialesCliente struct {
+	IdUsuario      int
+	PasswordHash   []byte
+	Intento        []byte
+	BloqueadoHasta sql.NullTime
+}
+
+// errorAcceso es el mismo para usuario inexistente, contraseña incorrecta y
+// cuenta bloqueada, para no revelar que cuentas existen.
+func errorAcceso() error {
+	return fmt.Errorf("correo/telefono o contraseña incorrectos, o la cuenta esta bloqueada temporalmente")
+}
+
+// Authenticate verifica la contraseña del cliente identificado por correo (si
+// login tiene "@") o telefono y devuelve el cliente. Tras MaxIntentosAcceso
+// fallos seguidos la cuenta queda bloqueada por BloqueoAcceso; un acceso
+// correcto reinicia el conteo y registra la fecha. Todo intento fallido hace
+// las mismas consultas, exista o no la cuenta.
+func (m *ClienteManager) Authenticate(ctx context.Context, login, password string) (*Cliente, error) {
+	if err := ensureDB(m.db); err != nil {
+		return nil, err
+	}
+	login, err := requireNonEmpty("correo o telefono", login)
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, fmt.Errorf("contraseña no puede ser vacio")
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/cliente_credenciales.sql",
+		sql.Named("login", login),
+		sql.Named("byEmail", strings.Contains(login, "@")),
+		sql.Named("password", password),
+	)
+	if err != nil {
+		return nil, err
+	}
+	items, err := sqlutil.ParseRow[credencialesCliente](rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	if len(items) != 1 {
+		return nil, errorAcceso()
+	}
+	cred := items[0]
+
+	now := time.Now()
+	locked := cred.BloqueadoHasta.Valid && cred.BloqueadoHasta.Time.After(now)
+	match := subtle.ConstantTimeCompare(cred.PasswordHash, cred.Intento) == 1
+	if cred.IdUsuario == 0 || locked || !match {
+		// Sin cuenta o bloqueada se escribe igual, sobre ningun cliente
+		failedId := cred.IdUsuario
+		if locked {
+			failedId = 0
+		}
+		_, err := db.ExecFromFile(ctx, "editar/cliente_acceso_fallido.sql",
+			sql.Named("id", failedId),
+			sql.Named("maxAttempts", MaxIntentosAcceso),
+			sql.Named("lockedUntil", now.Add(BloqueoAcceso).UTC()),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errorAcceso()
+	}
+
+	if _, err := db.ExecFromFile(ctx, "editar/cliente_acceso.sql", sql.Named("id", cred.IdUsuario)); err != nil {
+		return nil, err
+	}
+	return m.Get(ctx, cred.IdUsuario)
+}
+
+// Unlock levanta el bloqueo de acceso del cliente y reinicia sus intentos.
+func (m *ClienteManager) Unlock(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idUsuario", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "editar/cliente_desbloquear.sql", sql.Named("id", id))
+	return err
+}
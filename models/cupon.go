@@ -0,0 +1,347 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// Valores permitidos por el CHECK de Cupon.tipo.
+const (
+	CuponPorcentaje = "Porcentaje"
+	CuponMonto      = "Monto"
+)
+
+type Cupon struct {
+	IdCupon        int
+	Codigo         string
+	Tipo           string
+	Valor          float64
+	MontoMinimo    float64
+	UsosMaximos    sql.NullInt32
+	UsosPorCliente sql.NullInt32
+	VigenteDesde   sql.NullTime
+	VigenteHasta   sql.NullTime
+	IdCategoria    sql.NullInt32
+	Activo         bool
+}
+
+func (c Cupon) String() string {
+	valor := fmt.Sprintf("%.2f", c.Valor)
+	if c.Tipo == CuponPorcentaje {
+		valor = fmt.Sprintf("%.2f%%", c.Valor)
+	}
+	limites := "sin limite"
+	if c.UsosMaximos.Valid {
+		limites = fmt.Sprintf("%d usos", c.UsosMaximos.Int32)
+	}
+	if c.UsosPorCliente.Valid {
+		limites += fmt.Sprintf(", %d por cliente", c.UsosPorCliente.Int32)
+	}
+	vigencia := "siempre"
+	if c.VigenteDesde.Valid || c.VigenteHasta.Valid {
+		vigencia = fmt.Sprintf("%s a %s", fechaCupon(c.VigenteDesde), fechaCupon(c.VigenteHasta))
+	}
+	cat := "toda categoria"
+	if c.IdCategoria.Valid {
+		cat = fmt.Sprintf("CategoriaID:%d", c.IdCategoria.Int32)
+	}
+	estado := "Activo"
+	if !c.Activo {
+		estado = "Inactivo"
+	}
+	return fmt.Sprintf("[ Cupon #%d | %s | %s %s | Minimo: %.2f | %s | %s | %s | %s ]",
+		c.IdCupon, c.Codigo, c.Tipo, valor, c.MontoMinimo, limites, vigencia, cat, estado)
+}
+
+func fechaCupon(t sql.NullTime) string {
+	if !t.Valid {
+		return "..."
+	}
+	return t.Time.Local().Format("2006-01-02")
+}
+
+// CuponUso es un canje de cupon en un pedido.
+type CuponUso struct {
+	IdUso     int
+	IdCupon   int
+	IdPedido  int
+	IdUsuario int
+	Monto     float64
+	Fecha     time.Time
+}
+
+func (u CuponUso) String() string {
+	return fmt.Sprintf("[ Uso #%d | PedidoID:%d | UsuarioID:%d | Descuento: %.2f | %s ]",
+		u.IdUso, u.IdPedido, u.IdUsuario, u.Monto, u.Fecha.Local().Format("2006-01-02 15:04"))
+}
+
+// normalizarCodigoCupon deja el codigo como se guarda: sin espacios y en mayusculas.
+func normalizarCodigoCupon(code string) (string, error) {
+	code, err := requireNonEmpty("codigo", code)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(code), nil
+}
+
+// validarCupon revisa los datos de un cupon antes de guardarlo y normaliza el
+// codigo y el tipo.
+func validarCupon(c *Cupon) error {
+	code, err := normalizarCodigoCupon(c.Codigo)
+	if err != nil {
+		return err
+	}
+	if len(code) > 30 {
+		return fmt.Errorf("el codigo no puede tener mas de 30 caracteres")
+	}
+	c.Codigo = code
+	switch strings.ToLower(strings.TrimSpace(c.Tipo)) {
+	case "porcentaje", "%":
+		c.Tipo = CuponPorcentaje
+	case "monto", "fijo":
+		c.Tipo = CuponMonto
+	default:
+		return fmt.Errorf("tipo de cupon invalido: %q (usa %s o %s)", c.Tipo, CuponPorcentaje, CuponMonto)
+	}
+	if c.Valor <= 0 {
+		return fmt.Errorf("valor debe ser mayor a cero")
+	}
+	if c.Tipo == CuponPorcentaje && c.Valor > 100 {
+		return fmt.Errorf("un porcentaje no puede superar 100")
+	}
+	if c.MontoMinimo < 0 {
+		return fmt.Errorf("monto minimo no puede ser negativo")
+	}
+	if c.VigenteDesde.Valid && c.VigenteHasta.Valid && !c.VigenteHasta.Time.After(c.VigenteDesde.Time) {
+		return fmt.Errorf("la fecha final debe ser posterior a la inicial")
+	}
+	return nil
+}
+
+// lineaCupon es lo que el calculo del descuento necesita de cada linea.
+type lineaCupon struct {
+	// Categoria del producto y sus ancestros, de la mas cercana a la raiz
+	Categorias []int
+	Importe    float64
+}
+
+// calcularDescuentoCupon valida vigencia, minimo y usos (ya contados, sin
+// pedidos cancelados) y devuelve el descuento sobre las lineas elegibles.
+func calcularDescuentoCupon(c Cupon, lines []lineaCupon, now time.Time, usedTotal, usedByCustomer int) (float64, error) {
+	if !c.Activo {
+		return 0, fmt.Errorf("el cupon %s no esta activo", c.Codigo)
+	}
+	if c.VigenteDesde.Valid && now.Before(c.VigenteDesde.Time) {
+		return 0, fmt.Errorf("el cupon %s es valido desde %s", c.Codigo, fechaCupon(c.VigenteDesde))
+	}
+	if c.VigenteHasta.Valid && !now.Before(c.VigenteHasta.Time) {
+		return 0, fmt.Errorf("el cupon %s vencio el %s", c.Codigo, fechaCupon(c.VigenteHasta))
+	}
+	if c.UsosMaximos.Valid && usedTotal >= int(c.UsosMaximos.Int32) {
+		return 0, fmt.Errorf("el cupon %s ya alcanzo su limite de usos", c.Codigo)
+	}
+	if c.UsosPorCliente.Valid && usedByCustomer >= int(c.UsosPorCliente.Int32) {
+		return 0, fmt.Errorf("ya usaste el cupon %s el maximo de veces permitido", c.Codigo)
+	}
+
+	subtotal, eligible := 0.0, 0.0
+	for _, line := range lines {
+		subtotal += line.Importe
+		if !c.IdCategoria.Valid || enCategoria(line.Categorias, int(c.IdCategoria.Int32)) {
+			eligible += line.Importe
+		}
+	}
+	if subtotal < c.MontoMinimo {
+		return 0, fmt.Errorf("el cupon %s requiere un pedido minimo de %.2f", c.Codigo, c.MontoMinimo)
+	}
+	if eligible <= 0 {
+		return 0, fmt.Errorf("el cupon %s no aplica a ningun producto del carrito", c.Codigo)
+	}
+	if c.Tipo == CuponPorcentaje {
+		return redondear(eligible * c.Valor / 100), nil
+	}
+	return redondear(min(c.Valor, eligible)), nil
+}
+
+type CuponManager struct {
+	db *sql.DB
+}
+
+func NewCuponManager(database *sql.DB) *CuponManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &CuponManager{db: database}
+}
+
+func (m *CuponManager) List(ctx context.Context) ([]Cupon, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/cupon.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Cupon](rows)
+}
+
+func (m *CuponManager) Get(ctx context.Context, id int) (*Cupon, error) {
+	if err := requirePositive("idCupon", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/cupon_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Cupon](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("cupon %d no encontrado", id)
+	}
+	return &items[0], nil
+}
+
+// GetByCode busca un cupon por codigo, sin importar mayusculas.
+func (m *CuponManager) GetByCode(ctx context.Context, code string) (*Cupon, error) {
+	code, err := normalizarCodigoCupon(code)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/cupon_por_codigo.sql", sql.Named("code", code))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Cupon](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("cupon %s no encontrado", code)
+	}
+	return &items[0], nil
+}
+
+// Create guarda un cupon nuevo; IdCupon y Activo se ignoran.
+func (m *CuponManager) Create(ctx context.Context, coupon Cupon) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := validarCupon(&coupon); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "añadir/cupon.sql", cuponParams(coupon)...)
+	return err
+}
+
+func (m *CuponManager) Update(ctx context.Context, coupon Cupon) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idCupon", coupon.IdCupon); err != nil {
+		return err
+	}
+	if err := validarCupon(&coupon); err != nil {
+		return err
+	}
+	params := append(cuponParams(coupon),
+		sql.Named("id", coupon.IdCupon),
+		sql.Named("active", coupon.Activo),
+	)
+	_, err := db.ExecFromFile(ctx, "editar/cupon.sql", params...)
+	return err
+}
+
+func cuponParams(c Cupon) []any {
+	return []any{
+		sql.Named("code", c.Codigo),
+		sql.Named("type", c.Tipo),
+		sql.Named("value", c.Valor),
+		sql.Named("minimum", c.MontoMinimo),
+		sql.Named("maxUses", c.UsosMaximos),
+		sql.Named("maxUsesPerCustomer", c.UsosPorCliente),
+		sql.Named("validFrom", c.VigenteDesde),
+		sql.Named("validUntil", c.VigenteHasta),
+		sql.Named("categoryId", c.IdCategoria),
+	}
+}
+
+// Delete borra un cupon que nunca se uso; los usados se desactivan con Update.
+func (m *CuponManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idCupon", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/cupon.sql", sql.Named("id", id))
+	return err
+}
+
+// Redemptions obtiene el historial de canjes de un cupon, del mas reciente al mas viejo.
+func (m *CuponManager) Redemptions(ctx context.Context, id int) ([]CuponUso, error) {
+	if err := requirePositive("idCupon", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/cupon_uso_por_cupon.sql", sql.Named("couponId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[CuponUso](rows)
+}
+
+// Discount calcula cuanto descuenta el cupon code a las lineas de userId.
+// Devuelve el cupon para registrar el canje al crear el pedido.
+func (m *CuponManager) Discount(ctx context.Context, code string, userId int, items []PedidoDetalle) (*Cupon, float64, error) {
+	coupon, err := m.GetByCode(ctx, code)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/cupon_uso_conteo.sql",
+		sql.Named("couponId", coupon.IdCupon),
+		sql.Named("userId", userId),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	counts, err := sqlutil.ParseRow[struct {
+		Total   int
+		Cliente int
+	}](rows)
+	rows.Close()
+	if err != nil {
+		return nil, 0, err
+	}
+	usedTotal, usedByCustomer := 0, 0
+	if len(counts) > 0 {
+		usedTotal, usedByCustomer = counts[0].Total, counts[0].Cliente
+	}
+
+	skus := NewSKUManager(m.db)
+	categories := map[int][]int{}
+	lines := make([]lineaCupon, 0, len(items))
+	for _, item := range items {
+		ids, seen := categories[item.IdSKU]
+		if !seen {
+			ids, err = skus.categorias(ctx, item.IdSKU)
+			if err != nil {
+				return nil, 0, err
+			}
+			categories[item.IdSKU] = ids
+		}
+		lines = append(lines, lineaCupon{Categorias: ids, Importe: item.Importe()})
+	}
+	discount, err := calcularDescuentoCupon(*coupon, lines, time.Now(), usedTotal, usedByCustomer)
+	if err != nil {
+		return nil, 0, err
+	}
+	return coupon, discount, nil
+}
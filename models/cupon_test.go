@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalcularDescuentoCupon(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	fecha := func(d time.Duration) sql.NullTime { return sql.NullTime{Time: now.Add(d), Valid: true} }
+	limite := func(n int32) sql.NullInt32 { return sql.NullInt32{Int32: n, Valid: true} }
+	categoria := func(id int32) sql.NullInt32 { return sql.NullInt32{Int32: id, Valid: true} }
+
+	// Ropa (2) > Camisas (5); Hogar (3)
+	lines := []lineaCupon{
+		{Categorias: []int{5, 2}, Importe: 60},
+		{Categorias: []int{3}, Importe: 40},
+	}
+	porcentaje := Cupon{Codigo: "DIEZ", Tipo: CuponPorcentaje, Valor: 10, Activo: true}
+	monto := Cupon{Codigo: "VEINTE", Tipo: CuponMonto, Valor: 20, Activo: true}
+	with := func(c Cupon, f func(*Cupon)) Cupon { f(&c); return c }
+
+	tests := []struct {
+		name           string
+		cupon          Cupon
+		lines          []lineaCupon
+		usedTotal      int
+		usedByCustomer int
+		want           float64
+		wantErr        string
+	}{
+		{"porcentaje sobre todo", porcentaje, lines, 0, 0, 10, ""},
+		{"monto fijo", monto, lines, 0, 0, 20, ""},
+		{"monto fijo no supera lo elegible", with(monto, func(c *Cupon) { c.Valor = 500 }), lines, 0, 0, 100, ""},
+		{"redondea a centavos", with(porcentaje, func(c *Cupon) { c.Valor = 12.345 }), lines, 0, 0, 12.35, ""},
+		{"inactivo", with(porcentaje, func(c *Cupon) { c.Activo = false }), lines, 0, 0, 0, "no esta activo"},
+
+		{"antes de la vigencia", with(porcentaje, func(c *Cupon) { c.VigenteDesde = fecha(time.Hour) }), lines, 0, 0, 0, "es valido desde"},
+		{"justo al empezar", with(porcentaje, func(c *Cupon) { c.VigenteDesde = fecha(0) }), lines, 0, 0, 10, ""},
+		{"dentro de la ventana", with(porcentaje, func(c *Cupon) {
+			c.VigenteDesde, c.VigenteHasta = fecha(-time.Hour), fecha(time.Hour)
+		}), lines, 0, 0, 10, ""},
+		{"justo al vencer", with(porcentaje, func(c *Cupon) { c.VigenteHasta = fecha(0) }), lines, 0, 0, 0, "vencio"},
+		{"vencido", with(porcentaje, func(c *Cupon) { c.VigenteHasta = fecha(-time.Hour) }), lines, 0, 0, 0, "vencio"},
+
+		{"usos por debajo del limite", with(porcentaje, func(c *Cupon) { c.UsosMaximos = limite(3) }), lines, 2, 0, 10, ""},
+		{"limite de usos alcanzado", with(porcentaje, func(c *Cupon) { c.UsosMaximos = limite(3) }), lines, 3, 0, 0, "limite de usos"},
+		{"limite por cliente alcanzado", with(porcentaje, func(c *Cupon) { c.UsosPorCliente = limite(1) }), lines, 0, 1, 0, "maximo de veces"},
+		{"sin limite ignora los usos", porcentaje, lines, 1000, 1000, 10, ""},
+
+		{"minimo alcanzado", with(porcentaje, func(c *Cupon) { c.MontoMinimo = 100 }), lines, 0, 0, 10, ""},
+		{"minimo no alcanzado", with(porcentaje, func(c *Cupon) { c.MontoMinimo = 100.01 }), lines, 0, 0, 0, "pedido minimo"},
+		{"minimo cuenta lineas no elegibles", with(porcentaje, func(c *Cupon) {
+			c.MontoMinimo, c.IdCategoria = 90, categoria(3)
+		}), lines, 0, 0, 4, ""},
+
+		{"categoria directa", with(porcentaje, func(c *Cupon) { c.IdCategoria = categoria(5) }), lines, 0, 0, 6, ""},
+		{"categoria ancestro", with(porcentaje, func(c *Cupon) { c.IdCategoria = categoria(2) }), lines, 0, 0, 6, ""},
+		{"monto fijo topado a la categoria", with(monto, func(c *Cupon) { c.IdCategoria = categoria(3) }), lines, 0, 0, 20, ""},
+		{"monto fijo mayor que la categoria", with(monto, func(c *Cupon) { c.Valor, c.IdCategoria = 50, categoria(3) }), lines, 0, 0, 40, ""},
+		{"categoria sin productos", with(porcentaje, func(c *Cupon) { c.IdCategoria = categoria(9) }), lines, 0, 0, 0, "no aplica"},
+		{"carrito vacio", porcentaje, nil, 0, 0, 0, "no aplica"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calcularDescuentoCupon(tt.cupon, tt.lines, now, tt.usedTotal, tt.usedByCustomer)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("descuento = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
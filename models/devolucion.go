@@ -3,7 +3,9 @@ package models
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"tienda-online/internal"
@@ -15,14 +17,14 @@ type Devolucion struct {
 	IdDevolucion int
 	IdPedido     int
 	Fecha        time.Time
-	Estado       sql.NullString
+	Estado       EstadoDevolucion
 	Descripcion  sql.NullString
 	Resolucion   sql.NullString
 }
 
 func (d Devolucion) String() string {
 	return fmt.Sprintf("[ Devolucion #%d | PedidoID:%d | %s | Estado:%s | Desc:%s | Resol:%s ]",
-		d.IdDevolucion, d.IdPedido, d.Fecha.Format("2006-01-02"), internal.NullString(d.Estado),
+		d.IdDevolucion, d.IdPedido, d.Fecha.Format("2006-01-02"), d.Estado,
 		internal.NullString(d.Descripcion), internal.NullString(d.Resolucion))
 }
 
@@ -65,44 +67,182 @@ func (m *DevolucionManager) Get(ctx context.Context, id int) (*Devolucion, error
 	return &items[0], nil
 }
 
-func (m *DevolucionManager) Create(ctx context.Context, orderId int, fecha time.Time, estado, descripcion, resolucion string) error {
+// Create abre una solicitud de devolucion para algunas lineas del pedido y
+// devuelve su ID. Solo se aceptan pedidos entregados dentro de la ventana de
+// devolucion (ver SetReturnWindowDays), y la suma de lo devuelto por linea no
+// puede superar lo comprado; esto se vuelve a revisar al guardar, con las
+// lineas del pedido bloqueadas, por si otra devolucion entro al mismo tiempo.
+func (m *DevolucionManager) Create(ctx context.Context, orderId int, lines []LineaDevolucion, descripcion, actor string) (int, error) {
 	if err := ensureDB(m.db); err != nil {
-		return err
+		return 0, err
 	}
 	if err := requirePositive("idPedido", orderId); err != nil {
-		return err
+		return 0, err
+	}
+	actor, err := requireNonEmpty("actor", actor)
+	if err != nil {
+		return 0, err
+	}
+	order, err := NewPedidoManager(m.db).Get(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	if order.Estado != PedidoEntregado || !order.FechaEntrega.Valid {
+		return 0, fmt.Errorf("pedido %d esta %s, solo se devuelven pedidos entregados", orderId, order.Estado)
+	}
+	now := time.Now()
+	days := ReturnWindowDays()
+	if !dentroDeVentana(order.FechaEntrega.Time, now, days) {
+		return 0, fmt.Errorf("pedido %d fue entregado el %s, fuera de la ventana de %d dias",
+			orderId, order.FechaEntrega.Time.Local().Format("2006-01-02"), days)
 	}
-	_, err := db.ExecFromFile(ctx, "añadir/devolucion.sql",
+
+	available, err := m.Returnable(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	refundLines, err := armarLineasDevolucion(lines, available, *order)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(refundLines)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/devolucion.sql",
 		sql.Named("orderId", orderId),
-		sql.Named("date", fecha),
-		sql.Named("status", optionalString(estado)),
+		sql.Named("date", now),
 		sql.Named("description", optionalString(descripcion)),
-		sql.Named("resolution", optionalString(resolucion)),
+		sql.Named("lines", string(payload)),
+		sql.Named("actor", actor),
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	created, err := sqlutil.ParseRow[struct{ IdDevolucion int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(created) == 0 {
+		return 0, fmt.Errorf("no se obtuvo el ID de la devolucion creada")
+	}
+	return created[0].IdDevolucion, nil
 }
 
-func (m *DevolucionManager) Update(ctx context.Context, id, orderId int, fecha time.Time, estado, descripcion, resolucion string) error {
-	if err := ensureDB(m.db); err != nil {
-		return err
+// Returnable lista las lineas del pedido con lo que ya se devolvio de cada una.
+func (m *DevolucionManager) Returnable(ctx context.Context, orderId int) ([]LineaDevolvible, error) {
+	if err := requirePositive("idPedido", orderId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/pedido_detalle_devuelto.sql", sql.Named("orderId", orderId))
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
+	return sqlutil.ParseRow[LineaDevolvible](rows)
+}
+
+// Lines obtiene las lineas de una devolucion.
+func (m *DevolucionManager) Lines(ctx context.Context, id int) ([]DevolucionDetalle, error) {
 	if err := requirePositive("idDevolucion", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/devolucion_detalle_por_devolucion.sql", sql.Named("returnId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[DevolucionDetalle](rows)
+}
+
+// Update cambia la descripcion; el estado solo se cambia con Transition.
+func (m *DevolucionManager) Update(ctx context.Context, id int, descripcion string) error {
+	if err := ensureDB(m.db); err != nil {
 		return err
 	}
-	if err := requirePositive("idPedido", orderId); err != nil {
+	if err := requirePositive("idDevolucion", id); err != nil {
 		return err
 	}
 	_, err := db.ExecFromFile(ctx, "editar/devolucion.sql",
 		sql.Named("id", id),
-		sql.Named("orderId", orderId),
-		sql.Named("date", fecha),
-		sql.Named("status", optionalString(estado)),
 		sql.Named("description", optionalString(descripcion)),
-		sql.Named("resolution", optionalString(resolucion)),
 	)
 	return err
 }
 
+// Transition mueve la devolucion al estado indicado guardando la nota de
+// resolucion. Al pasar a Recibida el SQL repone el stock de las lineas
+// devueltas. Al pasar a Reembolsada devuelve el monto de la devolucion por la
+// pasarela del pago capturado del pedido, y el SQL registra el reembolso en el
+// pago y, cuando todo el pedido queda reembolsado, lo pasa a Devuelto.
+func (m *DevolucionManager) Transition(ctx context.Context, id int, to EstadoDevolucion, note, actor string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	actor, err := requireNonEmpty("actor", actor)
+	if err != nil {
+		return err
+	}
+	current, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !current.Estado.PuedeCambiarA(to) {
+		return fmt.Errorf("devolucion %d: no se puede pasar de %s a %s", id, current.Estado, to)
+	}
+	if to == DevolucionRechazada && strings.TrimSpace(note) == "" {
+		return fmt.Errorf("hay que indicar el motivo del rechazo")
+	}
+
+	// El reembolso sale por la pasarela del pago capturado; el pago se
+	// actualiza en la misma transaccion que la devolucion
+	paymentId, amount := 0, 0.0
+	if to == DevolucionReembolsada {
+		lines, err := m.Lines(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			amount += line.MontoReembolso
+		}
+		payment, err := NewPagoManager(m.db).refundForReturn(ctx, current.IdPedido, amount)
+		if err != nil {
+			return fmt.Errorf("devolucion %d: %w", id, err)
+		}
+		if payment != nil {
+			paymentId = payment.IdPago
+		}
+	}
+	_, err = db.ExecFromFile(ctx, "editar/devolucion_estado.sql",
+		sql.Named("id", id),
+		sql.Named("from", string(current.Estado)),
+		sql.Named("to", string(to)),
+		sql.Named("note", optionalString(note)),
+		sql.Named("actor", actor),
+		sql.Named("paymentId", optionalInt(paymentId)),
+		sql.Named("refundAmount", redondear(amount)),
+	)
+	if err != nil && paymentId > 0 {
+		return fmt.Errorf("devolucion %d: se reembolsaron %.2f por el pago %d, pero no se pudo registrar: %w", id, amount, paymentId, err)
+	}
+	return err
+}
+
+// History obtiene los cambios de estado de una devolucion con sus notas.
+func (m *DevolucionManager) History(ctx context.Context, id int) ([]DevolucionHistorial, error) {
+	if err := requirePositive("idDevolucion", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/devolucion_historial.sql", sql.Named("returnId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[DevolucionHistorial](rows)
+}
+
 func (m *DevolucionManager) Delete(ctx context.Context, id int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// DevolucionDetalle es una linea de pedido devuelta con su monto a reembolsar.
+type DevolucionDetalle struct {
+	IdDetalle       int
+	IdDevolucion    int
+	IdPedidoDetalle int
+	Cantidad        int
+	MontoReembolso  float64
+}
+
+func (d DevolucionDetalle) String() string {
+	return fmt.Sprintf("[ Linea devuelta #%d | LineaPedido:%d | Cantidad:%d | Reembolso: %.2f ]",
+		d.IdDetalle, d.IdPedidoDetalle, d.Cantidad, d.MontoReembolso)
+}
+
+// LineaDevolucion es lo que el cliente pide devolver de una linea del pedido.
+type LineaDevolucion struct {
+	IdPedidoDetalle int
+	Cantidad        int
+}
+
+// LineaDevolvible es una linea del pedido con lo que ya se devolvio de ella.
+type LineaDevolvible struct {
+	IdDetalle      int
+	IdSKU          int
+	Cantidad       int
+	PrecioUnitario float64
+	Devuelto       int
+	Codigo         string
+	Variante       sql.NullString
+}
+
+func (l LineaDevolvible) String() string {
+	return fmt.Sprintf("[ Linea #%d | %s | Comprado:%d | Devuelto:%d | Disponible:%d | Precio: %.2f ]",
+		l.IdDetalle, etiquetaSKU(l.IdSKU, l.Codigo, l.Variante), l.Cantidad, l.Devuelto, l.Disponible(), l.PrecioUnitario)
+}
+
+// Disponible es cuanto se puede devolver todavia de la linea.
+func (l LineaDevolvible) Disponible() int {
+	return max(l.Cantidad-l.Devuelto, 0)
+}
+
+// lineaReembolso es la forma en que las lineas viajan como JSON a añadir/devolucion.sql.
+type lineaReembolso struct {
+	IdPedidoDetalle int     `json:"idPedidoDetalle"`
+	Cantidad        int     `json:"cantidad"`
+	MontoReembolso  float64 `json:"montoReembolso"`
+}
+
+// calcularReembolso devuelve lo pagado por quantity unidades de la linea: su
+// parte proporcional de lo cobrado por productos (total sin envio), que ya
+// descuenta el descuento y suma el impuesto si no venia en el precio.
+func calcularReembolso(unitPrice float64, quantity int, order Pedido) float64 {
+	amount := unitPrice * float64(quantity)
+	if order.Subtotal > 0 {
+		amount = amount * (order.Total - order.Envio) / order.Subtotal
+	}
+	return redondear(max(amount, 0))
+}
+
+// armarLineasDevolucion valida lo pedido contra lo comprado y lo ya devuelto
+// (agrupando lineas repetidas) y calcula el reembolso de cada una.
+func armarLineasDevolucion(requested []LineaDevolucion, available []LineaDevolvible, order Pedido) ([]lineaReembolso, error) {
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("la devolucion debe incluir al menos una linea")
+	}
+	byId := map[int]LineaDevolvible{}
+	for _, line := range available {
+		byId[line.IdDetalle] = line
+	}
+
+	quantities := map[int]int{}
+	ids := []int{}
+	for _, r := range requested {
+		if err := requirePositive("idPedidoDetalle", r.IdPedidoDetalle); err != nil {
+			return nil, err
+		}
+		if err := requirePositive("cantidad", r.Cantidad); err != nil {
+			return nil, err
+		}
+		if _, ok := byId[r.IdPedidoDetalle]; !ok {
+			return nil, fmt.Errorf("la linea %d no pertenece al pedido %d", r.IdPedidoDetalle, order.IdPedido)
+		}
+		if _, seen := quantities[r.IdPedidoDetalle]; !seen {
+			ids = append(ids, r.IdPedidoDetalle)
+		}
+		quantities[r.IdPedidoDetalle] += r.Cantidad
+	}
+
+	lines := make([]lineaReembolso, 0, len(ids))
+	for _, id := range ids {
+		line := byId[id]
+		qty := quantities[id]
+		if qty > line.Disponible() {
+			return nil, fmt.Errorf("linea %d: se piden %d unidades pero solo quedan %d por devolver", id, qty, line.Disponible())
+		}
+		lines = append(lines, lineaReembolso{
+			IdPedidoDetalle: id,
+			Cantidad:        qty,
+			MontoReembolso:  calcularReembolso(line.PrecioUnitario, qty, order),
+		})
+	}
+	return lines, nil
+}
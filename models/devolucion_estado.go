@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"tienda-online/internal"
+)
+
+// EstadoDevolucion es el estado de una devolucion (columna Devolucion.estado).
+type EstadoDevolucion string
+
+const (
+	DevolucionSolicitada  EstadoDevolucion = "Solicitada"
+	DevolucionAprobada    EstadoDevolucion = "Aprobada"
+	DevolucionRecibida    EstadoDevolucion = "Recibida"
+	DevolucionReembolsada EstadoDevolucion = "Reembolsada"
+	DevolucionRechazada   EstadoDevolucion = "Rechazada"
+)
+
+// EstadosDevolucion lista todos los estados en el orden normal del flujo.
+var EstadosDevolucion = []EstadoDevolucion{
+	DevolucionSolicitada, DevolucionAprobada, DevolucionRecibida, DevolucionReembolsada, DevolucionRechazada,
+}
+
+// transicionesDevolucion es la tabla de cambios permitidos; Reembolsada y
+// Rechazada son finales. Una devolucion aprobada todavia se puede rechazar si
+// el articulo no llega o llega en mal estado.
+var transicionesDevolucion = map[EstadoDevolucion][]EstadoDevolucion{
+	DevolucionSolicitada: {DevolucionAprobada, DevolucionRechazada},
+	DevolucionAprobada:   {DevolucionRecibida, DevolucionRechazada},
+	DevolucionRecibida:   {DevolucionReembolsada},
+}
+
+// ParseEstadoDevolucion acepta el nombre del estado sin importar mayusculas.
+func ParseEstadoDevolucion(value string) (EstadoDevolucion, error) {
+	trim := strings.TrimSpace(value)
+	for _, e := range EstadosDevolucion {
+		if strings.EqualFold(string(e), trim) {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("estado de devolucion invalido: %q", value)
+}
+
+// Siguientes devuelve los estados a los que se puede pasar desde e.
+func (e EstadoDevolucion) Siguientes() []EstadoDevolucion {
+	return transicionesDevolucion[e]
+}
+
+// PuedeCambiarA indica si la tabla de transiciones permite pasar de e a to.
+func (e EstadoDevolucion) PuedeCambiarA(to EstadoDevolucion) bool {
+	for _, next := range transicionesDevolucion[e] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Final indica si ya no se permite ninguna transicion.
+func (e EstadoDevolucion) Final() bool {
+	return len(transicionesDevolucion[e]) == 0
+}
+
+// DevolucionHistorial es un cambio de estado de una devolucion con su nota.
+type DevolucionHistorial struct {
+	IdHistorial    int
+	IdDevolucion   int
+	EstadoAnterior sql.NullString
+	EstadoNuevo    EstadoDevolucion
+	Fecha          time.Time
+	Actor          string
+	Nota           sql.NullString
+}
+
+func (h DevolucionHistorial) String() string {
+	return fmt.Sprintf("[ %s | %s -> %s | por %s | Nota: %s ]",
+		h.Fecha.Local().Format("2006-01-02 15:04:05"), internal.NullString(h.EstadoAnterior), h.EstadoNuevo,
+		h.Actor, internal.NullString(h.Nota))
+}
+
+// DefaultReturnWindowDays son los dias despues de la entrega en que se acepta una devolucion.
+const DefaultReturnWindowDays = 30
+
+var (
+	returnWindowMu   sync.RWMutex
+	returnWindowDays = DefaultReturnWindowDays
+)
+
+// SetReturnWindowDays cambia la ventana de devolucion; debe ser mayor a cero.
+func SetReturnWindowDays(days int) error {
+	if err := requirePositive("ventana de devolucion", days); err != nil {
+		return err
+	}
+	returnWindowMu.Lock()
+	defer returnWindowMu.Unlock()
+	returnWindowDays = days
+	return nil
+}
+
+// ReturnWindowDays devuelve la ventana de devolucion vigente.
+func ReturnWindowDays() int {
+	returnWindowMu.RLock()
+	defer returnWindowMu.RUnlock()
+	return returnWindowDays
+}
+
+// dentroDeVentana indica si now cae dentro de los dias permitidos desde la entrega.
+func dentroDeVentana(delivered, now time.Time, days int) bool {
+	limit := delivered.AddDate(0, 0, days)
+	return !now.After(limit)
+}
@@ -4,21 +4,43 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"tienda-online/internal"
 	"tienda-online/internal/db"
 	sqlutil "tienda-online/internal/sql"
 )
 
+// Valores permitidos por el CHECK de Direccion.tipo.
+const (
+	DireccionEnvio       = "Envío"
+	DireccionFacturacion = "Facturación"
+)
+
+// normalizarTipoDireccion acepta el tipo con o sin tilde y devuelve el valor
+// exacto que espera el CHECK de la tabla.
+func normalizarTipoDireccion(tipo string) (string, error) {
+	trim := strings.ToLower(strings.TrimSpace(tipo))
+	switch trim {
+	case "envío", "envio":
+		return DireccionEnvio, nil
+	case "facturación", "facturacion":
+		return DireccionFacturacion, nil
+	}
+	return "", fmt.Errorf("tipo de direccion invalido: %q (usa %s o %s)", tipo, DireccionEnvio, DireccionFacturacion)
+}
+
 type Direccion struct {
 	IdDirección int
 	IdUsuario   int
 	Tipo        string
 	Detalle     sql.NullString
+	Region      sql.NullString
 }
 
 func (d Direccion) String() string {
-	return fmt.Sprintf("[ Direccion #%d | UsuarioID:%d | %s | %s ]", d.IdDirección, d.IdUsuario, d.Tipo, internal.NullString(d.Detalle))
+	return fmt.Sprintf("[ Direccion #%d | UsuarioID:%d | %s | %s | Region: %s ]",
+		d.IdDirección, d.IdUsuario, d.Tipo, internal.NullString(d.Detalle), internal.NullString(d.Region))
 }
 
 type DireccionManager struct {
@@ -60,14 +82,14 @@ func (m *DireccionManager) Get(ctx context.Context, id int) (*Direccion, error)
 	return &items[0], nil
 }
 
-func (m *DireccionManager) Create(ctx context.Context, userId int, tipo, detalle string) error {
+func (m *DireccionManager) Create(ctx context.Context, userId int, tipo, detalle, region string) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
 	}
 	if err := requirePositive("idUsuario", userId); err != nil {
 		return err
 	}
-	tipo, err := requireNonEmpty("tipo", tipo)
+	tipo, err := normalizarTipoDireccion(tipo)
 	if err != nil {
 		return err
 	}
@@ -75,11 +97,12 @@ func (m *DireccionManager) Create(ctx context.Context, userId int, tipo, detalle
 		sql.Named("userId", userId),
 		sql.Named("type", tipo),
 		sql.Named("detail", optionalString(detalle)),
+		sql.Named("region", optionalString(region)),
 	)
 	return err
 }
 
-func (m *DireccionManager) Update(ctx context.Context, id, userId int, tipo, detalle string) error {
+func (m *DireccionManager) Update(ctx context.Context, id, userId int, tipo, detalle, region string) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
 	}
@@ -89,7 +112,7 @@ func (m *DireccionManager) Update(ctx context.Context, id, userId int, tipo, det
 	if err := requirePositive("idUsuario", userId); err != nil {
 		return err
 	}
-	tipo, err := requireNonEmpty("tipo", tipo)
+	tipo, err := normalizarTipoDireccion(tipo)
 	if err != nil {
 		return err
 	}
@@ -98,6 +121,7 @@ func (m *DireccionManager) Update(ctx context.Context, id, userId int, tipo, det
 		sql.Named("userId", userId),
 		sql.Named("type", tipo),
 		sql.Named("detail", optionalString(detalle)),
+		sql.Named("region", optionalString(region)),
 	)
 	return err
 }
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// Envio es la guia que el transportista genero para un pedido.
+type Envio struct {
+	IdEnvio           int
+	IdPedido          int
+	IdMetodoEnvio     int
+	Transportista     string
+	NumeroSeguimiento string
+	PesoKg            float64
+	FechaCreacion     time.Time
+}
+
+func (e Envio) String() string {
+	return fmt.Sprintf("[ Envio #%d | PedidoID:%d | %s %s | %.3f kg | %s ]",
+		e.IdEnvio, e.IdPedido, e.Transportista, e.NumeroSeguimiento, e.PesoKg,
+		e.FechaCreacion.Local().Format("2006-01-02 15:04"))
+}
+
+type EnvioManager struct {
+	db *sql.DB
+}
+
+func NewEnvioManager(database *sql.DB) *EnvioManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &EnvioManager{db: database}
+}
+
+func (m *EnvioManager) List(ctx context.Context) ([]Envio, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/envio.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Envio](rows)
+}
+
+func (m *EnvioManager) Get(ctx context.Context, id int) (*Envio, error) {
+	if err := requirePositive("idEnvio", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/envio_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Envio](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("envio %d no encontrado", id)
+	}
+	return &items[0], nil
+}
+
+// ListByPedido obtiene los envios de un pedido.
+func (m *EnvioManager) ListByPedido(ctx context.Context, orderId int) ([]Envio, error) {
+	if err := requirePositive("idPedido", orderId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/envio_por_pedido.sql", sql.Named("orderId", orderId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Envio](rows)
+}
+
+// Ship pide la guia al transportista del metodo elegido en el pedido, la
+// guarda y pasa el pedido de Pagado a Enviado. Devuelve el ID del envio.
+func (m *EnvioManager) Ship(ctx context.Context, orderId int, actor string) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	actor, err := requireNonEmpty("actor", actor)
+	if err != nil {
+		return 0, err
+	}
+	orders := NewPedidoManager(m.db)
+	order, err := orders.Get(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	if order.Estado != PedidoPagado {
+		return 0, fmt.Errorf("pedido %d esta %s, solo se envian pedidos pagados", orderId, order.Estado)
+	}
+	if !order.IdMetodoEnvio.Valid {
+		return 0, fmt.Errorf("pedido %d no tiene metodo de envio", orderId)
+	}
+	if !order.IdDireccionEnvio.Valid {
+		return 0, fmt.Errorf("pedido %d no tiene direccion de envio", orderId)
+	}
+	method, err := NewMetodoEnvioManager(m.db).Get(ctx, int(order.IdMetodoEnvio.Int32))
+	if err != nil {
+		return 0, err
+	}
+	transportista, err := NewTransportistaManager(m.db).Get(ctx, method.IdTransportista)
+	if err != nil {
+		return 0, err
+	}
+	carrier, err := carrierByCode(transportista.Codigo)
+	if err != nil {
+		return 0, err
+	}
+	address, err := NewDireccionManager(m.db).Get(ctx, int(order.IdDireccionEnvio.Int32))
+	if err != nil {
+		return 0, err
+	}
+	items, err := orders.Items(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	weight, err := pesoLineas(ctx, NewSKUManager(m.db), items)
+	if err != nil {
+		return 0, err
+	}
+
+	destino := []string{}
+	if address.Detalle.Valid {
+		destino = append(destino, strings.TrimSpace(address.Detalle.String))
+	}
+	if region := regionDireccion(address); region != "" {
+		destino = append(destino, region)
+	}
+	tracking, err := carrier.CreateShipment(ctx, ShipmentRequest{
+		IdPedido: orderId,
+		Metodo:   method.Nombre,
+		PesoKg:   weight,
+		Destino:  strings.Join(destino, ", "),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/envio.sql",
+		sql.Named("orderId", orderId),
+		sql.Named("methodId", method.IdMetodoEnvio),
+		sql.Named("carrier", carrier.Code()),
+		sql.Named("tracking", tracking),
+		sql.Named("weight", weight),
+		sql.Named("actor", actor),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	created, err := sqlutil.ParseRow[struct{ IdEnvio int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(created) == 0 {
+		return 0, fmt.Errorf("no se obtuvo el ID del envio creado")
+	}
+	return created[0].IdEnvio, nil
+}
+
+// Track consulta al transportista el estado de la guia de un envio.
+func (m *EnvioManager) Track(ctx context.Context, id int) (string, error) {
+	shipment, err := m.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	carrier, err := carrierByCode(shipment.Transportista)
+	if err != nil {
+		return "", err
+	}
+	return carrier.Track(ctx, shipment.NumeroSeguimiento)
+}
+
+// pesoLineas suma el peso de las lineas con el peso actual de cada SKU.
+func pesoLineas(ctx context.Context, skus *SKUManager, items []PedidoDetalle) (float64, error) {
+	weights := map[int]float64{}
+	total := 0.0
+	for _, item := range items {
+		weight, seen := weights[item.IdSKU]
+		if !seen {
+			sku, err := skus.Get(ctx, item.IdSKU)
+			if err != nil {
+				return 0, err
+			}
+			weight = sku.Peso
+			weights[item.IdSKU] = weight
+		}
+		total += weight * float64(item.Cantidad)
+	}
+	return total, nil
+}
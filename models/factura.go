@@ -0,0 +1,258 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"tienda-online/internal/db"
+	"tienda-online/internal/pdf"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// DefaultInvoiceSeries es la serie que se usa si no se indica otra.
+const DefaultInvoiceSeries = "A"
+
+// Factura guarda una copia de los datos del pedido al momento de emitirla.
+type Factura struct {
+	IdFactura            int
+	Serie                string
+	Numero               int
+	IdPedido             int
+	Fecha                time.Time
+	NombreCliente        string
+	DireccionFacturacion string
+	Subtotal             float64
+	Descuento            float64
+	Impuesto             float64
+	Envio                float64
+	Total                float64
+}
+
+// Folio es el identificador impreso de la factura, por ejemplo A-000012.
+func (f Factura) Folio() string {
+	return fmt.Sprintf("%s-%06d", f.Serie, f.Numero)
+}
+
+func (f Factura) String() string {
+	return fmt.Sprintf("[ Factura %s | PedidoID:%d | %s | %s | Total: %.2f ]",
+		f.Folio(), f.IdPedido, f.Fecha.Local().Format("2006-01-02"), f.NombreCliente, f.Total)
+}
+
+type FacturaDetalle struct {
+	IdDetalle      int
+	IdFactura      int
+	IdSKU          int
+	Descripcion    string
+	Cantidad       int
+	PrecioUnitario float64
+	Importe        float64
+}
+
+func (d FacturaDetalle) String() string {
+	return fmt.Sprintf("[ %s | SKU:%d | Cantidad:%d | Precio: %.2f | Importe: %.2f ]",
+		d.Descripcion, d.IdSKU, d.Cantidad, d.PrecioUnitario, d.Importe)
+}
+
+// facturaDocumento es lo que reciben las plantillas de templates/.
+type facturaDocumento struct {
+	Factura   Factura
+	Lineas    []FacturaDetalle
+	Impuestos []PedidoImpuesto
+}
+
+type FacturaManager struct {
+	db *sql.DB
+}
+
+func NewFacturaManager(database *sql.DB) *FacturaManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &FacturaManager{db: database}
+}
+
+func (m *FacturaManager) List(ctx context.Context) ([]Factura, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/factura.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Factura](rows)
+}
+
+func (m *FacturaManager) Get(ctx context.Context, id int) (*Factura, error) {
+	if err := requirePositive("idFactura", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/factura_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Factura](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("factura %d no encontrada", id)
+	}
+	return &items[0], nil
+}
+
+// GetByPedido obtiene la factura emitida para un pedido, o nil si no tiene.
+func (m *FacturaManager) GetByPedido(ctx context.Context, orderId int) (*Factura, error) {
+	if err := requirePositive("idPedido", orderId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/factura_por_pedido.sql", sql.Named("orderId", orderId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Factura](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+// Lines obtiene las lineas de una factura.
+func (m *FacturaManager) Lines(ctx context.Context, id int) ([]FacturaDetalle, error) {
+	if err := requirePositive("idFactura", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/factura_detalle_por_factura.sql", sql.Named("invoiceId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[FacturaDetalle](rows)
+}
+
+// Issue emite la factura de un pedido cobrado en la serie indicada y devuelve
+// su ID. El numero se asigna en la misma transaccion, sin dejar huecos.
+func (m *FacturaManager) Issue(ctx context.Context, orderId int, series string) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	series = strings.ToUpper(strings.TrimSpace(series))
+	if series == "" {
+		series = DefaultInvoiceSeries
+	}
+	if len(series) > 10 {
+		return 0, fmt.Errorf("la serie no puede tener mas de 10 caracteres")
+	}
+	order, err := NewPedidoManager(m.db).Get(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	switch order.Estado {
+	case PedidoPendiente, PedidoCancelado:
+		return 0, fmt.Errorf("pedido %d esta %s, solo se facturan pedidos cobrados", orderId, order.Estado)
+	}
+	if !order.IdDireccionFacturacion.Valid {
+		return 0, fmt.Errorf("pedido %d no tiene direccion de facturacion", orderId)
+	}
+	existing, err := m.GetByPedido(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		return 0, fmt.Errorf("pedido %d ya tiene la factura %s", orderId, existing.Folio())
+	}
+
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/factura.sql",
+		sql.Named("orderId", orderId),
+		sql.Named("series", series),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	created, err := sqlutil.ParseRow[struct{ IdFactura int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(created) == 0 {
+		return 0, fmt.Errorf("no se obtuvo el ID de la factura creada")
+	}
+	return created[0].IdFactura, nil
+}
+
+// Render genera la factura como HTML y PDF dentro de dir usando las plantillas
+// templates/factura.html y templates/factura.txt. Devuelve las rutas creadas.
+func (m *FacturaManager) Render(ctx context.Context, id int, dir string) (string, string, error) {
+	invoice, err := m.Get(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	lines, err := m.Lines(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	// El desglose de impuestos del pedido no cambia despues de crearlo
+	taxes, err := NewPedidoManager(m.db).Taxes(ctx, invoice.IdPedido)
+	if err != nil {
+		return "", "", err
+	}
+	doc := facturaDocumento{Factura: *invoice, Lineas: lines, Impuestos: taxes}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", "", fmt.Errorf("creando carpeta %s: %w", dir, err)
+	}
+	base := filepath.Join(dir, "factura_"+invoice.Folio())
+
+	htmlPath := base + ".html"
+	if err := renderFacturaHTML(doc, htmlPath); err != nil {
+		return "", "", err
+	}
+	pdfPath := base + ".pdf"
+	if err := renderFacturaPDF(doc, pdfPath); err != nil {
+		return htmlPath, "", err
+	}
+	return htmlPath, pdfPath, nil
+}
+
+func renderFacturaHTML(doc facturaDocumento, path string) error {
+	tmpl, err := htmltemplate.ParseFiles(filepath.Join("templates", "factura.html"))
+	if err != nil {
+		return fmt.Errorf("leyendo plantilla HTML: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return tmpl.Execute(f, doc)
+}
+
+// renderFacturaPDF llena la plantilla de texto y la pasa a PDF linea por linea.
+func renderFacturaPDF(doc facturaDocumento, path string) error {
+	tmpl, err := texttemplate.New("factura.txt").
+		Funcs(texttemplate.FuncMap{"neg": func(v float64) float64 { return -v }}).
+		ParseFiles(filepath.Join("templates", "factura.txt"))
+	if err != nil {
+		return fmt.Errorf("leyendo plantilla de texto: %w", err)
+	}
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, doc); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return pdf.WriteText(f, strings.Split(strings.TrimRight(text.String(), "\n"), "\n"))
+}
@@ -42,3 +42,10 @@ func optionalInt(value int) sql.NullInt32 {
 	}
 	return sql.NullInt32{Int32: int32(value), Valid: true}
 }
+
+func optionalAmount(value float64) sql.NullFloat64 {
+	if value <= 0 {
+		return sql.NullFloat64{Valid: false}
+	}
+	return sql.NullFloat64{Float64: value, Valid: true}
+}
@@ -0,0 +1,342 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// ReglaImpuesto es una tasa que aplica a una categoria, a una region o a ambas.
+// Sin categoria ni region es la tasa general.
+type ReglaImpuesto struct {
+	IdRegla     int
+	Nombre      string
+	Tasa        float64
+	IdCategoria sql.NullInt32
+	Region      sql.NullString
+	Activo      bool
+}
+
+func (r ReglaImpuesto) String() string {
+	cat := "toda categoria"
+	if r.IdCategoria.Valid {
+		cat = fmt.Sprintf("CategoriaID:%d", r.IdCategoria.Int32)
+	}
+	region := "toda region"
+	if r.Region.Valid {
+		region = r.Region.String
+	}
+	estado := "Activa"
+	if !r.Activo {
+		estado = "Inactiva"
+	}
+	return fmt.Sprintf("[ Regla #%d | %s %.2f%% | %s | %s | %s ]",
+		r.IdRegla, r.Nombre, r.Tasa*100, cat, region, estado)
+}
+
+// especificidad ordena las reglas: categoria y region le gana a solo categoria,
+// que le gana a solo region, que le gana a la general.
+func (r ReglaImpuesto) especificidad() int {
+	score := 0
+	if r.IdCategoria.Valid {
+		score += 2
+	}
+	if r.Region.Valid {
+		score++
+	}
+	return score
+}
+
+// PedidoImpuesto es lo cobrado en un pedido para una tasa.
+type PedidoImpuesto struct {
+	IdImpuesto int
+	IdPedido   int
+	Nombre     string
+	Tasa       float64
+	Base       float64
+	Monto      float64
+}
+
+func (i PedidoImpuesto) String() string {
+	return fmt.Sprintf("[ %s %.2f%% | Base: %.2f | Impuesto: %.2f ]", i.Nombre, i.Porcentaje(), i.Base, i.Monto)
+}
+
+// Porcentaje es la tasa expresada como porcentaje (0.13 -> 13).
+func (i PedidoImpuesto) Porcentaje() float64 {
+	return i.Tasa * 100
+}
+
+// CalculoImpuesto es el resultado de la calculadora: el desglose por tasa y
+// si el monto ya venia incluido en los precios.
+type CalculoImpuesto struct {
+	Incluido bool
+	Desglose []PedidoImpuesto
+	Total    float64
+}
+
+var (
+	taxModeMu        sync.RWMutex
+	pricesIncludeTax bool
+)
+
+// SetPricesIncludeTax indica si los precios de SKU ya incluyen el impuesto.
+// Por defecto no lo incluyen y el impuesto se suma al total.
+func SetPricesIncludeTax(included bool) {
+	taxModeMu.Lock()
+	defer taxModeMu.Unlock()
+	pricesIncludeTax = included
+}
+
+// PricesIncludeTax devuelve el modo de precios vigente.
+func PricesIncludeTax() bool {
+	taxModeMu.RLock()
+	defer taxModeMu.RUnlock()
+	return pricesIncludeTax
+}
+
+// lineaGravable es lo que la calculadora necesita de cada linea.
+type lineaGravable struct {
+	// Categoria del producto y sus ancestros, de la mas cercana a la raiz
+	Categorias []int
+	Importe    float64
+}
+
+// reglaAplicable elige la regla activa mas especifica para las categorias de
+// la linea y la region. Una regla de una categoria tambien aplica a sus
+// subcategorias; a igual especificidad gana la categoria mas cercana. Devuelve
+// nil si ninguna aplica (la linea no paga impuesto).
+func reglaAplicable(rules []ReglaImpuesto, categories []int, region string) *ReglaImpuesto {
+	var best *ReglaImpuesto
+	bestDepth := 0
+	for i := range rules {
+		r := &rules[i]
+		if !r.Activo {
+			continue
+		}
+		depth := 0
+		if r.IdCategoria.Valid {
+			depth = slices.Index(categories, int(r.IdCategoria.Int32))
+			if depth < 0 {
+				continue
+			}
+		}
+		if r.Region.Valid && !strings.EqualFold(r.Region.String, strings.TrimSpace(region)) {
+			continue
+		}
+		if best == nil || r.especificidad() > best.especificidad() ||
+			(r.especificidad() == best.especificidad() && depth < bestDepth) {
+			best, bestDepth = r, depth
+		}
+	}
+	return best
+}
+
+// calcularImpuestos aplica a cada linea su regla y agrupa por tasa. El
+// descuento se reparte entre las lineas en proporcion a su importe antes de
+// gravar. Con incluido los importes ya traen el impuesto y se separa la base;
+// si no, el impuesto se calcula sobre el importe.
+func calcularImpuestos(lines []lineaGravable, rules []ReglaImpuesto, region string, descuento float64, incluido bool) CalculoImpuesto {
+	subtotal := 0.0
+	for _, line := range lines {
+		subtotal += line.Importe
+	}
+	factor := 1.0
+	if subtotal > 0 && descuento > 0 {
+		factor = max(1-descuento/subtotal, 0)
+	}
+
+	type grupo struct {
+		nombre string
+		tasa   float64
+		gross  float64
+	}
+	groups := []*grupo{}
+	byKey := map[string]*grupo{}
+	for _, line := range lines {
+		rule := reglaAplicable(rules, line.Categorias, region)
+		if rule == nil {
+			continue
+		}
+		key := fmt.Sprintf("%s|%.4f", rule.Nombre, rule.Tasa)
+		g, ok := byKey[key]
+		if !ok {
+			g = &grupo{nombre: rule.Nombre, tasa: rule.Tasa}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.gross += line.Importe * factor
+	}
+
+	result := CalculoImpuesto{Incluido: incluido}
+	for _, g := range groups {
+		base, amount := g.gross, g.gross*g.tasa
+		if incluido {
+			amount = g.gross * g.tasa / (1 + g.tasa)
+			base = g.gross - amount
+		}
+		item := PedidoImpuesto{Nombre: g.nombre, Tasa: g.tasa, Base: redondear(base), Monto: redondear(amount)}
+		result.Desglose = append(result.Desglose, item)
+		result.Total += item.Monto
+	}
+	result.Total = redondear(result.Total)
+	return result
+}
+
+// lineaImpuesto es la forma en que el desglose viaja como JSON a añadir/pedido.sql.
+type lineaImpuesto struct {
+	Nombre string  `json:"nombre"`
+	Tasa   float64 `json:"tasa"`
+	Base   float64 `json:"base"`
+	Monto  float64 `json:"monto"`
+}
+
+func lineasImpuesto(calc CalculoImpuesto) []lineaImpuesto {
+	lines := make([]lineaImpuesto, 0, len(calc.Desglose))
+	for _, item := range calc.Desglose {
+		lines = append(lines, lineaImpuesto{Nombre: item.Nombre, Tasa: item.Tasa, Base: item.Base, Monto: item.Monto})
+	}
+	return lines
+}
+
+type ImpuestoManager struct {
+	db *sql.DB
+}
+
+func NewImpuestoManager(database *sql.DB) *ImpuestoManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &ImpuestoManager{db: database}
+}
+
+func (m *ImpuestoManager) List(ctx context.Context) ([]ReglaImpuesto, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/regla_impuesto.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[ReglaImpuesto](rows)
+}
+
+func (m *ImpuestoManager) Get(ctx context.Context, id int) (*ReglaImpuesto, error) {
+	if err := requirePositive("idRegla", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/regla_impuesto_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[ReglaImpuesto](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("regla de impuesto %d no encontrada", id)
+	}
+	return &items[0], nil
+}
+
+// Create agrega una regla. rate es una fraccion (0.13 = 13%); categoryId en 0
+// y region vacia hacen que aplique a todas.
+func (m *ImpuestoManager) Create(ctx context.Context, name string, rate float64, categoryId int, region string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	if err := validarTasa(rate); err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "añadir/regla_impuesto.sql",
+		sql.Named("name", name),
+		sql.Named("rate", rate),
+		sql.Named("categoryId", optionalInt(categoryId)),
+		sql.Named("region", optionalString(region)),
+	)
+	return err
+}
+
+func (m *ImpuestoManager) Update(ctx context.Context, id int, name string, rate float64, categoryId int, region string, active bool) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idRegla", id); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	if err := validarTasa(rate); err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/regla_impuesto.sql",
+		sql.Named("id", id),
+		sql.Named("name", name),
+		sql.Named("rate", rate),
+		sql.Named("categoryId", optionalInt(categoryId)),
+		sql.Named("region", optionalString(region)),
+		sql.Named("active", active),
+	)
+	return err
+}
+
+func (m *ImpuestoManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idRegla", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/regla_impuesto.sql", sql.Named("id", id))
+	return err
+}
+
+// Calculate calcula el impuesto de unas lineas enviadas a region, con el modo
+// de precios vigente (ver SetPricesIncludeTax).
+func (m *ImpuestoManager) Calculate(ctx context.Context, items []PedidoDetalle, region string, discount float64) (*CalculoImpuesto, error) {
+	rules, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	skus := NewSKUManager(m.db)
+	categories := map[int][]int{}
+	lines := make([]lineaGravable, 0, len(items))
+	for _, item := range items {
+		ids, seen := categories[item.IdSKU]
+		if !seen {
+			ids, err = skus.categorias(ctx, item.IdSKU)
+			if err != nil {
+				return nil, err
+			}
+			categories[item.IdSKU] = ids
+		}
+		lines = append(lines, lineaGravable{Categorias: ids, Importe: item.Importe()})
+	}
+	result := calcularImpuestos(lines, rules, region, discount, PricesIncludeTax())
+	return &result, nil
+}
+
+func validarTasa(rate float64) error {
+	if rate < 0 || rate >= 1 {
+		return fmt.Errorf("la tasa debe ser una fraccion entre 0 y 1 (0.13 = 13%%), se recibio %v", rate)
+	}
+	return nil
+}
+
+// regionDireccion es la region de una direccion, vacia si no tiene.
+func regionDireccion(address *Direccion) string {
+	if !address.Region.Valid {
+		return ""
+	}
+	return strings.TrimSpace(address.Region.String)
+}
@@ -0,0 +1,499 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"tienda-online/internal"
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// ListaDeseos agrupa articulos que un cliente guardo para despues.
+type ListaDeseos struct {
+	IdLista       int
+	IdUsuario     int
+	Nombre        string
+	FechaCreacion time.Time
+}
+
+func (l ListaDeseos) String() string {
+	return fmt.Sprintf("[ Lista #%d | UsuarioID:%d | %s | Creada: %s ]",
+		l.IdLista, l.IdUsuario, l.Nombre, l.FechaCreacion.Local().Format("2006-01-02"))
+}
+
+// ListaDeseosItem es un producto (cualquier variante) o un SKU puntual de una
+// lista, con su precio y stock actuales. Sin SKU el precio es el mas bajo de
+// las variantes y el stock la suma de todas.
+type ListaDeseosItem struct {
+	IdItem        int
+	IdLista       int
+	IdProducto    int
+	IdSKU         sql.NullInt32
+	FechaAgregado time.Time
+	Descripcion   string
+	Codigo        sql.NullString
+	Variante      sql.NullString
+	Precio        sql.NullFloat64
+	Stock         int
+}
+
+func (i ListaDeseosItem) String() string {
+	articulo := "cualquier variante"
+	if i.IdSKU.Valid {
+		articulo = fmt.Sprintf("SKU:%d %s", i.IdSKU.Int32, etiquetaVariante(internal.NullString(i.Codigo), i.Variante))
+	}
+	precio := "sin precio"
+	if i.Precio.Valid {
+		precio = fmt.Sprintf("%.2f", i.Precio.Float64)
+		if !i.IdSKU.Valid {
+			precio = "desde " + precio
+		}
+	}
+	stock := fmt.Sprintf("Stock: %d", i.Stock)
+	if i.Stock <= 0 {
+		stock = "Agotado"
+	}
+	return fmt.Sprintf("[ Item #%d | Producto #%d %s | %s | %s | %s ]",
+		i.IdItem, i.IdProducto, i.Descripcion, articulo, precio, stock)
+}
+
+// Tipos de aviso de lista de deseos.
+const (
+	AvisoReposicion = "Reposicion"
+	AvisoBajaPrecio = "BajaPrecio"
+)
+
+// AvisoDeseo le avisa a un cliente que un articulo de su lista volvio a
+// tener stock o bajo de precio.
+type AvisoDeseo struct {
+	Tipo           string
+	IdUsuario      int
+	Nombre         string
+	Correo         sql.NullString
+	IdLista        int
+	IdItem         int
+	IdProducto     int
+	IdSKU          sql.NullInt32
+	Descripcion    string
+	PrecioAnterior float64
+	PrecioActual   float64
+	Stock          int
+	Fecha          time.Time
+}
+
+func (a AvisoDeseo) String() string {
+	articulo := fmt.Sprintf("Producto #%d %s", a.IdProducto, a.Descripcion)
+	if a.IdSKU.Valid {
+		articulo += fmt.Sprintf(" (SKU:%d)", a.IdSKU.Int32)
+	}
+	detalle := fmt.Sprintf("de nuevo en stock (%d)", a.Stock)
+	if a.Tipo == AvisoBajaPrecio {
+		detalle = fmt.Sprintf("bajo de %.2f a %.2f", a.PrecioAnterior, a.PrecioActual)
+	}
+	cliente := fmt.Sprintf("UsuarioID:%d %s", a.IdUsuario, a.Nombre)
+	if a.Correo.Valid {
+		cliente += fmt.Sprintf(" <%s>", a.Correo.String)
+	}
+	return fmt.Sprintf("[ Aviso %s | %s | %s %s ]", a.Tipo, cliente, articulo, detalle)
+}
+
+// cambioDeseo es un item cuyo precio o stock cambio desde el ultimo aviso.
+type cambioDeseo struct {
+	IdItem         int
+	IdLista        int
+	IdUsuario      int
+	Nombre         string
+	Correo         sql.NullString
+	IdProducto     int
+	IdSKU          sql.NullInt32
+	Descripcion    string
+	PrecioAnterior sql.NullFloat64
+	PrecioActual   sql.NullFloat64
+	StockAnterior  int
+	StockActual    int
+}
+
+// avisosDeseo decide que avisos merece un cambio: reposicion si paso de sin
+// stock a tener, baja de precio si el precio actual es menor al ultimo
+// avisado. Subas de precio o quedarse sin stock no se avisan.
+func avisosDeseo(c cambioDeseo, now time.Time) []AvisoDeseo {
+	base := AvisoDeseo{
+		IdUsuario:   c.IdUsuario,
+		Nombre:      c.Nombre,
+		Correo:      c.Correo,
+		IdLista:     c.IdLista,
+		IdItem:      c.IdItem,
+		IdProducto:  c.IdProducto,
+		IdSKU:       c.IdSKU,
+		Descripcion: c.Descripcion,
+		Stock:       c.StockActual,
+		Fecha:       now,
+	}
+	if c.PrecioActual.Valid {
+		base.PrecioActual = c.PrecioActual.Float64
+	}
+	if c.PrecioAnterior.Valid {
+		base.PrecioAnterior = c.PrecioAnterior.Float64
+	}
+	avisos := []AvisoDeseo{}
+	if c.StockAnterior <= 0 && c.StockActual > 0 {
+		aviso := base
+		aviso.Tipo = AvisoReposicion
+		avisos = append(avisos, aviso)
+	}
+	if c.PrecioAnterior.Valid && c.PrecioActual.Valid && c.PrecioActual.Float64 < c.PrecioAnterior.Float64 {
+		aviso := base
+		aviso.Tipo = AvisoBajaPrecio
+		avisos = append(avisos, aviso)
+	}
+	return avisos
+}
+
+// AvisoDeseoSink recibe los avisos de listas de deseos (archivo de log, correo, etc.).
+type AvisoDeseoSink interface {
+	Notify(ctx context.Context, aviso AvisoDeseo) error
+}
+
+var (
+	wishlistSinkMu sync.RWMutex
+	wishlistSink   AvisoDeseoSink
+)
+
+// SetWishlistSink define a donde se envian los avisos de listas de deseos;
+// nil los desactiva.
+func SetWishlistSink(sink AvisoDeseoSink) {
+	wishlistSinkMu.Lock()
+	defer wishlistSinkMu.Unlock()
+	wishlistSink = sink
+}
+
+func currentWishlistSink() AvisoDeseoSink {
+	wishlistSinkMu.RLock()
+	defer wishlistSinkMu.RUnlock()
+	return wishlistSink
+}
+
+// LogFileWishlistSink agrega cada aviso como una linea en un archivo de texto.
+type LogFileWishlistSink struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewLogFileWishlistSink(path string) *LogFileWishlistSink {
+	return &LogFileWishlistSink{Path: path}
+}
+
+func (s *LogFileWishlistSink) Notify(ctx context.Context, aviso AvisoDeseo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("abriendo log de avisos %s: %w", s.Path, err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s %s\n", aviso.Fecha.Format(time.RFC3339), aviso.String())
+	return err
+}
+
+type ListaDeseosManager struct {
+	db *sql.DB
+}
+
+func NewListaDeseosManager(database *sql.DB) *ListaDeseosManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &ListaDeseosManager{db: database}
+}
+
+// ListByUser obtiene las listas de un cliente.
+func (m *ListaDeseosManager) ListByUser(ctx context.Context, userId int) ([]ListaDeseos, error) {
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/lista_deseos_por_usuario.sql", sql.Named("userId", userId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[ListaDeseos](rows)
+}
+
+func (m *ListaDeseosManager) Get(ctx context.Context, id int) (*ListaDeseos, error) {
+	if err := requirePositive("idLista", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/lista_deseos_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[ListaDeseos](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("lista de deseos %d no encontrada", id)
+	}
+	return &items[0], nil
+}
+
+// Create crea una lista para el cliente y devuelve su ID. El nombre no se
+// puede repetir entre las listas del mismo cliente.
+func (m *ListaDeseosManager) Create(ctx context.Context, userId int, name string) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return 0, err
+	}
+	name, err := nombreListaDeseos(name)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/lista_deseos.sql",
+		sql.Named("userId", userId),
+		sql.Named("name", name),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	created, err := sqlutil.ParseRow[struct{ IdLista int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(created) == 0 {
+		return 0, fmt.Errorf("no se obtuvo el ID de la lista creada")
+	}
+	return created[0].IdLista, nil
+}
+
+// Rename cambia el nombre de la lista.
+func (m *ListaDeseosManager) Rename(ctx context.Context, id int, name string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idLista", id); err != nil {
+		return err
+	}
+	name, err := nombreListaDeseos(name)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/lista_deseos.sql",
+		sql.Named("id", id),
+		sql.Named("name", name),
+	)
+	return err
+}
+
+func nombreListaDeseos(name string) (string, error) {
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return "", err
+	}
+	if len(name) > 50 {
+		return "", fmt.Errorf("nombre no puede tener mas de 50 caracteres")
+	}
+	return name, nil
+}
+
+// Delete borra la lista con todos sus items.
+func (m *ListaDeseosManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idLista", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/lista_deseos.sql", sql.Named("id", id))
+	return err
+}
+
+// Items obtiene los articulos de la lista, los ultimos agregados primero.
+func (m *ListaDeseosManager) Items(ctx context.Context, listId int) ([]ListaDeseosItem, error) {
+	if err := requirePositive("idLista", listId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/lista_deseos_item_por_lista.sql", sql.Named("listId", listId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[ListaDeseosItem](rows)
+}
+
+func (m *ListaDeseosManager) GetItem(ctx context.Context, itemId int) (*ListaDeseosItem, error) {
+	if err := requirePositive("idItem", itemId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/lista_deseos_item_por_id.sql", sql.Named("id", itemId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[ListaDeseosItem](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("item %d no encontrado", itemId)
+	}
+	return &items[0], nil
+}
+
+// AddProduct guarda un producto sin elegir variante.
+func (m *ListaDeseosManager) AddProduct(ctx context.Context, listId, productId int) error {
+	if err := requirePositive("idProducto", productId); err != nil {
+		return err
+	}
+	return m.addItem(ctx, listId, productId, 0)
+}
+
+// AddSKU guarda una variante puntual.
+func (m *ListaDeseosManager) AddSKU(ctx context.Context, listId, skuId int) error {
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return err
+	}
+	return m.addItem(ctx, listId, 0, skuId)
+}
+
+func (m *ListaDeseosManager) addItem(ctx context.Context, listId, productId, skuId int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idLista", listId); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "añadir/lista_deseos_item.sql",
+		sql.Named("listId", listId),
+		sql.Named("productId", optionalInt(productId)),
+		sql.Named("skuId", optionalInt(skuId)),
+	)
+	return err
+}
+
+// RemoveItem quita un articulo de su lista.
+func (m *ListaDeseosManager) RemoveItem(ctx context.Context, itemId int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idItem", itemId); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/lista_deseos_item.sql", sql.Named("id", itemId))
+	return err
+}
+
+// MoveToCart pasa el item al carrito del cliente (creandolo si no tiene) y lo
+// quita de la lista. skuId elige la variante cuando el item es un producto;
+// en 0 usa el SKU del item.
+func (m *ListaDeseosManager) MoveToCart(ctx context.Context, itemId, skuId, quantity int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if quantity <= 0 {
+		return fmt.Errorf("cantidad debe ser mayor a cero")
+	}
+	item, err := m.GetItem(ctx, itemId)
+	if err != nil {
+		return err
+	}
+	if skuId <= 0 {
+		if !item.IdSKU.Valid {
+			return fmt.Errorf("el item %d es un producto, elige el SKU a agregar", itemId)
+		}
+		skuId = int(item.IdSKU.Int32)
+	}
+	_, err = db.ExecFromFile(ctx, "editar/lista_deseos_a_carrito.sql",
+		sql.Named("itemId", itemId),
+		sql.Named("skuId", skuId),
+		sql.Named("quantity", quantity),
+		sql.Named("maxQuantity", MaxCantidadLinea),
+	)
+	return err
+}
+
+// SaveForLater pasa una linea del carrito a una lista del mismo cliente.
+func (m *ListaDeseosManager) SaveForLater(ctx context.Context, cartDetailId, listId int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idDetalle", cartDetailId); err != nil {
+		return err
+	}
+	if err := requirePositive("idLista", listId); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "editar/carrito_a_lista_deseos.sql",
+		sql.Named("detailId", cartDetailId),
+		sql.Named("listId", listId),
+	)
+	return err
+}
+
+// CheckNotifications busca items que volvieron a tener stock o bajaron de
+// precio desde el ultimo aviso, los envia al sink y devuelve cuantos avisos
+// salieron. Cada item queda con el precio y stock evaluados como nueva base,
+// asi un mismo cambio no se avisa dos veces. Sin sink no se envia ni se
+// registra nada. Se llama periodicamente.
+func (m *ListaDeseosManager) CheckNotifications(ctx context.Context) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	sink := currentWishlistSink()
+	if sink == nil {
+		return 0, nil
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/lista_deseos_cambios.sql")
+	if err != nil {
+		return 0, err
+	}
+	changes, err := sqlutil.ParseRow[cambioDeseo](rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	sent := 0
+	for _, change := range changes {
+		for _, aviso := range avisosDeseo(change, now) {
+			// Sin mover la base se reintenta en la proxima revision
+			if err := sink.Notify(ctx, aviso); err != nil {
+				return sent, err
+			}
+			sent++
+			// Cada aviso enviado mueve solo su parte de la base, asi si
+			// falla el siguiente del mismo item este no se repite
+			err := m.moverBaseAviso(ctx, change, aviso.Tipo == AvisoBajaPrecio, aviso.Tipo == AvisoReposicion)
+			if err != nil {
+				return sent, err
+			}
+		}
+		// Todos los avisos salieron; subas de precio o quedarse sin stock
+		// tambien pasan a ser la base
+		if err := m.moverBaseAviso(ctx, change, true, true); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// moverBaseAviso guarda el precio y/o el stock actuales del item como base
+// del proximo aviso.
+func (m *ListaDeseosManager) moverBaseAviso(ctx context.Context, change cambioDeseo, price, stock bool) error {
+	_, err := db.ExecFromFile(ctx, "editar/lista_deseos_item_base.sql",
+		sql.Named("id", change.IdItem),
+		sql.Named("price", change.PrecioActual),
+		sql.Named("stock", change.StockActual),
+		sql.Named("setPrice", price),
+		sql.Named("setStock", stock),
+	)
+	return err
+}
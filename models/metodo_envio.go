@@ -0,0 +1,423 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// MetodoEnvio es un servicio de un transportista (estandar, express, ...).
+type MetodoEnvio struct {
+	IdMetodoEnvio    int
+	IdTransportista  int
+	Nombre           string
+	DiasEstimados    int
+	EnvioGratisDesde sql.NullFloat64
+	Activo           bool
+}
+
+func (m MetodoEnvio) String() string {
+	gratis := "sin envio gratis"
+	if m.EnvioGratisDesde.Valid {
+		gratis = fmt.Sprintf("gratis desde %.2f", m.EnvioGratisDesde.Float64)
+	}
+	estado := "Activo"
+	if !m.Activo {
+		estado = "Inactivo"
+	}
+	return fmt.Sprintf("[ Metodo #%d | TransportistaID:%d | %s | %d dias | %s | %s ]",
+		m.IdMetodoEnvio, m.IdTransportista, m.Nombre, m.DiasEstimados, gratis, estado)
+}
+
+// TarifaEnvio es el precio de un metodo para un rango de peso y de monto del
+// pedido, en una zona o en todas.
+type TarifaEnvio struct {
+	IdTarifa      int
+	IdMetodoEnvio int
+	IdZona        sql.NullInt32
+	PesoMin       float64
+	PesoMax       sql.NullFloat64
+	MontoMin      float64
+	MontoMax      sql.NullFloat64
+	Precio        float64
+}
+
+func (t TarifaEnvio) String() string {
+	zona := "toda zona"
+	if t.IdZona.Valid {
+		zona = fmt.Sprintf("ZonaID:%d", t.IdZona.Int32)
+	}
+	return fmt.Sprintf("[ Tarifa #%d | %s | Peso %s | Monto %s | Precio: %.2f ]",
+		t.IdTarifa, zona, rango(t.PesoMin, t.PesoMax, "kg"), rango(t.MontoMin, t.MontoMax, ""), t.Precio)
+}
+
+// aplica indica si la tarifa cubre la zona, el peso y el monto dados.
+func (t TarifaEnvio) aplica(zoneId int, weight, amount float64) bool {
+	if t.IdZona.Valid && int(t.IdZona.Int32) != zoneId {
+		return false
+	}
+	if weight < t.PesoMin || (t.PesoMax.Valid && weight >= t.PesoMax.Float64) {
+		return false
+	}
+	if amount < t.MontoMin || (t.MontoMax.Valid && amount >= t.MontoMax.Float64) {
+		return false
+	}
+	return true
+}
+
+func rango(min float64, max sql.NullFloat64, unit string) string {
+	if !max.Valid {
+		return strings.TrimSpace(fmt.Sprintf("%.2f+ %s", min, unit))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%.2f-%.2f %s", min, max.Float64, unit))
+}
+
+type ZonaEnvio struct {
+	IdZona int
+	Nombre string
+}
+
+func (z ZonaEnvio) String() string {
+	return fmt.Sprintf("[ Zona #%d | %s ]", z.IdZona, z.Nombre)
+}
+
+type ZonaEnvioRegion struct {
+	Region string
+	IdZona int
+}
+
+func (z ZonaEnvioRegion) String() string {
+	return fmt.Sprintf("[ %s -> ZonaID:%d ]", z.Region, z.IdZona)
+}
+
+// OpcionEnvio es un metodo disponible para un pedido con su costo.
+type OpcionEnvio struct {
+	Metodo MetodoEnvio
+	Costo  float64
+}
+
+func (o OpcionEnvio) String() string {
+	return fmt.Sprintf("[ Metodo #%d | %s | %d dias | Costo: %.2f ]",
+		o.Metodo.IdMetodoEnvio, o.Metodo.Nombre, o.Metodo.DiasEstimados, o.Costo)
+}
+
+// calcularCostoEnvio elige la tarifa del metodo para la zona, el peso y el
+// monto (sin impuesto) del pedido. Las tarifas de la zona exacta le ganan a
+// las generales y entre iguales se cobra la mas barata. Si el monto alcanza
+// el umbral de envio gratis del metodo, cuesta cero.
+func calcularCostoEnvio(method MetodoEnvio, rates []TarifaEnvio, zoneId int, weight, amount float64) (float64, error) {
+	if method.EnvioGratisDesde.Valid && amount >= method.EnvioGratisDesde.Float64 {
+		return 0, nil
+	}
+	var best *TarifaEnvio
+	for i := range rates {
+		r := &rates[i]
+		if r.IdMetodoEnvio != method.IdMetodoEnvio || !r.aplica(zoneId, weight, amount) {
+			continue
+		}
+		if best == nil ||
+			(r.IdZona.Valid && !best.IdZona.Valid) ||
+			(r.IdZona.Valid == best.IdZona.Valid && r.Precio < best.Precio) {
+			best = r
+		}
+	}
+	if best == nil {
+		return 0, fmt.Errorf("el metodo %s no tiene tarifa para %.3f kg y monto %.2f en esa zona", method.Nombre, weight, amount)
+	}
+	return redondear(best.Precio), nil
+}
+
+type MetodoEnvioManager struct {
+	db *sql.DB
+}
+
+func NewMetodoEnvioManager(database *sql.DB) *MetodoEnvioManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &MetodoEnvioManager{db: database}
+}
+
+func (m *MetodoEnvioManager) List(ctx context.Context) ([]MetodoEnvio, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/metodo_envio.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[MetodoEnvio](rows)
+}
+
+func (m *MetodoEnvioManager) Get(ctx context.Context, id int) (*MetodoEnvio, error) {
+	if err := requirePositive("idMetodoEnvio", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/metodo_envio_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[MetodoEnvio](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("metodo de envio %d no encontrado", id)
+	}
+	return &items[0], nil
+}
+
+// Create agrega un metodo a un transportista. freeFrom <= 0 deja el metodo
+// sin envio gratis.
+func (m *MetodoEnvioManager) Create(ctx context.Context, carrierId int, name string, days int, freeFrom float64) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idTransportista", carrierId); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	if err := requirePositive("dias estimados", days); err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "añadir/metodo_envio.sql",
+		sql.Named("carrierId", carrierId),
+		sql.Named("name", name),
+		sql.Named("days", days),
+		sql.Named("freeFrom", optionalAmount(freeFrom)),
+	)
+	return err
+}
+
+func (m *MetodoEnvioManager) Update(ctx context.Context, id, carrierId int, name string, days int, freeFrom float64, active bool) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idMetodoEnvio", id); err != nil {
+		return err
+	}
+	if err := requirePositive("idTransportista", carrierId); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	if err := requirePositive("dias estimados", days); err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/metodo_envio.sql",
+		sql.Named("id", id),
+		sql.Named("carrierId", carrierId),
+		sql.Named("name", name),
+		sql.Named("days", days),
+		sql.Named("freeFrom", optionalAmount(freeFrom)),
+		sql.Named("active", active),
+	)
+	return err
+}
+
+// Delete borra un metodo con sus tarifas; falla si ya se uso en algun pedido.
+func (m *MetodoEnvioManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idMetodoEnvio", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/metodo_envio.sql", sql.Named("id", id))
+	return err
+}
+
+// Rates obtiene las tarifas de un metodo.
+func (m *MetodoEnvioManager) Rates(ctx context.Context, methodId int) ([]TarifaEnvio, error) {
+	if err := requirePositive("idMetodoEnvio", methodId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/tarifa_envio_por_metodo.sql", sql.Named("methodId", methodId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[TarifaEnvio](rows)
+}
+
+// AddRate agrega una tarifa. zoneId en 0 aplica a todas las zonas y los
+// maximos en 0 dejan el rango sin limite.
+func (m *MetodoEnvioManager) AddRate(ctx context.Context, methodId, zoneId int, minWeight, maxWeight, minAmount, maxAmount, price float64) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idMetodoEnvio", methodId); err != nil {
+		return err
+	}
+	if minWeight < 0 || minAmount < 0 || price < 0 {
+		return fmt.Errorf("pesos, montos y precio no pueden ser negativos")
+	}
+	if maxWeight > 0 && maxWeight <= minWeight {
+		return fmt.Errorf("el peso maximo debe ser mayor al minimo")
+	}
+	if maxAmount > 0 && maxAmount <= minAmount {
+		return fmt.Errorf("el monto maximo debe ser mayor al minimo")
+	}
+	_, err := db.ExecFromFile(ctx, "añadir/tarifa_envio.sql",
+		sql.Named("methodId", methodId),
+		sql.Named("zoneId", optionalInt(zoneId)),
+		sql.Named("minWeight", minWeight),
+		sql.Named("maxWeight", optionalAmount(maxWeight)),
+		sql.Named("minAmount", minAmount),
+		sql.Named("maxAmount", optionalAmount(maxAmount)),
+		sql.Named("price", price),
+	)
+	return err
+}
+
+func (m *MetodoEnvioManager) DeleteRate(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idTarifa", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/tarifa_envio.sql", sql.Named("id", id))
+	return err
+}
+
+func (m *MetodoEnvioManager) ListZones(ctx context.Context) ([]ZonaEnvio, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/zona_envio.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[ZonaEnvio](rows)
+}
+
+func (m *MetodoEnvioManager) CreateZone(ctx context.Context, name string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "añadir/zona_envio.sql", sql.Named("name", name))
+	return err
+}
+
+// ListZoneRegions obtiene que region pertenece a que zona.
+func (m *MetodoEnvioManager) ListZoneRegions(ctx context.Context) ([]ZonaEnvioRegion, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/zona_envio_region.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[ZonaEnvioRegion](rows)
+}
+
+// AssignRegion pone la region en la zona; si ya estaba en otra, se mueve.
+func (m *MetodoEnvioManager) AssignRegion(ctx context.Context, zoneId int, region string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idZona", zoneId); err != nil {
+		return err
+	}
+	region, err := requireNonEmpty("region", region)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/zona_envio_region.sql",
+		sql.Named("zoneId", zoneId),
+		sql.Named("region", region),
+	)
+	return err
+}
+
+func (m *MetodoEnvioManager) UnassignRegion(ctx context.Context, region string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	region, err := requireNonEmpty("region", region)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "remover/zona_envio_region.sql", sql.Named("region", region))
+	return err
+}
+
+// zonaDeRegion devuelve la zona de la region, o 0 si no tiene (solo aplican
+// las tarifas para toda zona).
+func (m *MetodoEnvioManager) zonaDeRegion(ctx context.Context, region string) (int, error) {
+	if strings.TrimSpace(region) == "" {
+		return 0, nil
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/zona_envio_por_region.sql", sql.Named("region", strings.TrimSpace(region)))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[ZonaEnvioRegion](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+	return items[0].IdZona, nil
+}
+
+// Calculate calcula el costo de enviar weight kg a region con el metodo dado,
+// para un pedido de amount (sin impuesto).
+func (m *MetodoEnvioManager) Calculate(ctx context.Context, methodId int, region string, weight, amount float64) (float64, error) {
+	method, err := m.Get(ctx, methodId)
+	if err != nil {
+		return 0, err
+	}
+	if !method.Activo {
+		return 0, fmt.Errorf("el metodo de envio %s no esta activo", method.Nombre)
+	}
+	zoneId, err := m.zonaDeRegion(ctx, region)
+	if err != nil {
+		return 0, err
+	}
+	rates, err := m.Rates(ctx, methodId)
+	if err != nil {
+		return 0, err
+	}
+	return calcularCostoEnvio(*method, rates, zoneId, weight, amount)
+}
+
+// Options lista los metodos activos que pueden llevar el pedido a region con
+// su costo; los que no tienen tarifa aplicable se omiten.
+func (m *MetodoEnvioManager) Options(ctx context.Context, region string, weight, amount float64) ([]OpcionEnvio, error) {
+	methods, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	zoneId, err := m.zonaDeRegion(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	options := []OpcionEnvio{}
+	for _, method := range methods {
+		if !method.Activo {
+			continue
+		}
+		rates, err := m.Rates(ctx, method.IdMetodoEnvio)
+		if err != nil {
+			return nil, err
+		}
+		cost, err := calcularCostoEnvio(method, rates, zoneId, weight, amount)
+		if err != nil {
+			continue
+		}
+		options = append(options, OpcionEnvio{Metodo: method, Costo: cost})
+	}
+	return options, nil
+}
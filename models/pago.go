@@ -0,0 +1,339 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"tienda-online/internal"
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// Estados de la columna Pago.estado.
+const (
+	PagoAutorizado  = "Autorizado"
+	PagoCapturado   = "Capturado"
+	PagoAnulado     = "Anulado"
+	PagoReembolsado = "Reembolsado"
+	PagoRechazado   = "Rechazado"
+)
+
+type Pago struct {
+	IdPago             int
+	IdPedido           int
+	Pasarela           string
+	Referencia         sql.NullString
+	Monto              float64
+	MontoReembolsado   float64
+	Estado             string
+	Motivo             sql.NullString
+	FechaCreacion      time.Time
+	FechaActualizacion time.Time
+}
+
+func (p Pago) String() string {
+	return fmt.Sprintf("[ Pago #%d | PedidoID:%d | %s %s | Monto: %.2f | Reembolsado: %.2f | %s | Motivo: %s ]",
+		p.IdPago, p.IdPedido, p.Pasarela, internal.NullString(p.Referencia), p.Monto, p.MontoReembolsado,
+		p.Estado, internal.NullString(p.Motivo))
+}
+
+type PagoManager struct {
+	db *sql.DB
+}
+
+func NewPagoManager(database *sql.DB) *PagoManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &PagoManager{db: database}
+}
+
+func (m *PagoManager) List(ctx context.Context) ([]Pago, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/pago.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Pago](rows)
+}
+
+func (m *PagoManager) Get(ctx context.Context, id int) (*Pago, error) {
+	if err := requirePositive("idPago", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/pago_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Pago](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("pago %d no encontrado", id)
+	}
+	return &items[0], nil
+}
+
+// ListByPedido obtiene los pagos de un pedido.
+func (m *PagoManager) ListByPedido(ctx context.Context, orderId int) ([]Pago, error) {
+	if err := requirePositive("idPedido", orderId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/pago_por_pedido.sql", sql.Named("orderId", orderId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Pago](rows)
+}
+
+// Authorize pide a la pasarela reservar el total de un pedido pendiente y
+// guarda el resultado, aprobado o rechazado. Devuelve el ID del pago. Si al
+// guardar otro pago del pedido ya estaba autorizado, esta autorizacion se anula.
+func (m *PagoManager) Authorize(ctx context.Context, orderId int, token string) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	gateway, err := currentPaymentGateway()
+	if err != nil {
+		return 0, err
+	}
+	order, err := NewPedidoManager(m.db).Get(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	if order.Estado != PedidoPendiente {
+		return 0, fmt.Errorf("pedido %d esta %s, solo se cobran pedidos pendientes", orderId, order.Estado)
+	}
+	if order.Total <= 0 {
+		return 0, fmt.Errorf("pedido %d no tiene monto a cobrar", orderId)
+	}
+	existing, err := m.ListByPedido(ctx, orderId)
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range existing {
+		if p.Estado == PagoAutorizado || p.Estado == PagoCapturado {
+			return 0, fmt.Errorf("pedido %d ya tiene el pago %d %s", orderId, p.IdPago, p.Estado)
+		}
+	}
+
+	result, err := gateway.Authorize(ctx, PaymentRequest{IdPedido: orderId, Monto: order.Total, Token: token})
+	if err != nil {
+		return 0, err
+	}
+	status := PagoAutorizado
+	if !result.Approved {
+		status = PagoRechazado
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/pago.sql",
+		sql.Named("orderId", orderId),
+		sql.Named("gateway", gateway.Name()),
+		sql.Named("reference", optionalString(result.Reference)),
+		sql.Named("amount", order.Total),
+		sql.Named("status", status),
+		sql.Named("reason", optionalString(result.Reason)),
+	)
+	var created []struct{ IdPago int }
+	if err == nil {
+		created, err = sqlutil.ParseRow[struct{ IdPago int }](rows)
+		rows.Close()
+	}
+	if err == nil && len(created) == 0 {
+		err = fmt.Errorf("no se obtuvo el ID del pago creado")
+	}
+	if err != nil {
+		// Otra autorizacion del mismo pedido entro primero: se libera esta
+		if result.Approved {
+			if _, voidErr := gateway.Void(ctx, result.Reference); voidErr != nil {
+				return 0, fmt.Errorf("pago autorizado en la pasarela (%s) sin registrar, pero no se pudo anular: %v: %w", result.Reference, voidErr, err)
+			}
+		}
+		return 0, err
+	}
+	if !result.Approved {
+		return created[0].IdPago, fmt.Errorf("pago rechazado: %s", result.Reason)
+	}
+	return created[0].IdPago, nil
+}
+
+// Capture cobra un pago autorizado y pasa el pedido a Pagado; el pago y el
+// pedido cambian de estado en la misma transaccion. Si esa transaccion falla
+// el cobro se reembolsa en la pasarela.
+func (m *PagoManager) Capture(ctx context.Context, id int, actor string) error {
+	actor, err := requireNonEmpty("actor", actor)
+	if err != nil {
+		return err
+	}
+	payment, gateway, err := m.prepare(ctx, id, PagoAutorizado)
+	if err != nil {
+		return err
+	}
+	// Se revisa antes de cobrar para no capturar un pedido que ya no se puede marcar
+	order, err := NewPedidoManager(m.db).Get(ctx, payment.IdPedido)
+	if err != nil {
+		return err
+	}
+	if order.Estado != PedidoPendiente {
+		return fmt.Errorf("pedido %d esta %s, solo se cobran pedidos pendientes", order.IdPedido, order.Estado)
+	}
+	result, err := gateway.Capture(ctx, payment.Referencia.String, payment.Monto)
+	if err != nil {
+		return err
+	}
+	if !result.Approved {
+		return fmt.Errorf("captura rechazada: %s", result.Reason)
+	}
+	_, err = db.ExecFromFile(ctx, "editar/pago_capturar.sql",
+		sql.Named("id", payment.IdPago),
+		sql.Named("actor", actor),
+	)
+	if err != nil {
+		// El cobro ya se hizo pero no quedo registrado: se devuelve, asi el
+		// cliente no queda cobrado por un pedido que sigue Pendiente
+		if _, refundErr := gateway.Refund(ctx, payment.Referencia.String, payment.Monto); refundErr != nil {
+			return fmt.Errorf("pago %d capturado en la pasarela sin registrar, pero no se pudo reembolsar: %v: %w", id, refundErr, err)
+		}
+		return fmt.Errorf("pago %d: no se pudo registrar la captura, se reembolso el cobro: %w", id, err)
+	}
+	return nil
+}
+
+// Void libera una autorizacion que todavia no se cobro.
+func (m *PagoManager) Void(ctx context.Context, id int) error {
+	payment, gateway, err := m.prepare(ctx, id, PagoAutorizado)
+	if err != nil {
+		return err
+	}
+	result, err := gateway.Void(ctx, payment.Referencia.String)
+	if err != nil {
+		return err
+	}
+	if !result.Approved {
+		return fmt.Errorf("anulacion rechazada: %s", result.Reason)
+	}
+	return m.setStatus(ctx, payment, PagoAnulado, payment.MontoReembolsado)
+}
+
+// Refund devuelve parte o todo de un pago capturado; al devolver el total el
+// pago queda Reembolsado.
+func (m *PagoManager) Refund(ctx context.Context, id int, amount float64) error {
+	payment, gateway, err := m.prepare(ctx, id, PagoCapturado)
+	if err != nil {
+		return err
+	}
+	amount = redondear(amount)
+	if err := reembolsarEnPasarela(ctx, gateway, payment, amount); err != nil {
+		return err
+	}
+	refunded := redondear(payment.MontoReembolsado + amount)
+	status := PagoCapturado
+	if refunded >= payment.Monto {
+		status = PagoReembolsado
+	}
+	return m.setStatus(ctx, payment, status, refunded)
+}
+
+// refundForReturn devuelve amount por la pasarela del pago capturado del
+// pedido, sin registrarlo: el SQL de la devolucion actualiza el pago. Sin pago
+// capturado (pedido cobrado por fuera del sistema) no hace nada y devuelve nil.
+func (m *PagoManager) refundForReturn(ctx context.Context, orderId int, amount float64) (*Pago, error) {
+	payments, err := m.ListByPedido(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range payments {
+		if p.Estado != PagoCapturado {
+			continue
+		}
+		payment, gateway, err := m.prepare(ctx, p.IdPago, PagoCapturado)
+		if err != nil {
+			return nil, err
+		}
+		if err := reembolsarEnPasarela(ctx, gateway, payment, redondear(amount)); err != nil {
+			return nil, err
+		}
+		return payment, nil
+	}
+	return nil, nil
+}
+
+// reembolsarEnPasarela valida el monto contra lo que queda del pago y lo
+// devuelve por la pasarela.
+func reembolsarEnPasarela(ctx context.Context, gateway PaymentGateway, payment *Pago, amount float64) error {
+	pending := redondear(payment.Monto - payment.MontoReembolsado)
+	if amount <= 0 {
+		return fmt.Errorf("monto a reembolsar debe ser mayor a cero")
+	}
+	if amount > pending {
+		return fmt.Errorf("pago %d: se piden %.2f pero solo quedan %.2f por reembolsar", payment.IdPago, amount, pending)
+	}
+	result, err := gateway.Refund(ctx, payment.Referencia.String, amount)
+	if err != nil {
+		return err
+	}
+	if !result.Approved {
+		return fmt.Errorf("reembolso rechazado: %s", result.Reason)
+	}
+	return nil
+}
+
+// releaseOrder anula el pago autorizado del pedido o reembolsa lo que quede
+// del capturado.
+func (m *PagoManager) releaseOrder(ctx context.Context, orderId int) error {
+	payments, err := m.ListByPedido(ctx, orderId)
+	if err != nil {
+		return err
+	}
+	for _, p := range payments {
+		switch p.Estado {
+		case PagoAutorizado:
+			if err := m.Void(ctx, p.IdPago); err != nil {
+				return err
+			}
+		case PagoCapturado:
+			if pending := redondear(p.Monto - p.MontoReembolsado); pending > 0 {
+				if err := m.Refund(ctx, p.IdPago, pending); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// prepare carga el pago, revisa que este en el estado esperado y que haya pasarela.
+func (m *PagoManager) prepare(ctx context.Context, id int, expected string) (*Pago, PaymentGateway, error) {
+	if err := ensureDB(m.db); err != nil {
+		return nil, nil, err
+	}
+	gateway, err := currentPaymentGateway()
+	if err != nil {
+		return nil, nil, err
+	}
+	payment, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if payment.Estado != expected {
+		return nil, nil, fmt.Errorf("pago %d esta %s, se esperaba %s", id, payment.Estado, expected)
+	}
+	if payment.Pasarela != gateway.Name() {
+		return nil, nil, fmt.Errorf("pago %d fue procesado por %s, la pasarela activa es %s", id, payment.Pasarela, gateway.Name())
+	}
+	return payment, gateway, nil
+}
+
+func (m *PagoManager) setStatus(ctx context.Context, payment *Pago, to string, refunded float64) error {
+	_, err := db.ExecFromFile(ctx, "editar/pago_estado.sql",
+		sql.Named("id", payment.IdPago),
+		sql.Named("from", payment.Estado),
+		sql.Named("to", to),
+		sql.Named("refunded", refunded),
+	)
+	return err
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PaymentRequest es lo que se envia a la pasarela para autorizar un cobro.
+type PaymentRequest struct {
+	IdPedido int
+	Monto    float64
+	// Token del medio de pago (tarjeta tokenizada, cuenta, etc.)
+	Token string
+}
+
+// PaymentResult es la respuesta de la pasarela. Un rechazo no es un error:
+// Approved queda en false y Reason explica el motivo.
+type PaymentResult struct {
+	Approved  bool
+	Reference string
+	Reason    string
+}
+
+// PaymentGateway es el contrato con un procesador de pagos. Los errores se
+// reservan para fallas de comunicacion u operaciones invalidas.
+type PaymentGateway interface {
+	Name() string
+	Authorize(ctx context.Context, req PaymentRequest) (PaymentResult, error)
+	Capture(ctx context.Context, reference string, amount float64) (PaymentResult, error)
+	Void(ctx context.Context, reference string) (PaymentResult, error)
+	Refund(ctx context.Context, reference string, amount float64) (PaymentResult, error)
+}
+
+var (
+	paymentGatewayMu sync.RWMutex
+	paymentGateway   PaymentGateway
+)
+
+// SetPaymentGateway define la pasarela que usan los pagos.
+func SetPaymentGateway(gateway PaymentGateway) {
+	paymentGatewayMu.Lock()
+	defer paymentGatewayMu.Unlock()
+	paymentGateway = gateway
+}
+
+func currentPaymentGateway() (PaymentGateway, error) {
+	paymentGatewayMu.RLock()
+	defer paymentGatewayMu.RUnlock()
+	if paymentGateway == nil {
+		return nil, fmt.Errorf("no hay pasarela de pago configurada")
+	}
+	return paymentGateway, nil
+}
+
+// Tokens que la pasarela falsa rechaza siempre.
+const (
+	FakeTokenDeclined          = "tok_declined"
+	FakeTokenInsufficientFunds = "tok_insufficient_funds"
+)
+
+// FakeGateway es una pasarela en memoria para desarrollo: no contacta a
+// nadie y responde siempre igual para la misma entrada. Las referencias salen
+// de un contador, asi que dos instancias nuevas generan la misma secuencia.
+type FakeGateway struct {
+	// DeclineTokens mapea token -> motivo de rechazo.
+	DeclineTokens map[string]string
+	// DeclineAbove rechaza autorizaciones por encima de este monto (0 = sin limite).
+	DeclineAbove float64
+
+	mu  sync.Mutex
+	seq int
+}
+
+func NewFakeGateway() *FakeGateway {
+	return &FakeGateway{
+		DeclineTokens: map[string]string{
+			FakeTokenDeclined:          "tarjeta rechazada",
+			FakeTokenInsufficientFunds: "fondos insuficientes",
+		},
+	}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) Authorize(ctx context.Context, req PaymentRequest) (PaymentResult, error) {
+	if req.Monto <= 0 {
+		return PaymentResult{}, fmt.Errorf("monto debe ser mayor a cero")
+	}
+	if reason, ok := g.DeclineTokens[strings.TrimSpace(req.Token)]; ok {
+		return PaymentResult{Approved: false, Reason: reason}, nil
+	}
+	if g.DeclineAbove > 0 && req.Monto > g.DeclineAbove {
+		return PaymentResult{Approved: false, Reason: fmt.Sprintf("monto supera el limite de %.2f", g.DeclineAbove)}, nil
+	}
+	g.mu.Lock()
+	g.seq++
+	ref := fmt.Sprintf("fake_%d_%04d", req.IdPedido, g.seq)
+	g.mu.Unlock()
+	return PaymentResult{Approved: true, Reference: ref}, nil
+}
+
+func (g *FakeGateway) Capture(ctx context.Context, reference string, amount float64) (PaymentResult, error) {
+	if err := g.checkReference(reference); err != nil {
+		return PaymentResult{}, err
+	}
+	if amount <= 0 {
+		return PaymentResult{}, fmt.Errorf("monto debe ser mayor a cero")
+	}
+	return PaymentResult{Approved: true, Reference: reference}, nil
+}
+
+func (g *FakeGateway) Void(ctx context.Context, reference string) (PaymentResult, error) {
+	if err := g.checkReference(reference); err != nil {
+		return PaymentResult{}, err
+	}
+	return PaymentResult{Approved: true, Reference: reference}, nil
+}
+
+func (g *FakeGateway) Refund(ctx context.Context, reference string, amount float64) (PaymentResult, error) {
+	if err := g.checkReference(reference); err != nil {
+		return PaymentResult{}, err
+	}
+	if amount <= 0 {
+		return PaymentResult{}, fmt.Errorf("monto debe ser mayor a cero")
+	}
+	return PaymentResult{Approved: true, Reference: reference}, nil
+}
+
+// checkReference solo acepta referencias emitidas por esta pasarela; los
+// montos y estados los valida PagoManager contra la base.
+func (g *FakeGateway) checkReference(reference string) error {
+	if !strings.HasPrefix(reference, "fake_") {
+		return fmt.Errorf("referencia %q no pertenece a la pasarela fake", reference)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFakeGatewayAuthorize(t *testing.T) {
+	tests := []struct {
+		name         string
+		declineAbove float64
+		req          PaymentRequest
+		wantErr      bool
+		wantApproved bool
+		wantReason   string
+	}{
+		{"aprobado", 0, PaymentRequest{IdPedido: 7, Monto: 100, Token: "tok_ok"}, false, true, ""},
+		{"monto cero", 0, PaymentRequest{IdPedido: 7, Monto: 0, Token: "tok_ok"}, true, false, ""},
+		{"monto negativo", 0, PaymentRequest{IdPedido: 7, Monto: -5, Token: "tok_ok"}, true, false, ""},
+		{"tarjeta rechazada", 0, PaymentRequest{IdPedido: 7, Monto: 100, Token: FakeTokenDeclined}, false, false, "tarjeta rechazada"},
+		{"fondos insuficientes", 0, PaymentRequest{IdPedido: 7, Monto: 100, Token: FakeTokenInsufficientFunds}, false, false, "fondos insuficientes"},
+		{"token con espacios", 0, PaymentRequest{IdPedido: 7, Monto: 100, Token: "  " + FakeTokenDeclined + " "}, false, false, "tarjeta rechazada"},
+		{"sobre el limite", 50, PaymentRequest{IdPedido: 7, Monto: 50.01, Token: "tok_ok"}, false, false, "monto supera el limite de 50.00"},
+		{"justo en el limite", 50, PaymentRequest{IdPedido: 7, Monto: 50, Token: "tok_ok"}, false, true, ""},
+		{"token antes que limite", 50, PaymentRequest{IdPedido: 7, Monto: 80, Token: FakeTokenInsufficientFunds}, false, false, "fondos insuficientes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewFakeGateway()
+			g.DeclineAbove = tt.declineAbove
+			got, err := g.Authorize(context.Background(), tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Approved != tt.wantApproved || got.Reason != tt.wantReason {
+				t.Errorf("got %+v, want approved=%v reason=%q", got, tt.wantApproved, tt.wantReason)
+			}
+			if got.Approved && got.Reference != "fake_7_0001" {
+				t.Errorf("reference = %q, want fake_7_0001", got.Reference)
+			}
+			if !got.Approved && got.Reference != "" {
+				t.Errorf("reference = %q en un rechazo", got.Reference)
+			}
+		})
+	}
+}
+
+func TestFakeGatewayCustomDeclines(t *testing.T) {
+	g := &FakeGateway{DeclineTokens: map[string]string{"tok_robada": "tarjeta denunciada"}}
+	tests := []struct {
+		token        string
+		wantApproved bool
+		wantReason   string
+	}{
+		{"tok_robada", false, "tarjeta denunciada"},
+		// Sin los tokens por defecto, tok_declined pasa
+		{FakeTokenDeclined, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			got, err := g.Authorize(context.Background(), PaymentRequest{IdPedido: 1, Monto: 10, Token: tt.token})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Approved != tt.wantApproved || got.Reason != tt.wantReason {
+				t.Errorf("got %+v, want approved=%v reason=%q", got, tt.wantApproved, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestFakeGatewayReferenceSequence(t *testing.T) {
+	g := NewFakeGateway()
+	ctx := context.Background()
+	want := []string{"fake_3_0001", "fake_4_0002", "fake_3_0003"}
+	for i, order := range []int{3, 4, 3} {
+		got, err := g.Authorize(ctx, PaymentRequest{IdPedido: order, Monto: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Reference != want[i] {
+			t.Errorf("autorizacion %d: reference = %q, want %q", i+1, got.Reference, want[i])
+		}
+	}
+
+	// Los rechazos no consumen la secuencia
+	if _, err := g.Authorize(ctx, PaymentRequest{IdPedido: 3, Monto: 1, Token: FakeTokenDeclined}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := g.Authorize(ctx, PaymentRequest{IdPedido: 5, Monto: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Reference != "fake_5_0004" {
+		t.Errorf("reference = %q, want fake_5_0004", got.Reference)
+	}
+}
+
+func TestFakeGatewayFollowUps(t *testing.T) {
+	g := NewFakeGateway()
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		op        string
+		reference string
+		amount    float64
+		wantErr   bool
+	}{
+		{"captura", "capture", "fake_1_0001", 10, false},
+		{"captura sin monto", "capture", "fake_1_0001", 0, true},
+		{"captura de otra pasarela", "capture", "stripe_123", 10, true},
+		{"anulacion", "void", "fake_1_0001", 0, false},
+		{"anulacion de otra pasarela", "void", "", 0, true},
+		{"reembolso", "refund", "fake_1_0001", 2.5, false},
+		{"reembolso negativo", "refund", "fake_1_0001", -1, true},
+		{"reembolso de otra pasarela", "refund", "FAKE_1_0001", 2.5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got PaymentResult
+			var err error
+			switch tt.op {
+			case "capture":
+				got, err = g.Capture(ctx, tt.reference, tt.amount)
+			case "void":
+				got, err = g.Void(ctx, tt.reference)
+			case "refund":
+				got, err = g.Refund(ctx, tt.reference, tt.amount)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (!got.Approved || got.Reference != tt.reference) {
+				t.Errorf("got %+v, want aprobado con referencia %q", got, tt.reference)
+			}
+		})
+	}
+}
@@ -3,24 +3,40 @@ package models
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"tienda-online/internal/db"
 	sqlutil "tienda-online/internal/sql"
 )
 
 type Pedido struct {
-	IdPedido  int
-	IdUsuario int
-	Entregado bool
+	IdPedido               int
+	IdUsuario              int
+	Estado                 EstadoPedido
+	FechaCreacion          time.Time
+	FechaEntrega           sql.NullTime
+	IdDireccionEnvio       sql.NullInt32
+	IdDireccionFacturacion sql.NullInt32
+	Subtotal               float64
+	Descuento              float64
+	Impuesto               float64
+	Envio                  float64
+	Total                  float64
+	PreciosConImpuesto     bool
+	IdMetodoEnvio          sql.NullInt32
+	// Estado del ultimo pago del pedido (NULL si nunca se intento cobrar)
+	EstadoPago sql.NullString
 }
 
 func (p Pedido) String() string {
-	ent := "No entregado"
-	if p.Entregado {
-		ent = "Entregado"
+	pago := "sin pago"
+	if p.EstadoPago.Valid {
+		pago = "Pago " + p.EstadoPago.String
 	}
-	return fmt.Sprintf("[ Pedido #%d | UsuarioID:%d | %s ]", p.IdPedido, p.IdUsuario, ent)
+	return fmt.Sprintf("[ Pedido #%d | UsuarioID:%d | %s | %s | Total: %.2f | %s ]",
+		p.IdPedido, p.IdUsuario, p.Estado, p.FechaCreacion.Local().Format("2006-01-02 15:04"), p.Total, pago)
 }
 
 type PedidoManager struct {
@@ -62,21 +78,245 @@ func (m *PedidoManager) Get(ctx context.Context, id int) (*Pedido, error) {
 	return &items[0], nil
 }
 
-func (m *PedidoManager) Create(ctx context.Context, userId int, delivered bool) error {
+// CotizacionPedido es lo que costaria el carrito de un usuario enviado a una
+// direccion, con el mismo calculo que usa Create.
+type CotizacionPedido struct {
+	IdCarrito int
+	Items     []PedidoDetalle
+	PesoKg    float64
+	// Cupon aplicado en el carrito (nil si no tiene)
+	Cupon *Cupon
+	// Parte del descuento que viene del cupon; el resto es de promociones
+	DescuentoCupon float64
+	// Promociones que entraron, en el orden en que el motor las aplico
+	Promociones []AplicacionPromocion
+	Totales     TotalesPedido
+	Impuestos   CalculoImpuesto
+}
+
+// Quote calcula lineas, promociones, descuento del cupon, impuestos, envio y
+// totales del carrito del usuario sin crear el pedido. La region de la
+// direccion de envio decide los impuestos y la zona de la tarifa de envio.
+func (m *PedidoManager) Quote(ctx context.Context, userId, shippingAddressId, shippingMethodId int) (*CotizacionPedido, error) {
+	if err := requirePositive("idUsuario", userId); err != nil {
+		return nil, err
+	}
+	address, err := m.validarDireccion(ctx, userId, shippingAddressId, DireccionEnvio)
+	if err != nil {
+		return nil, err
+	}
+	carts := NewCarritoManager(m.db)
+	cart, err := carts.GetByUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	items, weight, err := carts.Items(ctx, cart.IdCarrito)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("el carrito del usuario %d esta vacio", userId)
+	}
+
+	promotions, err := NewPromocionManager(m.db).Evaluate(ctx, cart.IdCarrito)
+	if err != nil {
+		return nil, err
+	}
+	var coupon *Cupon
+	couponDiscount := 0.0
+	if cart.CodigoCupon.Valid {
+		coupon, couponDiscount, err = NewCuponManager(m.db).Discount(ctx, cart.CodigoCupon.String, userId, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+	discount := totalPromociones(promotions) + couponDiscount
+
+	region := regionDireccion(address)
+	taxes, err := NewImpuestoManager(m.db).Calculate(ctx, items, region, discount)
+	if err != nil {
+		return nil, err
+	}
+	// El umbral y los rangos de monto de las tarifas se comparan sin impuesto
+	productos := calcularTotalesPedido(items, discount, CalculoImpuesto{}, 0)
+	shipping, err := NewMetodoEnvioManager(m.db).Calculate(ctx, shippingMethodId, region, weight,
+		productos.Subtotal-productos.Descuento)
+	if err != nil {
+		return nil, err
+	}
+	return &CotizacionPedido{
+		IdCarrito:      cart.IdCarrito,
+		Items:          items,
+		PesoKg:         weight,
+		Cupon:          coupon,
+		DescuentoCupon: couponDiscount,
+		Promociones:    promotions,
+		Totales:        calcularTotalesPedido(items, discount, *taxes, shipping),
+		Impuestos:      *taxes,
+	}, nil
+}
+
+// Create arma un pedido con el contenido del carrito del usuario: valida las
+// direcciones, calcula descuento, impuestos y envio, y en una sola transaccion
+// guarda las lineas cotizadas, montos y promociones aplicadas, descuenta el
+// stock de los almacenes, canjea el cupon y vacia el carrito. Si a algun SKU le
+// falta stock no se guarda nada. Devuelve el ID del pedido nuevo.
+// billingAddressId en 0 deja el pedido sin direccion de facturacion.
+func (m *PedidoManager) Create(ctx context.Context, userId, shippingAddressId, billingAddressId, shippingMethodId int, actor string) (int, error) {
 	if err := ensureDB(m.db); err != nil {
-		return err
+		return 0, err
 	}
 	if err := requirePositive("idUsuario", userId); err != nil {
-		return err
+		return 0, err
+	}
+	actor, err := requireNonEmpty("actor", actor)
+	if err != nil {
+		return 0, err
 	}
-	_, err := db.ExecFromFile(ctx, "añadir/pedido.sql",
+	if billingAddressId > 0 {
+		if _, err := m.validarDireccion(ctx, userId, billingAddressId, DireccionFacturacion); err != nil {
+			return 0, err
+		}
+	}
+	quote, err := m.Quote(ctx, userId, shippingAddressId, shippingMethodId)
+	if err != nil {
+		return 0, err
+	}
+
+	// Estado previo de cada SKU para las alertas de stock
+	skus := NewSKUManager(m.db)
+	before := []*SKU{}
+	seen := map[int]bool{}
+	for _, item := range quote.Items {
+		if seen[item.IdSKU] {
+			continue
+		}
+		seen[item.IdSKU] = true
+		sku, err := skus.Get(ctx, item.IdSKU)
+		if err != nil {
+			return 0, err
+		}
+		before = append(before, sku)
+	}
+
+	lines, err := json.Marshal(lineasPedido(quote.Items))
+	if err != nil {
+		return 0, err
+	}
+	taxes, err := json.Marshal(lineasImpuesto(quote.Impuestos))
+	if err != nil {
+		return 0, err
+	}
+	promotions, err := json.Marshal(lineasPromocion(quote.Promociones))
+	if err != nil {
+		return 0, err
+	}
+	couponId := 0
+	if quote.Cupon != nil {
+		couponId = quote.Cupon.IdCupon
+	}
+	totals := quote.Totales
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/pedido.sql",
 		sql.Named("userId", userId),
-		sql.Named("delivered", delivered),
+		sql.Named("cartId", quote.IdCarrito),
+		sql.Named("shippingAddressId", shippingAddressId),
+		sql.Named("billingAddressId", optionalInt(billingAddressId)),
+		sql.Named("subtotal", totals.Subtotal),
+		sql.Named("discount", totals.Descuento),
+		sql.Named("tax", totals.Impuesto),
+		sql.Named("shipping", totals.Envio),
+		sql.Named("total", totals.Total),
+		sql.Named("pricesIncludeTax", quote.Impuestos.Incluido),
+		sql.Named("shippingMethodId", shippingMethodId),
+		sql.Named("couponId", optionalInt(couponId)),
+		sql.Named("couponDiscount", quote.DescuentoCupon),
+		sql.Named("lines", string(lines)),
+		sql.Named("taxes", string(taxes)),
+		sql.Named("promotions", string(promotions)),
+		sql.Named("remindedSince", time.Now().Add(-VentanaRecuperacionCarrito).UTC()),
+		sql.Named("actor", actor),
 	)
-	return err
+	if err != nil {
+		return 0, err
+	}
+	created, err := sqlutil.ParseRow[struct{ IdPedido int }](rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	if len(created) == 0 {
+		return 0, fmt.Errorf("no se obtuvo el ID del pedido creado")
+	}
+	orderId := created[0].IdPedido
+
+	for _, sku := range before {
+		if err := notifyStockChange(ctx, sku); err != nil {
+			return orderId, fmt.Errorf("pedido %d creado, pero fallo la alerta de stock del SKU %d: %w", orderId, sku.IdSKU, err)
+		}
+	}
+	return orderId, nil
+}
+
+// validarDireccion revisa que la direccion exista, sea del usuario y del tipo
+// esperado, y la devuelve.
+func (m *PedidoManager) validarDireccion(ctx context.Context, userId, addressId int, tipo string) (*Direccion, error) {
+	if err := requirePositive("idDireccion", addressId); err != nil {
+		return nil, err
+	}
+	address, err := NewDireccionManager(m.db).Get(ctx, addressId)
+	if err != nil {
+		return nil, err
+	}
+	if address.IdUsuario != userId {
+		return nil, fmt.Errorf("la direccion %d no pertenece al usuario %d", addressId, userId)
+	}
+	if address.Tipo != tipo {
+		return nil, fmt.Errorf("la direccion %d es de %s, se esperaba %s", addressId, address.Tipo, tipo)
+	}
+	return address, nil
 }
 
-func (m *PedidoManager) Update(ctx context.Context, id, userId int, delivered bool) error {
+// Items obtiene las lineas de un pedido.
+func (m *PedidoManager) Items(ctx context.Context, id int) ([]PedidoDetalle, error) {
+	if err := requirePositive("idPedido", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/pedido_detalle_por_pedido.sql", sql.Named("orderId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[PedidoDetalle](rows)
+}
+
+// Taxes obtiene el desglose de impuestos por tasa guardado al crear el pedido.
+func (m *PedidoManager) Taxes(ctx context.Context, id int) ([]PedidoImpuesto, error) {
+	if err := requirePositive("idPedido", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/pedido_impuesto_por_pedido.sql", sql.Named("orderId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[PedidoImpuesto](rows)
+}
+
+// Promotions obtiene las promociones que se aplicaron al crear el pedido.
+func (m *PedidoManager) Promotions(ctx context.Context, id int) ([]PedidoPromocion, error) {
+	if err := requirePositive("idPedido", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/pedido_promocion_por_pedido.sql", sql.Named("orderId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[PedidoPromocion](rows)
+}
+
+// Update cambia el cliente del pedido; el estado solo se cambia con Transition.
+func (m *PedidoManager) Update(ctx context.Context, id, userId int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
 	}
@@ -89,11 +329,89 @@ func (m *PedidoManager) Update(ctx context.Context, id, userId int, delivered bo
 	_, err := db.ExecFromFile(ctx, "editar/pedido.sql",
 		sql.Named("id", id),
 		sql.Named("userId", userId),
-		sql.Named("delivered", delivered),
 	)
 	return err
 }
 
+// Transition mueve el pedido al estado indicado si la tabla de transiciones lo
+// permite, y deja el cambio en PedidoHistorial con el actor que lo hizo.
+func (m *PedidoManager) Transition(ctx context.Context, id int, to EstadoPedido, actor string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	actor, err := requireNonEmpty("actor", actor)
+	if err != nil {
+		return err
+	}
+	order, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !order.Estado.PuedeCambiarA(to) {
+		return fmt.Errorf("pedido %d: no se puede pasar de %s a %s", id, order.Estado, to)
+	}
+	_, err = db.ExecFromFile(ctx, "editar/pedido_estado.sql",
+		sql.Named("id", id),
+		sql.Named("from", string(order.Estado)),
+		sql.Named("to", string(to)),
+		sql.Named("actor", actor),
+	)
+	return err
+}
+
+// MarkPaid pasa el pedido de Pendiente a Pagado.
+func (m *PedidoManager) MarkPaid(ctx context.Context, id int, actor string) error {
+	return m.Transition(ctx, id, PedidoPagado, actor)
+}
+
+// Ship pasa el pedido de Pagado a Enviado.
+func (m *PedidoManager) Ship(ctx context.Context, id int, actor string) error {
+	return m.Transition(ctx, id, PedidoEnviado, actor)
+}
+
+// Deliver pasa el pedido de Enviado a Entregado.
+func (m *PedidoManager) Deliver(ctx context.Context, id int, actor string) error {
+	return m.Transition(ctx, id, PedidoEntregado, actor)
+}
+
+// Cancel cancela un pedido que todavia no fue enviado. Antes libera su pago:
+// uno autorizado se anula y uno capturado se reembolsa por lo que quede; si la
+// pasarela falla el pedido no se cancela.
+func (m *PedidoManager) Cancel(ctx context.Context, id int, actor string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	order, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !order.Estado.PuedeCambiarA(PedidoCancelado) {
+		return fmt.Errorf("pedido %d: no se puede pasar de %s a %s", id, order.Estado, PedidoCancelado)
+	}
+	if err := NewPagoManager(m.db).releaseOrder(ctx, id); err != nil {
+		return fmt.Errorf("pedido %d: no se pudo liberar el pago: %w", id, err)
+	}
+	return m.Transition(ctx, id, PedidoCancelado, actor)
+}
+
+// MarkReturned marca como devuelto un pedido entregado.
+func (m *PedidoManager) MarkReturned(ctx context.Context, id int, actor string) error {
+	return m.Transition(ctx, id, PedidoDevuelto, actor)
+}
+
+// History obtiene los cambios de estado de un pedido en orden cronologico.
+func (m *PedidoManager) History(ctx context.Context, id int) ([]PedidoHistorial, error) {
+	if err := requirePositive("idPedido", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/pedido_historial.sql", sql.Named("orderId", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[PedidoHistorial](rows)
+}
+
 func (m *PedidoManager) Delete(ctx context.Context, id int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+// PedidoDetalle es una linea de un pedido con el precio pagado por unidad.
+type PedidoDetalle struct {
+	IdDetalle      int
+	IdPedido       int
+	IdSKU          int
+	Cantidad       int
+	PrecioUnitario float64
+	Codigo         string
+	Variante       sql.NullString
+}
+
+func (d PedidoDetalle) String() string {
+	return fmt.Sprintf("[ Linea #%d | %s | Cantidad:%d | Precio: %.2f | Importe: %.2f ]",
+		d.IdDetalle, etiquetaSKU(d.IdSKU, d.Codigo, d.Variante), d.Cantidad, d.PrecioUnitario, d.Importe())
+}
+
+// Importe es el precio de la linea sin descuentos ni impuestos.
+func (d PedidoDetalle) Importe() float64 {
+	return redondear(d.PrecioUnitario * float64(d.Cantidad))
+}
+
+// lineaPedido es una linea cotizada tal como se guarda en el pedido.
+type lineaPedido struct {
+	IdSKU          int     `json:"idSKU"`
+	Cantidad       int     `json:"cantidad"`
+	PrecioUnitario float64 `json:"precioUnitario"`
+}
+
+func lineasPedido(items []PedidoDetalle) []lineaPedido {
+	lines := make([]lineaPedido, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, lineaPedido{IdSKU: item.IdSKU, Cantidad: item.Cantidad, PrecioUnitario: item.PrecioUnitario})
+	}
+	return lines
+}
+
+// TotalesPedido son los montos que se guardan en el pedido al crearlo.
+type TotalesPedido struct {
+	Subtotal  float64
+	Descuento float64
+	Impuesto  float64
+	Envio     float64
+	Total     float64
+}
+
+// calcularTotalesPedido suma las lineas y aplica descuento, impuesto y envio.
+// Si el impuesto viene incluido en los precios solo se informa, no se suma.
+// El total nunca baja de cero aunque el descuento supere el subtotal.
+func calcularTotalesPedido(items []PedidoDetalle, descuento float64, impuesto CalculoImpuesto, envio float64) TotalesPedido {
+	subtotal := 0.0
+	for _, item := range items {
+		subtotal += item.Importe()
+	}
+	subtotal = redondear(subtotal)
+	descuento = redondear(math.Min(descuento, subtotal))
+	total := subtotal - descuento + envio
+	if !impuesto.Incluido {
+		total += impuesto.Total
+	}
+	total = redondear(total)
+	return TotalesPedido{
+		Subtotal:  subtotal,
+		Descuento: descuento,
+		Impuesto:  redondear(impuesto.Total),
+		Envio:     redondear(envio),
+		Total:     math.Max(total, 0),
+	}
+}
+
+// redondear deja un monto en centavos, igual que las columnas DECIMAL(10,2).
+func redondear(value float64) float64 {
+	return math.Round(value*100) / 100
+}
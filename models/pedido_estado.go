@@ -0,0 +1,82 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"tienda-online/internal"
+)
+
+// EstadoPedido es el estado del ciclo de vida de un pedido (columna Pedido.estado).
+type EstadoPedido string
+
+const (
+	PedidoPendiente EstadoPedido = "Pendiente"
+	PedidoPagado    EstadoPedido = "Pagado"
+	PedidoEnviado   EstadoPedido = "Enviado"
+	PedidoEntregado EstadoPedido = "Entregado"
+	PedidoCancelado EstadoPedido = "Cancelado"
+	PedidoDevuelto  EstadoPedido = "Devuelto"
+)
+
+// EstadosPedido lista todos los estados en el orden normal del ciclo.
+var EstadosPedido = []EstadoPedido{
+	PedidoPendiente, PedidoPagado, PedidoEnviado, PedidoEntregado, PedidoCancelado, PedidoDevuelto,
+}
+
+// transicionesPedido es la tabla de cambios permitidos; los estados sin
+// entrada (Cancelado, Devuelto) son finales.
+var transicionesPedido = map[EstadoPedido][]EstadoPedido{
+	PedidoPendiente: {PedidoPagado, PedidoCancelado},
+	PedidoPagado:    {PedidoEnviado, PedidoCancelado},
+	PedidoEnviado:   {PedidoEntregado},
+	PedidoEntregado: {PedidoDevuelto},
+}
+
+// ParseEstadoPedido acepta el nombre del estado sin importar mayusculas.
+func ParseEstadoPedido(value string) (EstadoPedido, error) {
+	trim := strings.TrimSpace(value)
+	for _, e := range EstadosPedido {
+		if strings.EqualFold(string(e), trim) {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("estado de pedido invalido: %q", value)
+}
+
+// Siguientes devuelve los estados a los que se puede pasar desde e.
+func (e EstadoPedido) Siguientes() []EstadoPedido {
+	return transicionesPedido[e]
+}
+
+// PuedeCambiarA indica si la tabla de transiciones permite pasar de e a to.
+func (e EstadoPedido) PuedeCambiarA(to EstadoPedido) bool {
+	for _, next := range transicionesPedido[e] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Final indica si ya no se permite ninguna transicion.
+func (e EstadoPedido) Final() bool {
+	return len(transicionesPedido[e]) == 0
+}
+
+// PedidoHistorial es un cambio de estado registrado para un pedido.
+type PedidoHistorial struct {
+	IdHistorial    int
+	IdPedido       int
+	EstadoAnterior sql.NullString
+	EstadoNuevo    EstadoPedido
+	Fecha          time.Time
+	Actor          string
+}
+
+func (h PedidoHistorial) String() string {
+	return fmt.Sprintf("[ %s | %s -> %s | por %s ]",
+		h.Fecha.Local().Format("2006-01-02 15:04:05"), internal.NullString(h.EstadoAnterior), h.EstadoNuevo, h.Actor)
+}
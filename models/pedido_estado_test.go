@@ -0,0 +1,79 @@
+package models
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEstadoPedidoTransiciones(t *testing.T) {
+	// Tabla completa esperada: cualquier par que no figure aca esta prohibido
+	permitidas := map[EstadoPedido][]EstadoPedido{
+		PedidoPendiente: {PedidoPagado, PedidoCancelado},
+		PedidoPagado:    {PedidoEnviado, PedidoCancelado},
+		PedidoEnviado:   {PedidoEntregado},
+		PedidoEntregado: {PedidoDevuelto},
+		PedidoCancelado: nil,
+		PedidoDevuelto:  nil,
+	}
+	for _, from := range EstadosPedido {
+		for _, to := range EstadosPedido {
+			want := slices.Contains(permitidas[from], to)
+			if got := from.PuedeCambiarA(to); got != want {
+				t.Errorf("%s.PuedeCambiarA(%s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+
+	tests := []struct {
+		estado    EstadoPedido
+		siguiente []EstadoPedido
+		final     bool
+	}{
+		{PedidoPendiente, []EstadoPedido{PedidoPagado, PedidoCancelado}, false},
+		{PedidoPagado, []EstadoPedido{PedidoEnviado, PedidoCancelado}, false},
+		{PedidoEnviado, []EstadoPedido{PedidoEntregado}, false},
+		{PedidoEntregado, []EstadoPedido{PedidoDevuelto}, false},
+		{PedidoCancelado, nil, true},
+		{PedidoDevuelto, nil, true},
+		{EstadoPedido("Perdido"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.estado), func(t *testing.T) {
+			if got := tt.estado.Siguientes(); !slices.Equal(got, tt.siguiente) {
+				t.Errorf("Siguientes() = %v, want %v", got, tt.siguiente)
+			}
+			if got := tt.estado.Final(); got != tt.final {
+				t.Errorf("Final() = %v, want %v", got, tt.final)
+			}
+		})
+	}
+}
+
+func TestParseEstadoPedido(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    EstadoPedido
+		wantErr bool
+	}{
+		{"Pendiente", PedidoPendiente, false},
+		{"pagado", PedidoPagado, false},
+		{"  ENVIADO ", PedidoEnviado, false},
+		{"entregado", PedidoEntregado, false},
+		{"Cancelado", PedidoCancelado, false},
+		{"devuelto", PedidoDevuelto, false},
+		{"", "", true},
+		{"Entregad", "", true},
+		{"perdido", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseEstadoPedido(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseEstadoPedido(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -13,6 +13,9 @@ type Producto struct {
 	IdProducto  int
 	Descripcion string
 	IdCategoria sql.NullInt32
+	// Promedio de las reseñas aprobadas, sin valor si no tiene (de ProductoCalificacion)
+	Calificacion sql.NullFloat64
+	Resenas      int
 }
 
 func (p Producto) String() string {
@@ -20,7 +23,11 @@ func (p Producto) String() string {
 	if p.IdCategoria.Valid {
 		cat = fmt.Sprintf("CategoriaID:%d", p.IdCategoria.Int32)
 	}
-	return fmt.Sprintf("[ Producto #%d | %s | %s ]", p.IdProducto, p.Descripcion, cat)
+	rating := "sin reseñas"
+	if p.Calificacion.Valid {
+		rating = fmt.Sprintf("%.1f/5 (%d)", p.Calificacion.Float64, p.Resenas)
+	}
+	return fmt.Sprintf("[ Producto #%d | %s | %s | %s ]", p.IdProducto, p.Descripcion, cat, rating)
 }
 
 type ProductoManager struct {
@@ -62,6 +69,19 @@ func (m *ProductoManager) Get(ctx context.Context, id int) (*Producto, error) {
 	return &items[0], nil
 }
 
+// ListByCategoria obtiene los productos de la categoria y de todas sus subcategorias.
+func (m *ProductoManager) ListByCategoria(ctx context.Context, categoryId int) ([]Producto, error) {
+	if err := requirePositive("idCategoria", categoryId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/producto_por_categoria.sql", sql.Named("categoryId", categoryId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Producto](rows)
+}
+
 func (m *ProductoManager) Create(ctx context.Context, description string, categoryId int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -103,6 +123,21 @@ func (m *ProductoManager) Delete(ctx context.Context, id int) error {
 	if err := requirePositive("idProducto", id); err != nil {
 		return err
 	}
-	_, err := db.ExecFromFile(ctx, "remover/producto.sql", sql.Named("id", id))
-	return err
+	// Las filas de ProductoImagen se borran en cascada; los archivos no, asi
+	// que se buscan antes de borrar el producto.
+	images, err := NewProductoImagenManager(m.db).ListByProducto(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecFromFile(ctx, "remover/producto.sql", sql.Named("id", id)); err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return nil
+	}
+	store, err := currentBlobStore()
+	if err != nil {
+		return err
+	}
+	return borrarArchivosImagen(ctx, store, images)
 }
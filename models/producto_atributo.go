@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// ProductoAtributo es una opcion que distingue los SKUs de un producto
+// (talla, color, ...).
+type ProductoAtributo struct {
+	IdAtributo int
+	IdProducto int
+	Nombre     string
+	Orden      int
+}
+
+func (a ProductoAtributo) String() string {
+	return fmt.Sprintf("[ Atributo #%d | ProductoID:%d | %s | Orden: %d ]", a.IdAtributo, a.IdProducto, a.Nombre, a.Orden)
+}
+
+// SKUAtributo es el valor de un atributo del producto en un SKU.
+type SKUAtributo struct {
+	IdSKU      int
+	IdAtributo int
+	Nombre     string
+	Valor      string
+}
+
+func (v SKUAtributo) String() string {
+	return fmt.Sprintf("[ SKU #%d | %s: %s ]", v.IdSKU, v.Nombre, v.Valor)
+}
+
+type ProductoAtributoManager struct {
+	db *sql.DB
+}
+
+func NewProductoAtributoManager(database *sql.DB) *ProductoAtributoManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &ProductoAtributoManager{db: database}
+}
+
+// ListByProducto obtiene los atributos de un producto en orden de etiqueta.
+func (m *ProductoAtributoManager) ListByProducto(ctx context.Context, productId int) ([]ProductoAtributo, error) {
+	if err := requirePositive("idProducto", productId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/producto_atributo_por_producto.sql", sql.Named("productId", productId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[ProductoAtributo](rows)
+}
+
+func (m *ProductoAtributoManager) Get(ctx context.Context, id int) (*ProductoAtributo, error) {
+	if err := requirePositive("idAtributo", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/producto_atributo_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[ProductoAtributo](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("atributo %d no encontrado", id)
+	}
+	return &items[0], nil
+}
+
+// Create agrega un atributo al producto; order decide su posicion en la etiqueta.
+func (m *ProductoAtributoManager) Create(ctx context.Context, productId int, name string, order int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idProducto", productId); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "añadir/producto_atributo.sql",
+		sql.Named("productId", productId),
+		sql.Named("name", name),
+		sql.Named("order", order),
+	)
+	return err
+}
+
+func (m *ProductoAtributoManager) Update(ctx context.Context, id int, name string, order int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idAtributo", id); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/producto_atributo.sql",
+		sql.Named("id", id),
+		sql.Named("name", name),
+		sql.Named("order", order),
+	)
+	return err
+}
+
+// Delete borra el atributo y su valor en todos los SKUs del producto.
+func (m *ProductoAtributoManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idAtributo", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/producto_atributo.sql", sql.Named("id", id))
+	return err
+}
+
+// Values obtiene los valores de atributos de un SKU.
+func (m *ProductoAtributoManager) Values(ctx context.Context, skuId int) ([]SKUAtributo, error) {
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_atributo_por_sku.sql", sql.Named("skuId", skuId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[SKUAtributo](rows)
+}
+
+// SetValue fija el valor del atributo en el SKU; value vacio lo quita. El
+// atributo tiene que ser del producto del SKU.
+func (m *ProductoAtributoManager) SetValue(ctx context.Context, skuId, attributeId int, value string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return err
+	}
+	if err := requirePositive("idAtributo", attributeId); err != nil {
+		return err
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		_, err := db.ExecFromFile(ctx, "remover/sku_atributo.sql",
+			sql.Named("skuId", skuId),
+			sql.Named("attributeId", attributeId),
+		)
+		return err
+	}
+	if len(value) > 50 {
+		return fmt.Errorf("valor no puede tener mas de 50 caracteres")
+	}
+	_, err := db.ExecFromFile(ctx, "editar/sku_atributo.sql",
+		sql.Named("skuId", skuId),
+		sql.Named("attributeId", attributeId),
+		sql.Named("value", value),
+	)
+	return err
+}
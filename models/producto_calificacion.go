@@ -0,0 +1,95 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// CalificacionProducto resume las reseñas aprobadas de un producto. Se
+// mantiene al crear, editar, moderar o borrar reseñas.
+type CalificacionProducto struct {
+	IdProducto int
+	Total      int
+	// Sin valor mientras no haya reseñas aprobadas
+	Promedio sql.NullFloat64
+	// Cantidad de reseñas con 1 a 5 estrellas
+	Estrellas1         int
+	Estrellas2         int
+	Estrellas3         int
+	Estrellas4         int
+	Estrellas5         int
+	FechaActualizacion sql.NullTime
+}
+
+// Distribucion devuelve la cantidad de reseñas por estrellas; el indice 0 es 1 estrella.
+func (c CalificacionProducto) Distribucion() [5]int {
+	return [5]int{c.Estrellas1, c.Estrellas2, c.Estrellas3, c.Estrellas4, c.Estrellas5}
+}
+
+func (c CalificacionProducto) String() string {
+	if !c.Promedio.Valid {
+		return fmt.Sprintf("[ Producto #%d | sin reseñas aprobadas ]", c.IdProducto)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[ Producto #%d | %.2f/5 | %d reseñas ]", c.IdProducto, c.Promedio.Float64, c.Total)
+	dist := c.Distribucion()
+	for stars := 5; stars >= 1; stars-- {
+		n := dist[stars-1]
+		fmt.Fprintf(&b, "\n  %d★ %-20s %d", stars, strings.Repeat("#", barraCalificacion(n, c.Total, 20)), n)
+	}
+	return b.String()
+}
+
+// barraCalificacion escala n sobre total a un largo de hasta width.
+func barraCalificacion(n, total, width int) int {
+	if total == 0 {
+		return 0
+	}
+	return (n*width + total/2) / total
+}
+
+// Rating obtiene el promedio y la distribucion de estrellas de un producto.
+func (m *ProductoManager) Rating(ctx context.Context, productId int) (*CalificacionProducto, error) {
+	if err := requirePositive("idProducto", productId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/producto_calificacion_por_producto.sql", sql.Named("productId", productId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[CalificacionProducto](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("producto %d no encontrado", productId)
+	}
+	return &items[0], nil
+}
+
+// RebuildRatings recalcula desde cero la calificacion de todos los productos
+// (por ejemplo despues de cargar reseñas a mano) y devuelve cuantos quedaron.
+func (m *ProductoManager) RebuildRatings(ctx context.Context) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "editar/producto_calificacion_reconstruir.sql")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	result, err := sqlutil.ParseRow[struct{ Productos int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return result[0].Productos, nil
+}
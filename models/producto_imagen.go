@@ -0,0 +1,419 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"tienda-online/internal"
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// Limites de las imagenes que se aceptan.
+const (
+	maxBytesImagen     = 5 << 20
+	maxLadoImagen      = 6000
+	ladoMiniatura      = 200
+	calidadMiniatura   = 85
+	prefijoClaveMedios = "productos/"
+)
+
+// GraciaArchivosHuerfanos es cuanto tiene una subida para registrar su imagen
+// antes de que CleanupOrphans considere huerfanos sus archivos.
+const GraciaArchivosHuerfanos = time.Hour
+
+// extensionesImagen son los tipos aceptados con la extension de su archivo.
+var extensionesImagen = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ProductoImagen es una imagen de la galeria de un producto.
+type ProductoImagen struct {
+	IdImagen         int
+	IdProducto       int
+	Clave            string
+	ClaveMiniatura   string
+	TipoContenido    string
+	Tamano           int
+	Ancho            int
+	Alto             int
+	TextoAlternativo sql.NullString
+	Orden            int
+	Principal        bool
+	FechaCreacion    time.Time
+}
+
+func (i ProductoImagen) String() string {
+	principal := ""
+	if i.Principal {
+		principal = " | Principal"
+	}
+	return fmt.Sprintf("[ Imagen #%d | ProductoID:%d | %s | %dx%d | %d KB | Orden: %d | Alt: %s%s ]",
+		i.IdImagen, i.IdProducto, i.Clave, i.Ancho, i.Alto, (i.Tamano+1023)/1024, i.Orden,
+		internal.NullString(i.TextoAlternativo), principal)
+}
+
+// imagenValidada es lo que validarImagen averigua del archivo.
+type imagenValidada struct {
+	TipoContenido string
+	Ancho         int
+	Alto          int
+}
+
+// validarImagen revisa tamaño, que el contenido real coincida con el tipo
+// declarado (si se declaro) y que las dimensiones sean razonables antes de
+// decodificar la imagen completa.
+func validarImagen(data []byte, declaredType string) (*imagenValidada, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("la imagen esta vacia")
+	}
+	if len(data) > maxBytesImagen {
+		return nil, fmt.Errorf("la imagen pesa %d KB, el maximo es %d KB", len(data)/1024, maxBytesImagen/1024)
+	}
+	sniffed := http.DetectContentType(data)
+	if _, ok := extensionesImagen[sniffed]; !ok {
+		return nil, fmt.Errorf("tipo de archivo no permitido: %s (se aceptan JPEG, PNG y GIF)", sniffed)
+	}
+	declaredType = strings.ToLower(strings.TrimSpace(declaredType))
+	if declaredType == "image/jpg" {
+		declaredType = "image/jpeg"
+	}
+	if declaredType != "" && declaredType != sniffed {
+		return nil, fmt.Errorf("el archivo dice ser %s pero su contenido es %s", declaredType, sniffed)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("la imagen no se puede leer: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxLadoImagen || cfg.Height > maxLadoImagen {
+		return nil, fmt.Errorf("la imagen mide %dx%d, el maximo es %dx%d", cfg.Width, cfg.Height, maxLadoImagen, maxLadoImagen)
+	}
+	return &imagenValidada{TipoContenido: sniffed, Ancho: cfg.Width, Alto: cfg.Height}, nil
+}
+
+// tamanoMiniatura ajusta ancho y alto para que el lado mayor mida a lo sumo
+// side, sin agrandar imagenes chicas.
+func tamanoMiniatura(width, height, side int) (int, int) {
+	if width <= side && height <= side {
+		return width, height
+	}
+	if width >= height {
+		return side, max(height*side/width, 1)
+	}
+	return max(width*side/height, 1), side
+}
+
+// reducirImagen escala src promediando los pixeles de origen que caen en cada
+// pixel de destino, lo que evita el aliasing del vecino mas cercano.
+func reducirImagen(src image.Image, width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	b := src.Bounds()
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*b.Dy()/height
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*b.Dx()/width
+			x1 := max(b.Min.X+(x+1)*b.Dx()/width, x0+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// generarMiniatura devuelve la miniatura codificada en el mismo tipo que el
+// original, salvo GIF que pasa a PNG para no perder colores al reducir.
+func generarMiniatura(data []byte, contentType string) ([]byte, string, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("la imagen no se puede leer: %w", err)
+	}
+	w, h := tamanoMiniatura(src.Bounds().Dx(), src.Bounds().Dy(), ladoMiniatura)
+	thumb := reducirImagen(src, w, h)
+	var out bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&out, thumb, &jpeg.Options{Quality: calidadMiniatura})
+	} else {
+		contentType = "image/png"
+		err = png.Encode(&out, thumb)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return out.Bytes(), contentType, nil
+}
+
+// claveImagen arma una clave unica para un archivo del producto.
+func claveImagen(productId int, suffix, ext string) (string, error) {
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%d/%s%s%s", prefijoClaveMedios, productId, hex.EncodeToString(random), suffix, ext), nil
+}
+
+type ProductoImagenManager struct {
+	db *sql.DB
+}
+
+func NewProductoImagenManager(database *sql.DB) *ProductoImagenManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &ProductoImagenManager{db: database}
+}
+
+// ListByProducto obtiene la galeria de un producto, la principal primero.
+func (m *ProductoImagenManager) ListByProducto(ctx context.Context, productId int) ([]ProductoImagen, error) {
+	if err := requirePositive("idProducto", productId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/producto_imagen_por_producto.sql", sql.Named("productId", productId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[ProductoImagen](rows)
+}
+
+func (m *ProductoImagenManager) Get(ctx context.Context, id int) (*ProductoImagen, error) {
+	if err := requirePositive("idImagen", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/producto_imagen_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[ProductoImagen](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("imagen %d no encontrada", id)
+	}
+	return &items[0], nil
+}
+
+// Upload valida la imagen, guarda original y miniatura en el BlobStore y
+// registra la imagen al final de la galeria. contentType vacio acepta lo que
+// diga el contenido. Devuelve el ID de la imagen nueva.
+func (m *ProductoImagenManager) Upload(ctx context.Context, productId int, contentType string, data []byte, altText string) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	store, err := currentBlobStore()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := NewProductoManager(m.db).Get(ctx, productId); err != nil {
+		return 0, err
+	}
+	if len(strings.TrimSpace(altText)) > 200 {
+		return 0, fmt.Errorf("texto alternativo no puede tener mas de 200 caracteres")
+	}
+	info, err := validarImagen(data, contentType)
+	if err != nil {
+		return 0, err
+	}
+	thumb, thumbType, err := generarMiniatura(data, info.TipoContenido)
+	if err != nil {
+		return 0, err
+	}
+	key, err := claveImagen(productId, "", extensionesImagen[info.TipoContenido])
+	if err != nil {
+		return 0, err
+	}
+	thumbKey := strings.TrimSuffix(key, extensionesImagen[info.TipoContenido]) + "_min" + extensionesImagen[thumbType]
+
+	if err := store.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return 0, err
+	}
+	if err := store.Put(ctx, thumbKey, bytes.NewReader(thumb)); err != nil {
+		store.Delete(ctx, key)
+		return 0, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/producto_imagen.sql",
+		sql.Named("productId", productId),
+		sql.Named("key", key),
+		sql.Named("thumbnailKey", thumbKey),
+		sql.Named("contentType", info.TipoContenido),
+		sql.Named("size", len(data)),
+		sql.Named("width", info.Ancho),
+		sql.Named("height", info.Alto),
+		sql.Named("altText", optionalString(altText)),
+	)
+	if err != nil {
+		// Sin registro los archivos quedarian huerfanos
+		store.Delete(ctx, key)
+		store.Delete(ctx, thumbKey)
+		return 0, err
+	}
+	defer rows.Close()
+	created, err := sqlutil.ParseRow[struct{ IdImagen int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(created) == 0 {
+		return 0, fmt.Errorf("no se obtuvo el ID de la imagen creada")
+	}
+	return created[0].IdImagen, nil
+}
+
+// Update cambia el texto alternativo y la posicion en la galeria.
+func (m *ProductoImagenManager) Update(ctx context.Context, id int, altText string, order int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idImagen", id); err != nil {
+		return err
+	}
+	if order < 0 {
+		return fmt.Errorf("orden no puede ser negativo")
+	}
+	if len(strings.TrimSpace(altText)) > 200 {
+		return fmt.Errorf("texto alternativo no puede tener mas de 200 caracteres")
+	}
+	_, err := db.ExecFromFile(ctx, "editar/producto_imagen.sql",
+		sql.Named("id", id),
+		sql.Named("altText", optionalString(altText)),
+		sql.Named("order", order),
+	)
+	return err
+}
+
+// SetPrimary deja la imagen como la principal de su producto.
+func (m *ProductoImagenManager) SetPrimary(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if _, err := m.Get(ctx, id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "editar/producto_imagen_principal.sql", sql.Named("id", id))
+	return err
+}
+
+// Open abre el original (o la miniatura) de una imagen para leerlo.
+func (m *ProductoImagenManager) Open(ctx context.Context, id int, thumbnail bool) (io.ReadCloser, *ProductoImagen, error) {
+	store, err := currentBlobStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := img.Clave
+	if thumbnail {
+		key = img.ClaveMiniatura
+	}
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r, img, nil
+}
+
+// Delete borra el registro y despues sus archivos. Si borrar los archivos
+// falla quedan como huerfanos para CleanupOrphans.
+func (m *ProductoImagenManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	store, err := currentBlobStore()
+	if err != nil {
+		return err
+	}
+	img, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecFromFile(ctx, "remover/producto_imagen.sql", sql.Named("id", id)); err != nil {
+		return err
+	}
+	return borrarArchivosImagen(ctx, store, []ProductoImagen{*img})
+}
+
+// CleanupOrphans borra del BlobStore los archivos de productos que ninguna
+// imagen referencia (por ejemplo si un borrado se corto a medias) y devuelve
+// las claves eliminadas. Los archivos escritos hace menos de
+// GraciaArchivosHuerfanos se dejan, porque pueden ser de una subida en curso.
+func (m *ProductoImagenManager) CleanupOrphans(ctx context.Context) ([]string, error) {
+	if err := ensureDB(m.db); err != nil {
+		return nil, err
+	}
+	store, err := currentBlobStore()
+	if err != nil {
+		return nil, err
+	}
+	// Se listan los archivos antes de leer la base, asi una imagen registrada
+	// despues del listado no puede tener archivos viejos sin referencia.
+	threshold := time.Now().Add(-GraciaArchivosHuerfanos)
+	blobs, err := store.List(ctx, prefijoClaveMedios)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/producto_imagen_claves.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	used, err := sqlutil.ParseRow[struct{ Clave string }](rows)
+	if err != nil {
+		return nil, err
+	}
+	referenced := make(map[string]bool, len(used))
+	for _, u := range used {
+		referenced[u.Clave] = true
+	}
+	removed := []string{}
+	for _, blob := range blobs {
+		if referenced[blob.Clave] || blob.Modificado.After(threshold) {
+			continue
+		}
+		if err := store.Delete(ctx, blob.Clave); err != nil {
+			return removed, err
+		}
+		removed = append(removed, blob.Clave)
+	}
+	return removed, nil
+}
+
+// borrarArchivosImagen quita original y miniatura de cada imagen.
+func borrarArchivosImagen(ctx context.Context, store BlobStore, images []ProductoImagen) error {
+	for _, img := range images {
+		if err := store.Delete(ctx, img.Clave); err != nil {
+			return fmt.Errorf("imagen %d borrada, pero no su archivo: %w", img.IdImagen, err)
+		}
+		if err := store.Delete(ctx, img.ClaveMiniatura); err != nil {
+			return fmt.Errorf("imagen %d borrada, pero no su miniatura: %w", img.IdImagen, err)
+		}
+	}
+	return nil
+}
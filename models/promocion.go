@@ -0,0 +1,581 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// Valores permitidos por el CHECK de Promocion.tipo.
+const (
+	// Por cada CantidadCompra unidades del SKU, CantidadGratis mas sin costo.
+	PromoCompraXLlevaY = "CompraXLlevaY"
+	// Porcentaje de descuento en las lineas de una categoria.
+	PromoPorcentajeCategoria = "PorcentajeCategoria"
+	// Porcentaje sobre el carrito segun el escalon que alcance su total.
+	PromoEscalonada = "Escalonada"
+	// Precio especial de un SKU por tiempo limitado.
+	PromoPrecioFlash = "PrecioFlash"
+)
+
+var tiposPromocion = []string{PromoCompraXLlevaY, PromoPorcentajeCategoria, PromoEscalonada, PromoPrecioFlash}
+
+type Promocion struct {
+	IdPromocion    int
+	Nombre         string
+	Tipo           string
+	Prioridad      int
+	Acumulable     bool
+	IdSKU          sql.NullInt32
+	IdCategoria    sql.NullInt32
+	CantidadCompra sql.NullInt32
+	CantidadGratis sql.NullInt32
+	Porcentaje     sql.NullFloat64
+	PrecioEspecial sql.NullFloat64
+	VigenteDesde   sql.NullTime
+	VigenteHasta   sql.NullTime
+	Activo         bool
+	// Codigo y variante del SKU (calculados al leer)
+	Codigo   sql.NullString
+	Variante sql.NullString
+}
+
+func (p Promocion) String() string {
+	regla := ""
+	switch p.Tipo {
+	case PromoCompraXLlevaY:
+		regla = fmt.Sprintf("%s: compra %d lleva %d", etiquetaSKU(int(p.IdSKU.Int32), p.Codigo.String, p.Variante), p.CantidadCompra.Int32, p.CantidadGratis.Int32)
+	case PromoPorcentajeCategoria:
+		regla = fmt.Sprintf("CategoriaID:%d -%.2f%%", p.IdCategoria.Int32, p.Porcentaje.Float64)
+	case PromoEscalonada:
+		regla = "por total del carrito"
+	case PromoPrecioFlash:
+		regla = fmt.Sprintf("%s a %.2f", etiquetaSKU(int(p.IdSKU.Int32), p.Codigo.String, p.Variante), p.PrecioEspecial.Float64)
+	}
+	acumula := "exclusiva"
+	if p.Acumulable {
+		acumula = "acumulable"
+	}
+	estado := "Activa"
+	if !p.Activo {
+		estado = "Inactiva"
+	}
+	return fmt.Sprintf("[ Promocion #%d | %s | %s | %s | Prioridad: %d | %s | %s a %s | %s ]",
+		p.IdPromocion, p.Nombre, p.Tipo, regla, p.Prioridad, acumula,
+		fechaCupon(p.VigenteDesde), fechaCupon(p.VigenteHasta), estado)
+}
+
+// vigente indica si la promocion esta activa y dentro de sus fechas.
+func (p Promocion) vigente(now time.Time) bool {
+	if !p.Activo {
+		return false
+	}
+	if p.VigenteDesde.Valid && now.Before(p.VigenteDesde.Time) {
+		return false
+	}
+	if p.VigenteHasta.Valid && !now.Before(p.VigenteHasta.Time) {
+		return false
+	}
+	return true
+}
+
+// PromocionEscalon es un tramo de una promocion Escalonada.
+type PromocionEscalon struct {
+	IdPromocion int
+	MontoMinimo float64
+	Porcentaje  float64
+}
+
+func (e PromocionEscalon) String() string {
+	return fmt.Sprintf("[ Desde %.2f: -%.2f%% ]", e.MontoMinimo, e.Porcentaje)
+}
+
+// AplicacionPromocion explica cuanto desconto una promocion y sobre que linea
+// del carrito. IdDetalle e IdSKU quedan en 0 cuando aplica a todo el carrito.
+type AplicacionPromocion struct {
+	IdPromocion int
+	Nombre      string
+	IdDetalle   int
+	IdSKU       int
+	Descuento   float64
+	Detalle     string
+	Codigo      string
+	Variante    sql.NullString
+}
+
+func (a AplicacionPromocion) String() string {
+	linea := "todo el carrito"
+	if a.IdDetalle > 0 {
+		linea = fmt.Sprintf("Linea #%d %s", a.IdDetalle, etiquetaSKU(a.IdSKU, a.Codigo, a.Variante))
+	}
+	return fmt.Sprintf("[ %s | %s | %s | -%.2f ]", a.Nombre, linea, a.Detalle, a.Descuento)
+}
+
+// PedidoPromocion es una AplicacionPromocion guardada en un pedido.
+type PedidoPromocion struct {
+	IdAplicacion int
+	IdPedido     int
+	IdPromocion  int
+	Nombre       string
+	IdSKU        sql.NullInt32
+	Descuento    float64
+	Detalle      string
+	// Codigo y variante del SKU si todavia existe (calculados al leer)
+	Codigo   sql.NullString
+	Variante sql.NullString
+}
+
+func (p PedidoPromocion) String() string {
+	linea := "todo el pedido"
+	if p.IdSKU.Valid {
+		linea = etiquetaSKU(int(p.IdSKU.Int32), p.Codigo.String, p.Variante)
+	}
+	return fmt.Sprintf("[ %s | %s | %s | -%.2f ]", p.Nombre, linea, p.Detalle, p.Descuento)
+}
+
+// validarPromocion revisa que la promocion tenga los campos que su tipo usa.
+func validarPromocion(p *Promocion) error {
+	name, err := requireNonEmpty("nombre", p.Nombre)
+	if err != nil {
+		return err
+	}
+	p.Nombre = name
+	tipo := ""
+	for _, t := range tiposPromocion {
+		if strings.EqualFold(strings.TrimSpace(p.Tipo), t) {
+			tipo = t
+		}
+	}
+	if tipo == "" {
+		return fmt.Errorf("tipo de promocion invalido: %q (usa %s)", p.Tipo, strings.Join(tiposPromocion, ", "))
+	}
+	p.Tipo = tipo
+	if err := requirePositive("prioridad", p.Prioridad); err != nil {
+		return err
+	}
+	if p.VigenteDesde.Valid && p.VigenteHasta.Valid && !p.VigenteHasta.Time.After(p.VigenteDesde.Time) {
+		return fmt.Errorf("la fecha final debe ser posterior a la inicial")
+	}
+
+	switch p.Tipo {
+	case PromoCompraXLlevaY:
+		if !p.IdSKU.Valid || !p.CantidadCompra.Valid || !p.CantidadGratis.Valid {
+			return fmt.Errorf("%s necesita SKU, cantidad a comprar y cantidad gratis", p.Tipo)
+		}
+	case PromoPorcentajeCategoria:
+		if !p.IdCategoria.Valid || !p.Porcentaje.Valid {
+			return fmt.Errorf("%s necesita categoria y porcentaje", p.Tipo)
+		}
+	case PromoPrecioFlash:
+		if !p.IdSKU.Valid || !p.PrecioEspecial.Valid {
+			return fmt.Errorf("%s necesita SKU y precio especial", p.Tipo)
+		}
+		if !p.VigenteHasta.Valid {
+			return fmt.Errorf("%s necesita fecha de fin", p.Tipo)
+		}
+	}
+	if p.Porcentaje.Valid && (p.Porcentaje.Float64 <= 0 || p.Porcentaje.Float64 > 100) {
+		return fmt.Errorf("porcentaje debe estar entre 0 y 100")
+	}
+	return nil
+}
+
+// lineaPromocion es una linea del carrito con lo que el motor necesita.
+type lineaPromocion struct {
+	IdDetalle int
+	IdSKU     int
+	// Categoria del producto y sus ancestros, de la mas cercana a la raiz
+	Categorias     []int
+	Cantidad       int
+	PrecioUnitario float64
+	Codigo         string
+	Variante       sql.NullString
+}
+
+// evaluarPromociones aplica las promociones vigentes a las lineas en orden de
+// prioridad (menor numero primero) y devuelve que desconto cada una.
+//
+// Conflictos: una promocion exclusiva (no acumulable) solo entra en lineas que
+// no tengan otra promocion, y una vez aplicada bloquea la linea para las
+// siguientes. Las acumulables se suman sobre lo que queda de la linea. Las
+// escalonadas miran el carrito completo: si son exclusivas solo aplican cuando
+// ninguna otra promocion entro, y entonces cierran el carrito.
+func evaluarPromociones(promos []Promocion, tiers []PromocionEscalon, lines []lineaPromocion, now time.Time) []AplicacionPromocion {
+	active := []Promocion{}
+	for _, p := range promos {
+		if p.vigente(now) {
+			active = append(active, p)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		if active[i].Prioridad != active[j].Prioridad {
+			return active[i].Prioridad < active[j].Prioridad
+		}
+		return active[i].IdPromocion < active[j].IdPromocion
+	})
+	tiersByPromo := map[int][]PromocionEscalon{}
+	for _, t := range tiers {
+		tiersByPromo[t.IdPromocion] = append(tiersByPromo[t.IdPromocion], t)
+	}
+
+	remaining := make([]float64, len(lines))
+	touched := make([]bool, len(lines))
+	locked := make([]bool, len(lines))
+	for i, line := range lines {
+		remaining[i] = line.PrecioUnitario * float64(line.Cantidad)
+	}
+	anyApplied, cartLocked := false, false
+
+	applied := []AplicacionPromocion{}
+	for _, p := range active {
+		if cartLocked {
+			break
+		}
+		if p.Tipo == PromoEscalonada {
+			if !p.Acumulable && anyApplied {
+				continue
+			}
+			total := 0.0
+			for _, r := range remaining {
+				total += r
+			}
+			tier, ok := escalonAplicable(tiersByPromo[p.IdPromocion], total)
+			if !ok {
+				continue
+			}
+			discount := redondear(total * tier.Porcentaje / 100)
+			if discount <= 0 {
+				continue
+			}
+			for i := range remaining {
+				remaining[i] -= remaining[i] * tier.Porcentaje / 100
+				touched[i] = true
+			}
+			applied = append(applied, AplicacionPromocion{
+				IdPromocion: p.IdPromocion,
+				Nombre:      p.Nombre,
+				Descuento:   discount,
+				Detalle:     fmt.Sprintf("carrito de %.2f alcanza el escalon desde %.2f: -%.2f%%", total, tier.MontoMinimo, tier.Porcentaje),
+			})
+			anyApplied = true
+			cartLocked = !p.Acumulable
+			continue
+		}
+
+		for i, line := range lines {
+			if locked[i] || (!p.Acumulable && touched[i]) {
+				continue
+			}
+			discount, detail := descuentoLinea(p, line)
+			discount = redondear(min(discount, remaining[i]))
+			if discount <= 0 {
+				continue
+			}
+			remaining[i] -= discount
+			touched[i] = true
+			locked[i] = !p.Acumulable
+			applied = append(applied, AplicacionPromocion{
+				IdPromocion: p.IdPromocion,
+				Nombre:      p.Nombre,
+				IdDetalle:   line.IdDetalle,
+				IdSKU:       line.IdSKU,
+				Descuento:   discount,
+				Detalle:     detail,
+				Codigo:      line.Codigo,
+				Variante:    line.Variante,
+			})
+			anyApplied = true
+		}
+	}
+	return applied
+}
+
+// descuentoLinea calcula lo que una promocion de linea descuenta, sin tope.
+func descuentoLinea(p Promocion, line lineaPromocion) (float64, string) {
+	switch p.Tipo {
+	case PromoCompraXLlevaY:
+		if int(p.IdSKU.Int32) != line.IdSKU {
+			return 0, ""
+		}
+		buy, free := int(p.CantidadCompra.Int32), int(p.CantidadGratis.Int32)
+		units := (line.Cantidad / (buy + free)) * free
+		return float64(units) * line.PrecioUnitario,
+			fmt.Sprintf("compra %d lleva %d: %d unidad(es) gratis", buy, free, units)
+	case PromoPorcentajeCategoria:
+		if !enCategoria(line.Categorias, int(p.IdCategoria.Int32)) {
+			return 0, ""
+		}
+		return line.PrecioUnitario * float64(line.Cantidad) * p.Porcentaje.Float64 / 100,
+			fmt.Sprintf("-%.2f%% en la categoria", p.Porcentaje.Float64)
+	case PromoPrecioFlash:
+		if int(p.IdSKU.Int32) != line.IdSKU {
+			return 0, ""
+		}
+		return max(line.PrecioUnitario-p.PrecioEspecial.Float64, 0) * float64(line.Cantidad),
+			fmt.Sprintf("precio flash %.2f (antes %.2f) hasta %s", p.PrecioEspecial.Float64, line.PrecioUnitario, fechaCupon(p.VigenteHasta))
+	}
+	return 0, ""
+}
+
+// escalonAplicable devuelve el escalon mas alto que alcanza total.
+func escalonAplicable(tiers []PromocionEscalon, total float64) (PromocionEscalon, bool) {
+	var best PromocionEscalon
+	found := false
+	for _, t := range tiers {
+		if total >= t.MontoMinimo && (!found || t.MontoMinimo > best.MontoMinimo) {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+// totalPromociones suma lo descontado por todas las aplicaciones.
+func totalPromociones(applied []AplicacionPromocion) float64 {
+	total := 0.0
+	for _, a := range applied {
+		total += a.Descuento
+	}
+	return redondear(total)
+}
+
+// lineaPromocionPedido es la forma en que las aplicaciones viajan como JSON a añadir/pedido.sql.
+type lineaPromocionPedido struct {
+	IdPromocion int     `json:"idPromocion"`
+	Nombre      string  `json:"nombre"`
+	IdSKU       *int    `json:"idSKU"`
+	Descuento   float64 `json:"descuento"`
+	Detalle     string  `json:"detalle"`
+}
+
+func lineasPromocion(applied []AplicacionPromocion) []lineaPromocionPedido {
+	lines := make([]lineaPromocionPedido, 0, len(applied))
+	for _, a := range applied {
+		line := lineaPromocionPedido{IdPromocion: a.IdPromocion, Nombre: a.Nombre, Descuento: a.Descuento, Detalle: a.Detalle}
+		if a.IdSKU > 0 {
+			skuId := a.IdSKU
+			line.IdSKU = &skuId
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+type PromocionManager struct {
+	db *sql.DB
+}
+
+func NewPromocionManager(database *sql.DB) *PromocionManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &PromocionManager{db: database}
+}
+
+func (m *PromocionManager) List(ctx context.Context) ([]Promocion, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/promocion.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Promocion](rows)
+}
+
+func (m *PromocionManager) Get(ctx context.Context, id int) (*Promocion, error) {
+	if err := requirePositive("idPromocion", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/promocion_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Promocion](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("promocion %d no encontrada", id)
+	}
+	return &items[0], nil
+}
+
+// Create guarda una promocion nueva; IdPromocion y Activo se ignoran. Los
+// escalones de una Escalonada se cargan despues con SetTiers.
+func (m *PromocionManager) Create(ctx context.Context, promo Promocion) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := validarPromocion(&promo); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "añadir/promocion.sql", promocionParams(promo)...)
+	return err
+}
+
+func (m *PromocionManager) Update(ctx context.Context, promo Promocion) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idPromocion", promo.IdPromocion); err != nil {
+		return err
+	}
+	if err := validarPromocion(&promo); err != nil {
+		return err
+	}
+	params := append(promocionParams(promo),
+		sql.Named("id", promo.IdPromocion),
+		sql.Named("active", promo.Activo),
+	)
+	_, err := db.ExecFromFile(ctx, "editar/promocion.sql", params...)
+	return err
+}
+
+func promocionParams(p Promocion) []any {
+	return []any{
+		sql.Named("name", p.Nombre),
+		sql.Named("type", p.Tipo),
+		sql.Named("priority", p.Prioridad),
+		sql.Named("stackable", p.Acumulable),
+		sql.Named("skuId", p.IdSKU),
+		sql.Named("categoryId", p.IdCategoria),
+		sql.Named("buyQuantity", p.CantidadCompra),
+		sql.Named("freeQuantity", p.CantidadGratis),
+		sql.Named("percentage", p.Porcentaje),
+		sql.Named("specialPrice", p.PrecioEspecial),
+		sql.Named("validFrom", p.VigenteDesde),
+		sql.Named("validUntil", p.VigenteHasta),
+	}
+}
+
+func (m *PromocionManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idPromocion", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/promocion.sql", sql.Named("id", id))
+	return err
+}
+
+// Tiers obtiene los escalones de una promocion.
+func (m *PromocionManager) Tiers(ctx context.Context, id int) ([]PromocionEscalon, error) {
+	if err := requirePositive("idPromocion", id); err != nil {
+		return nil, err
+	}
+	all, err := m.allTiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tiers := []PromocionEscalon{}
+	for _, t := range all {
+		if t.IdPromocion == id {
+			tiers = append(tiers, t)
+		}
+	}
+	return tiers, nil
+}
+
+func (m *PromocionManager) allTiers(ctx context.Context) ([]PromocionEscalon, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/promocion_escalon.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[PromocionEscalon](rows)
+}
+
+// SetTiers reemplaza los escalones de una promocion Escalonada.
+func (m *PromocionManager) SetTiers(ctx context.Context, id int, tiers []PromocionEscalon) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	promo, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if promo.Tipo != PromoEscalonada {
+		return fmt.Errorf("la promocion %d es %s, solo las %s tienen escalones", id, promo.Tipo, PromoEscalonada)
+	}
+	type escalon struct {
+		MontoMinimo float64 `json:"montoMinimo"`
+		Porcentaje  float64 `json:"porcentaje"`
+	}
+	payload := make([]escalon, 0, len(tiers))
+	seen := map[float64]bool{}
+	for _, t := range tiers {
+		if t.MontoMinimo < 0 {
+			return fmt.Errorf("monto minimo no puede ser negativo")
+		}
+		if t.Porcentaje <= 0 || t.Porcentaje > 100 {
+			return fmt.Errorf("porcentaje debe estar entre 0 y 100")
+		}
+		if seen[t.MontoMinimo] {
+			return fmt.Errorf("hay dos escalones desde %.2f", t.MontoMinimo)
+		}
+		seen[t.MontoMinimo] = true
+		payload = append(payload, escalon{MontoMinimo: t.MontoMinimo, Porcentaje: t.Porcentaje})
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/promocion_escalon.sql",
+		sql.Named("id", id),
+		sql.Named("tiers", string(data)),
+	)
+	return err
+}
+
+// Evaluate corre el motor sobre las lineas del carrito y explica que
+// promocion desconto cuanto en cada linea.
+func (m *PromocionManager) Evaluate(ctx context.Context, cartId int) ([]AplicacionPromocion, error) {
+	if err := requirePositive("idCarrito", cartId); err != nil {
+		return nil, err
+	}
+	details, err := NewCarritoDetalleManager(m.db).ListByCarrito(ctx, cartId)
+	if err != nil {
+		return nil, err
+	}
+	skus := NewSKUManager(m.db)
+	lines := make([]lineaPromocion, 0, len(details))
+	for _, d := range details {
+		// Un SKU borrado no se compra, no participa
+		if !d.IdSKU.Valid {
+			continue
+		}
+		sku, err := skus.Get(ctx, int(d.IdSKU.Int32))
+		if err != nil {
+			return nil, err
+		}
+		categories, err := skus.categorias(ctx, sku.IdSKU)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, lineaPromocion{
+			IdDetalle:      d.IdDetalle,
+			IdSKU:          sku.IdSKU,
+			Categorias:     categories,
+			Cantidad:       d.Cantidad,
+			PrecioUnitario: sku.Precio,
+			Codigo:         sku.Codigo,
+			Variante:       sku.Variante,
+		})
+	}
+	promos, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tiers, err := m.allTiers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return evaluarPromociones(promos, tiers, lines, time.Now()), nil
+}
@@ -4,23 +4,44 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"tienda-online/internal"
 	"tienda-online/internal/db"
 	sqlutil "tienda-online/internal/sql"
 )
 
+// EstadoResena es el estado de moderacion de una reseña (columna Reseña.estado).
+type EstadoResena string
+
+const (
+	ResenaPendiente EstadoResena = "Pendiente"
+	ResenaAprobada  EstadoResena = "Aprobada"
+	ResenaRechazada EstadoResena = "Rechazada"
+)
+
 type Resena struct {
-	IdReseña   int
-	IdUsuario  int
-	IdProducto int
-	Puntuación int
-	Comentario sql.NullString
+	IdReseña      int
+	IdUsuario     int
+	IdProducto    int
+	Puntuación    int
+	Comentario    sql.NullString
+	FechaCreacion time.Time
+	// Solo las aprobadas son publicas
+	Estado          EstadoResena
+	MotivoRechazo   sql.NullString
+	Moderador       sql.NullString
+	FechaModeracion sql.NullTime
 }
 
 func (r Resena) String() string {
-	return fmt.Sprintf("[ Reseña #%d | UsuarioID:%d | ProductoID:%d | %d/5 | %s ]",
-		r.IdReseña, r.IdUsuario, r.IdProducto, r.Puntuación, internal.NullString(r.Comentario))
+	estado := string(r.Estado)
+	if r.Estado == ResenaRechazada {
+		estado += ": " + internal.NullString(r.MotivoRechazo)
+	}
+	return fmt.Sprintf("[ Reseña #%d | UsuarioID:%d | ProductoID:%d | %d/5 | %s | %s | %s ]",
+		r.IdReseña, r.IdUsuario, r.IdProducto, r.Puntuación, internal.NullString(r.Comentario),
+		r.FechaCreacion.Local().Format("2006-01-02"), estado)
 }
 
 type ResenaManager struct {
@@ -34,6 +55,7 @@ func NewResenaManager(database *sql.DB) *ResenaManager {
 	return &ResenaManager{db: database}
 }
 
+// List obtiene todas las reseñas sin importar su estado, para administracion.
 func (m *ResenaManager) List(ctx context.Context) ([]Resena, error) {
 	rows, err := db.QueryRowsFromFile(ctx, "leer/resena.sql")
 	if err != nil {
@@ -62,6 +84,31 @@ func (m *ResenaManager) Get(ctx context.Context, id int) (*Resena, error) {
 	return &items[0], nil
 }
 
+// ListByProducto obtiene las reseñas publicas (aprobadas) de un producto.
+func (m *ResenaManager) ListByProducto(ctx context.Context, productId int) ([]Resena, error) {
+	if err := requirePositive("idProducto", productId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/resena_por_producto.sql", sql.Named("productId", productId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Resena](rows)
+}
+
+// ListPending obtiene la cola de moderacion, las mas antiguas primero.
+func (m *ResenaManager) ListPending(ctx context.Context) ([]Resena, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/resena_pendiente.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Resena](rows)
+}
+
+// Create registra la reseña pendiente de moderacion. Un cliente puede reseñar
+// cada producto una sola vez.
 func (m *ResenaManager) Create(ctx context.Context, userId, productId, rating int, comment string) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -84,6 +131,7 @@ func (m *ResenaManager) Create(ctx context.Context, userId, productId, rating in
 	return err
 }
 
+// Update cambia la reseña y la devuelve a la cola de moderacion.
 func (m *ResenaManager) Update(ctx context.Context, id, userId, productId, rating int, comment string) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -110,6 +158,50 @@ func (m *ResenaManager) Update(ctx context.Context, id, userId, productId, ratin
 	return err
 }
 
+// Approve publica la reseña.
+func (m *ResenaManager) Approve(ctx context.Context, id int, moderator string) error {
+	return m.moderate(ctx, id, ResenaAprobada, "", moderator)
+}
+
+// Reject oculta la reseña; el motivo es obligatorio para poder explicarle al cliente.
+func (m *ResenaManager) Reject(ctx context.Context, id int, reason, moderator string) error {
+	reason, err := requireNonEmpty("motivo", reason)
+	if err != nil {
+		return err
+	}
+	if len(reason) > 200 {
+		return fmt.Errorf("motivo no puede tener mas de 200 caracteres")
+	}
+	return m.moderate(ctx, id, ResenaRechazada, reason, moderator)
+}
+
+// moderate fija el estado; una reseña ya moderada se puede volver a moderar,
+// por ejemplo para bajar una aprobada que recibio denuncias.
+func (m *ResenaManager) moderate(ctx context.Context, id int, status EstadoResena, reason, moderator string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	moderator, err := requireNonEmpty("moderador", moderator)
+	if err != nil {
+		return err
+	}
+	current, err := m.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Rechazar de nuevo sirve para corregir el motivo
+	if current.Estado == status && status == ResenaAprobada {
+		return fmt.Errorf("la reseña %d ya esta aprobada", id)
+	}
+	_, err = db.ExecFromFile(ctx, "editar/resena_estado.sql",
+		sql.Named("id", id),
+		sql.Named("status", string(status)),
+		sql.Named("reason", optionalString(reason)),
+		sql.Named("moderator", moderator),
+	)
+	return err
+}
+
 func (m *ResenaManager) Delete(ctx context.Context, id int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -4,16 +4,65 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
 
 	"tienda-online/internal/db"
 	sqlutil "tienda-online/internal/sql"
 )
 
 type SKU struct {
-	IdSKU      int
-	IdProducto int
-	Precio     float64
-	Stock      int
+	IdSKU           int
+	IdProducto      int
+	Precio          float64
+	Stock           int
+	PuntoReorden    int
+	CantidadReorden int
+	// Peso de una unidad en kg
+	Peso float64
+	// Codigo del comercio, unico y en mayusculas
+	Codigo       string
+	CodigoBarras sql.NullString
+	// Valores de sus atributos, ej. "Talla: S, Color: Azul" (calculado al leer)
+	Variante sql.NullString
+}
+
+// Etiqueta identifica al SKU por su codigo y su variante.
+func (s SKU) Etiqueta() string {
+	return etiquetaVariante(s.Codigo, s.Variante)
+}
+
+// etiquetaSKU muestra el ID del SKU con el codigo y la variante leidos junto
+// con la fila; sin codigo (SKU borrado) queda solo el ID.
+func etiquetaSKU(id int, codigo string, variante sql.NullString) string {
+	if codigo == "" {
+		return fmt.Sprintf("SKU:%d", id)
+	}
+	return fmt.Sprintf("SKU:%d %s", id, etiquetaVariante(codigo, variante))
+}
+
+func etiquetaVariante(codigo string, variante sql.NullString) string {
+	if variante.Valid && variante.String != "" {
+		return fmt.Sprintf("%s (%s)", codigo, variante.String)
+	}
+	return codigo
+}
+
+// StockBajo es un SKU en o por debajo de su punto de reorden.
+type StockBajo struct {
+	IdSKU           int
+	IdProducto      int
+	Descripcion     string
+	Stock           int
+	PuntoReorden    int
+	CantidadReorden int
+	Codigo          string
+	Variante        sql.NullString
+}
+
+func (s StockBajo) String() string {
+	return fmt.Sprintf("[ SKU #%d %s | %s | Stock: %d | Reorden en: %d | Pedir: %d ]",
+		s.IdSKU, etiquetaVariante(s.Codigo, s.Variante), s.Descripcion, s.Stock, s.PuntoReorden, s.CantidadReorden)
 }
 
 type SKUManager struct {
@@ -55,28 +104,86 @@ func (m *SKUManager) Get(ctx context.Context, id int) (*SKU, error) {
 	return &items[0], nil
 }
 
-func (m *SKUManager) Create(ctx context.Context, productId int, price float64, stock int) error {
+// GetByCode busca un SKU por su codigo del comercio, sin importar mayusculas.
+func (m *SKUManager) GetByCode(ctx context.Context, code string) (*SKU, error) {
+	code, err := normalizarCodigoSKU(code)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_por_codigo.sql", sql.Named("code", code))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[SKU](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("SKU con codigo %q no encontrado", code)
+	}
+	return &items[0], nil
+}
+
+// GetByBarcode busca un SKU por su codigo de barras.
+func (m *SKUManager) GetByBarcode(ctx context.Context, barcode string) (*SKU, error) {
+	barcode, err := validarCodigoBarras(barcode)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_por_codigo_barras.sql", sql.Named("barcode", barcode))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[SKU](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("SKU con codigo de barras %s no encontrado", barcode)
+	}
+	return &items[0], nil
+}
+
+// Create da de alta el SKU; el stock inicial entra al almacen activo de mayor
+// prioridad. barcode vacio deja el SKU sin codigo de barras.
+func (m *SKUManager) Create(ctx context.Context, productId int, code string, price float64, stock int, barcode string) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
 	}
 	if err := requirePositive("idProducto", productId); err != nil {
 		return err
 	}
+	code, err := normalizarCodigoSKU(code)
+	if err != nil {
+		return err
+	}
 	if price < 0 {
 		return fmt.Errorf("precio no puede ser negativo")
 	}
 	if stock < 0 {
 		return fmt.Errorf("stock no puede ser negativo")
 	}
-	_, err := db.ExecFromFile(ctx, "añadir/sku.sql",
+	barcodeParam, err := codigoBarrasOpcional(barcode)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "añadir/sku.sql",
 		sql.Named("productId", productId),
+		sql.Named("code", code),
 		sql.Named("price", price),
 		sql.Named("stock", stock),
+		sql.Named("barcode", barcodeParam),
 	)
 	return err
 }
 
-func (m *SKUManager) Update(ctx context.Context, id, productId int, price float64, stock int) error {
+// Update cambia producto, codigos y precio; el stock se ajusta por almacen
+// (ver AlmacenManager). Un precio distinto rige desde ya y queda en el
+// historial (ver SchedulePrice para cambios a futuro). Si cambia el producto
+// se pierden los valores de los atributos del producto anterior.
+func (m *SKUManager) Update(ctx context.Context, id, productId int, code string, price float64, barcode string) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
 	}
@@ -86,21 +193,160 @@ func (m *SKUManager) Update(ctx context.Context, id, productId int, price float6
 	if err := requirePositive("idProducto", productId); err != nil {
 		return err
 	}
+	code, err := normalizarCodigoSKU(code)
+	if err != nil {
+		return err
+	}
 	if price < 0 {
 		return fmt.Errorf("precio no puede ser negativo")
 	}
-	if stock < 0 {
-		return fmt.Errorf("stock no puede ser negativo")
+	barcodeParam, err := codigoBarrasOpcional(barcode)
+	if err != nil {
+		return err
 	}
-	_, err := db.ExecFromFile(ctx, "editar/sku.sql",
+	_, err = db.ExecFromFile(ctx, "editar/sku.sql",
 		sql.Named("id", id),
 		sql.Named("productId", productId),
+		sql.Named("code", code),
 		sql.Named("price", price),
-		sql.Named("stock", stock),
+		sql.Named("barcode", barcodeParam),
 	)
 	return err
 }
 
+// normalizarCodigoSKU quita espacios a los lados y pasa el codigo a mayusculas.
+func normalizarCodigoSKU(code string) (string, error) {
+	code, err := requireNonEmpty("codigo", strings.ToUpper(code))
+	if err != nil {
+		return "", err
+	}
+	if len(code) > 40 {
+		return "", fmt.Errorf("codigo no puede tener mas de 40 caracteres")
+	}
+	if strings.ContainsAny(code, " \t") {
+		return "", fmt.Errorf("codigo no puede tener espacios")
+	}
+	return code, nil
+}
+
+// validarCodigoBarras acepta EAN-8, UPC-A (12 digitos) y EAN-13 con su digito
+// verificador correcto.
+func validarCodigoBarras(barcode string) (string, error) {
+	barcode = strings.TrimSpace(barcode)
+	switch len(barcode) {
+	case 8, 12, 13:
+	default:
+		return "", fmt.Errorf("codigo de barras %q debe tener 8, 12 o 13 digitos", barcode)
+	}
+	sum := 0
+	for i := len(barcode) - 2; i >= 0; i-- {
+		c := barcode[i]
+		if c < '0' || c > '9' {
+			return "", fmt.Errorf("codigo de barras %q solo puede tener digitos", barcode)
+		}
+		// De derecha a izquierda, sin contar el verificador, los pesos son 3, 1, 3, ...
+		weight := 1
+		if (len(barcode)-2-i)%2 == 0 {
+			weight = 3
+		}
+		sum += int(c-'0') * weight
+	}
+	check := barcode[len(barcode)-1]
+	if check < '0' || check > '9' {
+		return "", fmt.Errorf("codigo de barras %q solo puede tener digitos", barcode)
+	}
+	if want := (10 - sum%10) % 10; int(check-'0') != want {
+		return "", fmt.Errorf("codigo de barras %q tiene digito verificador invalido (se esperaba %d)", barcode, want)
+	}
+	return barcode, nil
+}
+
+// codigoBarrasOpcional valida el codigo de barras o lo deja en NULL si viene vacio.
+func codigoBarrasOpcional(barcode string) (sql.NullString, error) {
+	if strings.TrimSpace(barcode) == "" {
+		return sql.NullString{}, nil
+	}
+	barcode, err := validarCodigoBarras(barcode)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: barcode, Valid: true}, nil
+}
+
+// SetReorder fija el punto de reorden (0 desactiva la alerta) y la cantidad a reabastecer.
+func (m *SKUManager) SetReorder(ctx context.Context, id, reorderPoint, reorderQuantity int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idSKU", id); err != nil {
+		return err
+	}
+	if reorderPoint < 0 {
+		return fmt.Errorf("punto de reorden no puede ser negativo")
+	}
+	if reorderQuantity < 0 {
+		return fmt.Errorf("cantidad de reorden no puede ser negativa")
+	}
+	_, err := db.ExecFromFile(ctx, "editar/sku_reorden.sql",
+		sql.Named("id", id),
+		sql.Named("reorderPoint", reorderPoint),
+		sql.Named("reorderQuantity", reorderQuantity),
+	)
+	return err
+}
+
+// SetWeight fija el peso en kg de una unidad del SKU, usado para el costo de envio.
+func (m *SKUManager) SetWeight(ctx context.Context, id int, kg float64) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idSKU", id); err != nil {
+		return err
+	}
+	if kg < 0 {
+		return fmt.Errorf("peso no puede ser negativo")
+	}
+	_, err := db.ExecFromFile(ctx, "editar/sku_peso.sql",
+		sql.Named("id", id),
+		sql.Named("weight", kg),
+	)
+	return err
+}
+
+// ListLowStock obtiene los SKUs en o por debajo de su punto de reorden.
+func (m *SKUManager) ListLowStock(ctx context.Context) ([]StockBajo, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_stock_bajo.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[StockBajo](rows)
+}
+
+// categorias devuelve la categoria del producto del SKU seguida de sus
+// ancestros hasta la raiz; vacio si el producto no tiene categoria.
+func (m *SKUManager) categorias(ctx context.Context, skuId int) ([]int, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_categoria.sql", sql.Named("skuId", skuId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[struct{ IdCategoria int }](rows)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.IdCategoria)
+	}
+	return ids, nil
+}
+
+// enCategoria indica si categoryId es la categoria de la linea o uno de sus ancestros.
+func enCategoria(categories []int, categoryId int) bool {
+	return slices.Contains(categories, categoryId)
+}
+
 func (m *SKUManager) Delete(ctx context.Context, id int) error {
 	if err := ensureDB(m.db); err != nil {
 		return err
@@ -113,5 +359,10 @@ func (m *SKUManager) Delete(ctx context.Context, id int) error {
 }
 
 func (s SKU) String() string {
-	return fmt.Sprintf("[ SKU #%d | ProductoID:%d | Precio: %.2f | Stock: %d ]", s.IdSKU, s.IdProducto, s.Precio, s.Stock)
+	barcode := ""
+	if s.CodigoBarras.Valid {
+		barcode = " | " + s.CodigoBarras.String
+	}
+	return fmt.Sprintf("[ SKU #%d %s%s | ProductoID:%d | Precio: %.2f | Stock: %d | Peso: %.3f kg ]",
+		s.IdSKU, s.Etiqueta(), barcode, s.IdProducto, s.Precio, s.Stock, s.Peso)
 }
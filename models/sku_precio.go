@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// SKUPrecio es un periodo del historial de precios de un SKU. Las fechas estan
+// en UTC; VigenteHasta sin valor es el ultimo periodo, abierto.
+type SKUPrecio struct {
+	IdPrecio      int
+	IdSKU         int
+	Precio        float64
+	VigenteDesde  time.Time
+	VigenteHasta  sql.NullTime
+	FechaCreacion time.Time
+	Codigo        string
+	Variante      sql.NullString
+}
+
+// programado indica si el periodo todavia no empezo.
+func (p SKUPrecio) programado(now time.Time) bool {
+	return p.VigenteDesde.After(now)
+}
+
+func (p SKUPrecio) String() string {
+	hasta := "..."
+	if p.VigenteHasta.Valid {
+		hasta = p.VigenteHasta.Time.Local().Format("2006-01-02 15:04")
+	}
+	estado := ""
+	if p.programado(time.Now()) {
+		estado = " | Programado"
+	}
+	return fmt.Sprintf("[ Precio #%d | %s | %.2f | %s - %s%s ]",
+		p.IdPrecio, etiquetaSKU(p.IdSKU, p.Codigo, p.Variante), p.Precio, p.VigenteDesde.Local().Format("2006-01-02 15:04"), hasta, estado)
+}
+
+// PriceHistory obtiene todos los periodos de precio de un SKU, los mas nuevos
+// (incluidos los programados) primero.
+func (m *SKUManager) PriceHistory(ctx context.Context, skuId int) ([]SKUPrecio, error) {
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_precio_por_sku.sql", sql.Named("skuId", skuId))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[SKUPrecio](rows)
+}
+
+// PriceAt obtiene el precio que regia para el SKU en el instante at, para
+// reportes y reclamos.
+func (m *SKUManager) PriceAt(ctx context.Context, skuId int, at time.Time) (*SKUPrecio, error) {
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/sku_precio_vigente.sql",
+		sql.Named("skuId", skuId),
+		sql.Named("at", at.UTC()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[SKUPrecio](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("el SKU %d no tenia precio registrado el %s", skuId, at.Local().Format("2006-01-02 15:04"))
+	}
+	return &items[0], nil
+}
+
+// SchedulePrice programa un precio para el SKU a partir de from; from en cero
+// lo aplica ya. El precio rige hasta el siguiente cambio programado y
+// ApplyScheduledPrices lo pasa al SKU cuando llega su fecha. Devuelve el ID
+// del periodo.
+func (m *SKUManager) SchedulePrice(ctx context.Context, skuId int, price float64, from time.Time) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	if err := requirePositive("idSKU", skuId); err != nil {
+		return 0, err
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("precio debe ser mayor a 0")
+	}
+	start := sql.NullTime{}
+	if !from.IsZero() {
+		if !from.After(time.Now()) {
+			return 0, fmt.Errorf("la fecha del cambio tiene que ser futura")
+		}
+		start = sql.NullTime{Time: from.UTC(), Valid: true}
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "añadir/sku_precio.sql",
+		sql.Named("skuId", skuId),
+		sql.Named("price", redondear(price)),
+		sql.Named("from", start),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	created, err := sqlutil.ParseRow[struct{ IdPrecio int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(created) == 0 {
+		return 0, fmt.Errorf("no se obtuvo el ID del precio programado")
+	}
+	return created[0].IdPrecio, nil
+}
+
+// CancelScheduledPrice quita un cambio de precio que todavia no rige; el
+// periodo anterior sigue hasta donde terminaba el cancelado.
+func (m *SKUManager) CancelScheduledPrice(ctx context.Context, priceId int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idPrecio", priceId); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/sku_precio.sql", sql.Named("id", priceId))
+	return err
+}
+
+// ApplyScheduledPrices actualiza el precio de los SKUs cuyo cambio programado
+// ya entro en vigencia y devuelve cuantos cambiaron. Se llama periodicamente.
+func (m *SKUManager) ApplyScheduledPrices(ctx context.Context) (int, error) {
+	if err := ensureDB(m.db); err != nil {
+		return 0, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "editar/sku_precio_aplicar.sql")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	result, err := sqlutil.ParseRow[struct{ Actualizados int }](rows)
+	if err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return result[0].Actualizados, nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidarCodigoBarras(t *testing.T) {
+	tests := []struct {
+		name    string
+		barcode string
+		want    string
+		wantErr string
+	}{
+		{"EAN-13", "4006381333931", "4006381333931", ""},
+		{"UPC-A", "036000291452", "036000291452", ""},
+		{"EAN-8", "96385074", "96385074", ""},
+		{"verificador cero", "0000000000000", "0000000000000", ""},
+		{"recorta espacios", " 4006381333931\t", "4006381333931", ""},
+
+		{"EAN-13 verificador invalido", "4006381333932", "", "se esperaba 1"},
+		{"UPC-A verificador invalido", "036000291450", "", "se esperaba 2"},
+		{"EAN-8 verificador invalido", "96385070", "", "se esperaba 4"},
+		{"digitos cambiados", "4060381333931", "", "digito verificador invalido"},
+
+		{"vacio", "", "", "8, 12 o 13 digitos"},
+		{"EAN-13 sin un digito", "400638133393", "", "digito verificador invalido"},
+		{"11 digitos", "03600029145", "", "8, 12 o 13 digitos"},
+		{"14 digitos", "40063813339310", "", "8, 12 o 13 digitos"},
+		{"letra en el cuerpo", "40063813A3931", "", "solo puede tener digitos"},
+		{"letra en el verificador", "400638133393X", "", "solo puede tener digitos"},
+		{"espacio interno", "4006381 33931", "", "solo puede tener digitos"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validarCodigoBarras(tt.barcode)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("validarCodigoBarras(%q) = %q, want %q", tt.barcode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodigoBarrasOpcional(t *testing.T) {
+	tests := []struct {
+		barcode   string
+		wantValid bool
+		wantErr   bool
+	}{
+		{"", false, false},
+		{"   ", false, false},
+		{"96385074", true, false},
+		{"96385070", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.barcode, func(t *testing.T) {
+			got, err := codigoBarrasOpcional(tt.barcode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Valid != tt.wantValid {
+				t.Errorf("codigoBarrasOpcional(%q) = %+v, want Valid=%v", tt.barcode, got, tt.wantValid)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"tienda-online/internal/db"
+	sqlutil "tienda-online/internal/sql"
+)
+
+// ShipmentRequest es lo que se envia al transportista para generar una guia.
+type ShipmentRequest struct {
+	IdPedido int
+	Metodo   string
+	PesoKg   float64
+	// Direccion de entrega tal como la escribio el cliente
+	Destino string
+}
+
+// Carrier es el contrato con un transportista: genera guias y consulta su
+// estado. Transportista.codigo dice que implementacion usar.
+type Carrier interface {
+	Code() string
+	CreateShipment(ctx context.Context, req ShipmentRequest) (trackingNumber string, err error)
+	Track(ctx context.Context, trackingNumber string) (status string, err error)
+}
+
+var (
+	carriersMu sync.RWMutex
+	carriers   = map[string]Carrier{}
+)
+
+// RegisterCarrier agrega o reemplaza la implementacion para carrier.Code().
+func RegisterCarrier(carrier Carrier) {
+	carriersMu.Lock()
+	defer carriersMu.Unlock()
+	carriers[carrier.Code()] = carrier
+}
+
+func carrierByCode(code string) (Carrier, error) {
+	carriersMu.RLock()
+	defer carriersMu.RUnlock()
+	carrier, ok := carriers[code]
+	if !ok {
+		return nil, fmt.Errorf("no hay transportista registrado con codigo %q", code)
+	}
+	return carrier, nil
+}
+
+// FakeCarrier es un transportista en memoria para desarrollo: las guias salen
+// de un contador y siempre figuran en transito.
+type FakeCarrier struct {
+	mu  sync.Mutex
+	seq int
+}
+
+func NewFakeCarrier() *FakeCarrier {
+	return &FakeCarrier{}
+}
+
+func (c *FakeCarrier) Code() string {
+	return "fake"
+}
+
+func (c *FakeCarrier) CreateShipment(ctx context.Context, req ShipmentRequest) (string, error) {
+	if req.PesoKg < 0 {
+		return "", fmt.Errorf("peso no puede ser negativo")
+	}
+	if strings.TrimSpace(req.Destino) == "" {
+		return "", fmt.Errorf("el envio del pedido %d no tiene destino", req.IdPedido)
+	}
+	c.mu.Lock()
+	c.seq++
+	tracking := fmt.Sprintf("FAKE%d%06d", req.IdPedido, c.seq)
+	c.mu.Unlock()
+	return tracking, nil
+}
+
+func (c *FakeCarrier) Track(ctx context.Context, trackingNumber string) (string, error) {
+	if !strings.HasPrefix(trackingNumber, "FAKE") {
+		return "", fmt.Errorf("guia %q no pertenece al transportista fake", trackingNumber)
+	}
+	return "En transito", nil
+}
+
+type Transportista struct {
+	IdTransportista int
+	Nombre          string
+	Codigo          string
+	Activo          bool
+}
+
+func (t Transportista) String() string {
+	estado := "Activo"
+	if !t.Activo {
+		estado = "Inactivo"
+	}
+	return fmt.Sprintf("[ Transportista #%d | %s | Codigo: %s | %s ]", t.IdTransportista, t.Nombre, t.Codigo, estado)
+}
+
+type TransportistaManager struct {
+	db *sql.DB
+}
+
+func NewTransportistaManager(database *sql.DB) *TransportistaManager {
+	if database == nil {
+		database = db.CurrentDatabase
+	}
+	return &TransportistaManager{db: database}
+}
+
+func (m *TransportistaManager) List(ctx context.Context) ([]Transportista, error) {
+	rows, err := db.QueryRowsFromFile(ctx, "leer/transportista.sql")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return sqlutil.ParseRow[Transportista](rows)
+}
+
+func (m *TransportistaManager) Get(ctx context.Context, id int) (*Transportista, error) {
+	if err := requirePositive("idTransportista", id); err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryRowsFromFile(ctx, "leer/transportista_por_id.sql", sql.Named("id", id))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items, err := sqlutil.ParseRow[Transportista](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("transportista %d no encontrado", id)
+	}
+	return &items[0], nil
+}
+
+// Create agrega un transportista; code debe coincidir con un Carrier registrado.
+func (m *TransportistaManager) Create(ctx context.Context, name, code string) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	code, err = requireNonEmpty("codigo", code)
+	if err != nil {
+		return err
+	}
+	if _, err := carrierByCode(code); err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "añadir/transportista.sql",
+		sql.Named("name", name),
+		sql.Named("code", code),
+	)
+	return err
+}
+
+func (m *TransportistaManager) Update(ctx context.Context, id int, name, code string, active bool) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idTransportista", id); err != nil {
+		return err
+	}
+	name, err := requireNonEmpty("nombre", name)
+	if err != nil {
+		return err
+	}
+	code, err = requireNonEmpty("codigo", code)
+	if err != nil {
+		return err
+	}
+	if _, err := carrierByCode(code); err != nil {
+		return err
+	}
+	_, err = db.ExecFromFile(ctx, "editar/transportista.sql",
+		sql.Named("id", id),
+		sql.Named("name", name),
+		sql.Named("code", code),
+		sql.Named("active", active),
+	)
+	return err
+}
+
+// Delete borra un transportista; falla si todavia tiene metodos de envio.
+func (m *TransportistaManager) Delete(ctx context.Context, id int) error {
+	if err := ensureDB(m.db); err != nil {
+		return err
+	}
+	if err := requirePositive("idTransportista", id); err != nil {
+		return err
+	}
+	_, err := db.ExecFromFile(ctx, "remover/transportista.sql", sql.Named("id", id))
+	return err
+}
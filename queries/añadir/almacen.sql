@@ -0,0 +1,3 @@
+-- Nuevo almacen (prioridad menor = se despacha primero)
+INSERT INTO Almacen (nombre, prioridad)
+VALUES (@name, @priority);
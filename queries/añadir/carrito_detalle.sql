@@ -1,3 +1,28 @@
--- Agregar item al carrito del cliente
-INSERT INTO CarritoDetalle (idCarrito, idSKU, cantidad)
-VALUES (@cartId, @skuId, @quantity);
+-- Agregar item al carrito; si el carrito ya tiene el SKU se suma la cantidad
+-- a esa linea, hasta @maxQuantity
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @current INT = ISNULL((
+    SELECT cantidad FROM CarritoDetalle WITH (UPDLOCK, HOLDLOCK)
+    WHERE idCarrito = @cartId AND idSKU = @skuId), 0);
+IF @current + @quantity > @maxQuantity
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50121, 'la cantidad supera el maximo por linea', 1;
+END;
+
+MERGE CarritoDetalle WITH (HOLDLOCK) AS destino
+USING (SELECT @cartId AS idCarrito, @skuId AS idSKU) AS origen
+ON destino.idCarrito = origen.idCarrito AND destino.idSKU = origen.idSKU
+WHEN MATCHED THEN
+    UPDATE SET cantidad = destino.cantidad + @quantity, fechaActualizacion = SYSUTCDATETIME()
+WHEN NOT MATCHED THEN
+    INSERT (idCarrito, idSKU, cantidad) VALUES (origen.idCarrito, origen.idSKU, @quantity);
+
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = @cartId;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,3 @@
+-- Crear un carrito de invitado identificado por un token opaco
+INSERT INTO Carrito (token)
+VALUES (@token);
@@ -0,0 +1,51 @@
+-- Agregar un SKU al carrito del usuario (se crea si no tiene) o al del
+-- invitado con @token. Si el carrito ya tiene el SKU se suma la cantidad a esa
+-- linea, hasta @maxQuantity.
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM SKU WHERE idSKU = @skuId)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50120, 'el SKU no existe', 1;
+END;
+
+DECLARE @cartId INT = (
+    SELECT idCarrito FROM Carrito WITH (UPDLOCK, HOLDLOCK)
+    WHERE idUsuario = @userId OR token = @token);
+IF @cartId IS NULL AND @userId IS NULL
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50122, 'el carrito de invitado no existe o vencio', 1;
+END;
+IF @cartId IS NULL
+BEGIN
+    INSERT INTO Carrito (idUsuario) VALUES (@userId);
+    SET @cartId = SCOPE_IDENTITY();
+END;
+
+DECLARE @current INT = ISNULL((
+    SELECT cantidad FROM CarritoDetalle WITH (UPDLOCK, HOLDLOCK)
+    WHERE idCarrito = @cartId AND idSKU = @skuId), 0);
+IF @current + @quantity > @maxQuantity
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50121, 'la cantidad supera el maximo por linea', 1;
+END;
+
+MERGE CarritoDetalle WITH (HOLDLOCK) AS destino
+USING (SELECT @cartId AS idCarrito, @skuId AS idSKU) AS origen
+ON destino.idCarrito = origen.idCarrito AND destino.idSKU = origen.idSKU
+WHEN MATCHED THEN
+    UPDATE SET cantidad = destino.cantidad + @quantity, fechaActualizacion = SYSUTCDATETIME()
+WHEN NOT MATCHED THEN
+    INSERT (idCarrito, idSKU, cantidad) VALUES (origen.idCarrito, origen.idSKU, @quantity);
+
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = @cartId;
+
+COMMIT TRANSACTION;
+
+SELECT cantidad FROM CarritoDetalle WHERE idCarrito = @cartId AND idSKU = @skuId;
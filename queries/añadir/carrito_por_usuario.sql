@@ -0,0 +1,10 @@
+-- Obtener el carrito del usuario, creandolo si no tiene (uno por cliente)
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM Carrito WITH (UPDLOCK, HOLDLOCK) WHERE idUsuario = @userId)
+    INSERT INTO Carrito (idUsuario) VALUES (@userId);
+
+COMMIT TRANSACTION;
+
+SELECT * FROM Carrito WHERE idUsuario = @userId;
@@ -0,0 +1,20 @@
+-- Reservar el recordatorio de un carrito abandonado antes de enviarlo. Si otra
+-- revision ya lo reservo para el mismo periodo de inactividad no se inserta y
+-- devuelve NULL.
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @newReminderId INT;
+INSERT INTO CarritoRecordatorio (idCarrito, fechaCarrito, importe)
+SELECT @cartId, @cartUpdatedAt, @amount
+WHERE NOT EXISTS (
+    SELECT 1 FROM CarritoRecordatorio WITH (UPDLOCK, HOLDLOCK)
+    WHERE idCarrito = @cartId AND fechaCarrito = @cartUpdatedAt
+);
+IF @@ROWCOUNT > 0
+    SET @newReminderId = SCOPE_IDENTITY();
+
+COMMIT TRANSACTION;
+
+SELECT @newReminderId AS idRecordatorio;
@@ -1,3 +1,3 @@
--- Nueva categoria
-INSERT INTO Categoria (nombre)
-VALUES (@name);
+-- Nueva categoria (@parentId NULL = raiz)
+INSERT INTO Categoria (nombre, idPadre)
+VALUES (@name, @parentId);
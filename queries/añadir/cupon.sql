@@ -0,0 +1,5 @@
+-- Nuevo cupon
+INSERT INTO Cupon (codigo, tipo, valor, montoMinimo, usosMaximos, usosPorCliente,
+    vigenteDesde, vigenteHasta, idCategoria)
+VALUES (@code, @type, @value, @minimum, @maxUses, @maxUsesPerCustomer,
+    @validFrom, @validUntil, @categoryId);
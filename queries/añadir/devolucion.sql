@@ -1,3 +1,51 @@
--- Registrar devolucion ligada a un pedido
-INSERT INTO Devolucion (idPedido, fecha, estado, descripcion, resolucion)
-VALUES (@orderId, @date, @status, @description, @resolution);
+-- Registrar solicitud de devolucion con sus lineas (la ventana se valida en Go).
+-- Las lineas del pedido quedan bloqueadas hasta el final para que dos devoluciones
+-- simultaneas no superen entre las dos lo comprado.
+-- @lines es un arreglo JSON: [{"idPedidoDetalle":1,"cantidad":2,"montoReembolso":39.98}, ...]
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @purchased TABLE (idDetalle INT PRIMARY KEY, cantidad INT NOT NULL);
+INSERT INTO @purchased (idDetalle, cantidad)
+SELECT idDetalle, cantidad
+FROM PedidoDetalle WITH (UPDLOCK, HOLDLOCK)
+WHERE idPedido = @orderId;
+
+DECLARE @newReturnId INT;
+INSERT INTO Devolucion (idPedido, fecha, estado, descripcion)
+VALUES (@orderId, @date, 'Solicitada', @description);
+SET @newReturnId = SCOPE_IDENTITY();
+
+INSERT INTO DevolucionDetalle (idDevolucion, idPedidoDetalle, cantidad, montoReembolso)
+SELECT @newReturnId, l.idPedidoDetalle, l.cantidad, l.montoReembolso
+FROM OPENJSON(@lines)
+WITH (
+    idPedidoDetalle INT '$.idPedidoDetalle',
+    cantidad INT '$.cantidad',
+    montoReembolso DECIMAL(10,2) '$.montoReembolso'
+) l;
+
+IF EXISTS (
+    SELECT 1
+    FROM DevolucionDetalle dd
+    LEFT JOIN @purchased p ON p.idDetalle = dd.idPedidoDetalle
+    WHERE dd.idDevolucion = @newReturnId AND p.idDetalle IS NULL)
+    OR EXISTS (
+    SELECT 1
+    FROM @purchased p
+    INNER JOIN DevolucionDetalle dd ON dd.idPedidoDetalle = p.idDetalle
+    INNER JOIN Devolucion d ON d.idDevolucion = dd.idDevolucion AND d.estado <> 'Rechazada'
+    GROUP BY p.idDetalle, p.cantidad
+    HAVING SUM(dd.cantidad) > p.cantidad)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50022, 'la cantidad devuelta supera lo comprado', 1;
+END;
+
+INSERT INTO DevolucionHistorial (idDevolucion, estadoAnterior, estadoNuevo, actor, nota)
+VALUES (@newReturnId, NULL, 'Solicitada', @actor, @description);
+
+COMMIT TRANSACTION;
+
+SELECT @newReturnId AS idDevolucion;
@@ -1,3 +1,3 @@
 -- Direccion de envio o facturacion para el usuario
-INSERT INTO Direccion (idUsuario, tipo, detalle)
-VALUES (@userId, @type, @detail);
+INSERT INTO Direccion (idUsuario, tipo, detalle, region)
+VALUES (@userId, @type, @detail, @region);
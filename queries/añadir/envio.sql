@@ -0,0 +1,26 @@
+-- Registrar la guia de un pedido pagado y pasarlo a Enviado en la misma transaccion
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE Pedido
+SET estado = 'Enviado'
+WHERE idPedido = @orderId AND estado = 'Pagado';
+
+IF @@ROWCOUNT = 0
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50050, 'el pedido ya no esta pagado y pendiente de envio', 1;
+END;
+
+DECLARE @newShipmentId INT;
+INSERT INTO Envio (idPedido, idMetodoEnvio, transportista, numeroSeguimiento, pesoKg)
+VALUES (@orderId, @methodId, @carrier, @tracking, @weight);
+SET @newShipmentId = SCOPE_IDENTITY();
+
+INSERT INTO PedidoHistorial (idPedido, estadoAnterior, estadoNuevo, actor)
+VALUES (@orderId, 'Pagado', 'Enviado', @actor);
+
+COMMIT TRANSACTION;
+
+SELECT @newShipmentId AS idEnvio;
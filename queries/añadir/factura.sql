@@ -0,0 +1,51 @@
+-- Emitir la factura de un pedido: toma el siguiente numero de la serie (la crea si no existe),
+-- copia nombre del cliente, direccion de facturacion, montos y lineas del pedido.
+-- Todo va en una transaccion: si algo falla el numero no se consume.
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM FacturaSerie WITH (UPDLOCK, HOLDLOCK) WHERE serie = @series)
+    INSERT INTO FacturaSerie (serie, siguiente) VALUES (@series, 1);
+
+DECLARE @number INT;
+UPDATE FacturaSerie
+SET @number = siguiente,
+    siguiente = siguiente + 1
+WHERE serie = @series;
+
+DECLARE @newInvoiceId INT;
+INSERT INTO Factura (serie, numero, idPedido, nombreCliente, direccionFacturacion,
+    subtotal, descuento, impuesto, envio, total)
+SELECT @series, @number, p.idPedido, c.nombre, ISNULL(d.detalle, ''),
+    p.subtotal, p.descuento, p.impuesto, p.envio, p.total
+FROM Pedido p
+INNER JOIN Clientes c ON c.idUsuario = p.idUsuario
+INNER JOIN Direccion d ON d.idDirección = p.idDireccionFacturacion
+WHERE p.idPedido = @orderId;
+
+IF @@ROWCOUNT = 0
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50040, 'el pedido no existe o no tiene direccion de facturacion', 1;
+END;
+SET @newInvoiceId = SCOPE_IDENTITY();
+
+INSERT INTO FacturaDetalle (idFactura, idSKU, descripcion, cantidad, precioUnitario, importe)
+SELECT @newInvoiceId, pd.idSKU, LEFT(pr.descripcion + ISNULL(' (' + v.variante + ')', ''), 200),
+    pd.cantidad, pd.precioUnitario, pd.cantidad * pd.precioUnitario
+FROM PedidoDetalle pd
+INNER JOIN SKU s ON s.idSKU = pd.idSKU
+INNER JOIN Producto pr ON pr.idProducto = s.idProducto
+OUTER APPLY (
+    SELECT STRING_AGG(a.nombre + ': ' + sa.valor, ', ') WITHIN GROUP (ORDER BY a.orden, a.idAtributo) AS variante
+    FROM SKUAtributo sa
+    INNER JOIN ProductoAtributo a ON a.idAtributo = sa.idAtributo
+    WHERE sa.idSKU = s.idSKU
+) v
+WHERE pd.idPedido = @orderId
+ORDER BY pd.idDetalle;
+
+COMMIT TRANSACTION;
+
+SELECT @newInvoiceId AS idFactura;
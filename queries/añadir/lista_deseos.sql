@@ -0,0 +1,9 @@
+-- Crear una lista de deseos para un cliente (nombre unico por cliente)
+SET NOCOUNT ON;
+
+DECLARE @newListId INT;
+INSERT INTO ListaDeseos (idUsuario, nombre)
+VALUES (@userId, @name);
+SET @newListId = SCOPE_IDENTITY();
+
+SELECT @newListId AS idLista;
@@ -0,0 +1,33 @@
+-- Agregar un producto (@skuId NULL) o un SKU puntual a una lista de deseos.
+-- Con SKU el producto sale del SKU. El precio y stock actuales quedan como
+-- base para los avisos, asi que solo se avisa de cambios posteriores.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @product INT = CASE WHEN @skuId IS NULL THEN @productId END;
+IF @skuId IS NOT NULL
+    SELECT @product = idProducto FROM SKU WHERE idSKU = @skuId;
+
+IF NOT EXISTS (SELECT 1 FROM Producto WHERE idProducto = @product)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50110, 'el producto o SKU no existe', 1;
+END;
+
+IF EXISTS (
+    SELECT 1 FROM ListaDeseosItem WITH (UPDLOCK, HOLDLOCK)
+    WHERE idLista = @listId
+      AND idProducto = @product
+      AND ((@skuId IS NULL AND idSKU IS NULL) OR idSKU = @skuId)
+)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50111, 'el articulo ya esta en la lista', 1;
+END;
+
+INSERT INTO ListaDeseosItem (idLista, idProducto, idSKU, ultimoPrecio, ultimoStock)
+SELECT @listId, @product, @skuId, MIN(s.precio), ISNULL(SUM(s.stock), 0)
+FROM SKU s
+WHERE s.idProducto = @product AND (@skuId IS NULL OR s.idSKU = @skuId);
+
+COMMIT TRANSACTION;
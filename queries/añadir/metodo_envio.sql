@@ -0,0 +1,3 @@
+-- Nuevo metodo de envio de un transportista
+INSERT INTO MetodoEnvio (idTransportista, nombre, diasEstimados, envioGratisDesde)
+VALUES (@carrierId, @name, @days, @freeFrom);
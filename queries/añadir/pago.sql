@@ -0,0 +1,24 @@
+-- Registrar el resultado de una autorizacion de pago. Un pedido tiene a lo
+-- sumo un pago Autorizado o Capturado: se revisa con el pedido bloqueado para
+-- que dos autorizaciones simultaneas no entren las dos.
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF @status = 'Autorizado' AND EXISTS (
+    SELECT 1 FROM Pago WITH (UPDLOCK, HOLDLOCK)
+    WHERE idPedido = @orderId AND estado IN ('Autorizado', 'Capturado')
+)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50031, 'el pedido ya tiene un pago autorizado o capturado', 1;
+END;
+
+DECLARE @newPaymentId INT;
+INSERT INTO Pago (idPedido, pasarela, referencia, monto, estado, motivo)
+VALUES (@orderId, @gateway, @reference, @amount, @status, @reason);
+SET @newPaymentId = SCOPE_IDENTITY();
+
+COMMIT TRANSACTION;
+
+SELECT @newPaymentId AS idPago;
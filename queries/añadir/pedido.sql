@@ -1,3 +1,121 @@
--- Nuevo pedido del usuario
-INSERT INTO Pedido (idUsuario, entregado)
-VALUES (@userId, @delivered);
+-- Nuevo pedido a partir del carrito del usuario: guarda las lineas cotizadas, los
+-- montos calculados, el desglose de impuestos y las promociones aplicadas, descuenta
+-- el stock de los almacenes, registra el canje del cupon, marca como recuperado el
+-- recordatorio de carrito abandonado, vacia el carrito y registra el estado inicial.
+-- Todo en una transaccion: si a un SKU le falta stock no queda nada escrito.
+-- @lines es un arreglo JSON: [{"idSKU":4,"cantidad":2,"precioUnitario":10.00}, ...]
+-- @taxes es un arreglo JSON: [{"nombre":"IVA","tasa":0.13,"base":100.00,"monto":13.00}, ...]
+-- @promotions es un arreglo JSON: [{"idPromocion":1,"nombre":"3x2","idSKU":4,"descuento":10.00,"detalle":"..."}, ...]
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @newOrderId INT;
+INSERT INTO Pedido (idUsuario, estado, idDireccionEnvio, idDireccionFacturacion,
+    subtotal, descuento, impuesto, envio, total, preciosConImpuesto, idMetodoEnvio)
+VALUES (@userId, 'Pendiente', @shippingAddressId, @billingAddressId,
+    @subtotal, @discount, @tax, @shipping, @total, @pricesIncludeTax, @shippingMethodId);
+SET @newOrderId = SCOPE_IDENTITY();
+
+INSERT INTO PedidoDetalle (idPedido, idSKU, cantidad, precioUnitario)
+SELECT @newOrderId, l.idSKU, l.cantidad, l.precioUnitario
+FROM OPENJSON(@lines)
+WITH (
+    idSKU INT '$.idSKU',
+    cantidad INT '$.cantidad',
+    precioUnitario DECIMAL(10,2) '$.precioUnitario'
+) l;
+
+-- Descuenta el stock SKU por SKU; AsignarStockSKU bloquea las filas de
+-- SKUAlmacen y falla si no alcanza
+DECLARE @perSKU TABLE (idSKU INT PRIMARY KEY, cantidad INT NOT NULL);
+INSERT INTO @perSKU (idSKU, cantidad)
+SELECT idSKU, SUM(cantidad)
+FROM PedidoDetalle
+WHERE idPedido = @newOrderId
+GROUP BY idSKU;
+
+DECLARE @plan TABLE (idAlmacen INT NOT NULL, prioridad INT NOT NULL, cantidad INT NOT NULL);
+DECLARE @skuId INT = (SELECT MIN(idSKU) FROM @perSKU);
+DECLARE @quantity INT;
+WHILE @skuId IS NOT NULL
+BEGIN
+    SELECT @quantity = cantidad FROM @perSKU WHERE idSKU = @skuId;
+    BEGIN TRY
+        INSERT INTO @plan (idAlmacen, prioridad, cantidad)
+        EXEC AsignarStockSKU @skuId = @skuId, @quantity = @quantity, @descontar = 1;
+    END TRY
+    BEGIN CATCH
+        IF @@TRANCOUNT > 0 ROLLBACK TRANSACTION;
+        IF ERROR_NUMBER() <> 50002 THROW;
+        DECLARE @mensaje NVARCHAR(200) = CONCAT(N'SKU ', @skuId, N': ', ERROR_MESSAGE());
+        THROW 50002, @mensaje, 1;
+    END CATCH;
+    SET @skuId = (SELECT MIN(idSKU) FROM @perSKU WHERE idSKU > @skuId);
+END;
+
+INSERT INTO PedidoImpuesto (idPedido, nombre, tasa, base, monto)
+SELECT @newOrderId, t.nombre, t.tasa, t.base, t.monto
+FROM OPENJSON(@taxes)
+WITH (
+    nombre VARCHAR(50) '$.nombre',
+    tasa DECIMAL(6,4) '$.tasa',
+    base DECIMAL(10,2) '$.base',
+    monto DECIMAL(10,2) '$.monto'
+) t;
+
+INSERT INTO PedidoPromocion (idPedido, idPromocion, nombre, idSKU, descuento, detalle)
+SELECT @newOrderId, p.idPromocion, p.nombre, p.idSKU, p.descuento, p.detalle
+FROM OPENJSON(@promotions)
+WITH (
+    idPromocion INT '$.idPromocion',
+    nombre VARCHAR(50) '$.nombre',
+    idSKU INT '$.idSKU',
+    descuento DECIMAL(10,2) '$.descuento',
+    detalle VARCHAR(200) '$.detalle'
+) p;
+
+IF @couponId IS NOT NULL
+BEGIN
+    -- Bloquea el cupon para que dos pedidos no pasen el limite al mismo tiempo
+    DECLARE @maxUses INT, @maxUsesPerCustomer INT;
+    SELECT @maxUses = usosMaximos, @maxUsesPerCustomer = usosPorCliente
+    FROM Cupon WITH (UPDLOCK, HOLDLOCK)
+    WHERE idCupon = @couponId;
+
+    IF (@maxUses IS NOT NULL AND @maxUses <= (
+            SELECT COUNT(*) FROM CuponUso u
+            INNER JOIN Pedido p ON p.idPedido = u.idPedido
+            WHERE u.idCupon = @couponId AND p.estado <> 'Cancelado'))
+        OR (@maxUsesPerCustomer IS NOT NULL AND @maxUsesPerCustomer <= (
+            SELECT COUNT(*) FROM CuponUso u
+            INNER JOIN Pedido p ON p.idPedido = u.idPedido
+            WHERE u.idCupon = @couponId AND u.idUsuario = @userId AND p.estado <> 'Cancelado'))
+    BEGIN
+        ROLLBACK TRANSACTION;
+        THROW 50060, 'el cupon alcanzo su limite de usos', 1;
+    END;
+
+    INSERT INTO CuponUso (idCupon, idPedido, idUsuario, monto)
+    VALUES (@couponId, @newOrderId, @userId, @couponDiscount);
+END;
+
+-- Si al cliente se le envio un recordatorio del carrito hace poco, el pedido
+-- cuenta como carrito recuperado
+UPDATE CarritoRecordatorio
+SET idPedido = @newOrderId
+WHERE idCarrito = @cartId AND idPedido IS NULL AND fechaEnvio >= @remindedSince;
+
+DELETE FROM CarritoDetalle
+WHERE idCarrito = @cartId;
+
+UPDATE Carrito
+SET codigoCupon = NULL
+WHERE idCarrito = @cartId;
+
+INSERT INTO PedidoHistorial (idPedido, estadoAnterior, estadoNuevo, actor)
+VALUES (@newOrderId, NULL, 'Pendiente', @actor);
+
+COMMIT TRANSACTION;
+
+SELECT @newOrderId AS idPedido;
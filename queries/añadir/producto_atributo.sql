@@ -0,0 +1,3 @@
+-- Nuevo atributo de variante para un producto
+INSERT INTO ProductoAtributo (idProducto, nombre, orden)
+VALUES (@productId, @name, @order);
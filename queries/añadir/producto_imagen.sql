@@ -0,0 +1,20 @@
+-- Nueva imagen al final de la galeria; la primera imagen de un producto queda como principal
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @newImageId INT, @order INT, @primary BIT;
+SELECT @order = ISNULL(MAX(orden), 0) + 1,
+    @primary = CASE WHEN COUNT(*) = 0 THEN 1 ELSE 0 END
+FROM ProductoImagen WITH (UPDLOCK, HOLDLOCK)
+WHERE idProducto = @productId;
+
+INSERT INTO ProductoImagen (idProducto, clave, claveMiniatura, tipoContenido, tamano, ancho, alto,
+    textoAlternativo, orden, principal)
+VALUES (@productId, @key, @thumbnailKey, @contentType, @size, @width, @height,
+    @altText, @order, @primary);
+SET @newImageId = SCOPE_IDENTITY();
+
+COMMIT TRANSACTION;
+
+SELECT @newImageId AS idImagen;
@@ -0,0 +1,5 @@
+-- Nueva promocion
+INSERT INTO Promocion (nombre, tipo, prioridad, acumulable, idSKU, idCategoria,
+    cantidadCompra, cantidadGratis, porcentaje, precioEspecial, vigenteDesde, vigenteHasta)
+VALUES (@name, @type, @priority, @stackable, @skuId, @categoryId,
+    @buyQuantity, @freeQuantity, @percentage, @specialPrice, @validFrom, @validUntil);
@@ -0,0 +1,3 @@
+-- Nueva regla de impuesto (categoria y region NULL = aplica a todas)
+INSERT INTO ReglaImpuesto (nombre, tasa, idCategoria, region)
+VALUES (@name, @rate, @categoryId, @region);
@@ -1,3 +1,19 @@
--- Registrar reseña con puntuacion de 1 a 5
+-- Registrar reseña con puntuacion de 1 a 5; queda pendiente de moderacion.
+-- Cada cliente reseña un producto una sola vez (UQ_Reseña_Cliente). Las
+-- pendientes no cuentan, asi que ProductoCalificacion no cambia.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF EXISTS (
+    SELECT 1 FROM [Reseña] WITH (UPDLOCK, HOLDLOCK)
+    WHERE idUsuario = @userId AND idProducto = @productId
+)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50100, 'el cliente ya reseño este producto', 1;
+END;
+
 INSERT INTO [Reseña] (idUsuario, idProducto, [puntuación], comentario)
 VALUES (@userId, @productId, @rating, @comment);
+
+COMMIT TRANSACTION;
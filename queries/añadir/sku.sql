@@ -1,3 +1,28 @@
--- Variante minima vendible (SKU)
-INSERT INTO SKU (idProducto, precio, stock)
-VALUES (@productId, @price, @stock);
+-- Variante minima vendible (SKU); el stock inicial entra al almacen activo de mayor prioridad
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @newSkuId INT;
+INSERT INTO SKU (idProducto, precio, stock, codigo, codigoBarras)
+VALUES (@productId, @price, @stock, @code, @barcode);
+SET @newSkuId = SCOPE_IDENTITY();
+
+INSERT INTO SKUPrecio (idSKU, precio, vigenteDesde)
+VALUES (@newSkuId, @price, SYSUTCDATETIME());
+
+IF @stock > 0
+BEGIN
+    INSERT INTO SKUAlmacen (idSKU, idAlmacen, stock)
+    SELECT TOP 1 @newSkuId, idAlmacen, @stock
+    FROM Almacen
+    WHERE activo = 1
+    ORDER BY prioridad, idAlmacen;
+
+    IF @@ROWCOUNT = 0
+    BEGIN
+        ROLLBACK TRANSACTION;
+        THROW 50003, 'no hay almacenes activos para recibir el stock inicial', 1;
+    END;
+END;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,52 @@
+-- Programar un precio del SKU desde @from (NULL = ahora). Corta el periodo que
+-- contiene esa fecha y el nuevo dura hasta el siguiente cambio ya programado;
+-- si ya habia un cambio justo en @from se reemplaza su precio.
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @now DATETIME2 = SYSUTCDATETIME();
+DECLARE @start DATETIME2 = COALESCE(@from, @now);
+
+IF NOT EXISTS (SELECT 1 FROM SKU WITH (UPDLOCK, HOLDLOCK) WHERE idSKU = @skuId)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50090, 'el SKU no existe', 1;
+END;
+
+IF @start < @now
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50091, 'no se puede programar un precio en el pasado', 1;
+END;
+
+DECLARE @newPriceId INT = (
+    SELECT idPrecio FROM SKUPrecio WHERE idSKU = @skuId AND vigenteDesde = @start
+);
+
+IF @newPriceId IS NOT NULL
+    UPDATE SKUPrecio SET precio = @price WHERE idPrecio = @newPriceId;
+ELSE
+BEGIN
+    DECLARE @end DATETIME2 = (
+        SELECT MIN(vigenteDesde) FROM SKUPrecio WHERE idSKU = @skuId AND vigenteDesde > @start
+    );
+
+    UPDATE SKUPrecio
+    SET vigenteHasta = @start
+    WHERE idSKU = @skuId
+      AND vigenteDesde < @start
+      AND (vigenteHasta IS NULL OR vigenteHasta > @start);
+
+    INSERT INTO SKUPrecio (idSKU, precio, vigenteDesde, vigenteHasta)
+    VALUES (@skuId, @price, @start, @end);
+    SET @newPriceId = SCOPE_IDENTITY();
+END;
+
+-- Si ya rige se refleja en el SKU
+IF @start <= @now
+    UPDATE SKU SET precio = @price WHERE idSKU = @skuId;
+
+COMMIT TRANSACTION;
+
+SELECT @newPriceId AS idPrecio;
@@ -0,0 +1,3 @@
+-- Nueva tarifa de envio (zona y maximos NULL = sin restriccion)
+INSERT INTO TarifaEnvio (idMetodoEnvio, idZona, pesoMin, pesoMax, montoMin, montoMax, precio)
+VALUES (@methodId, @zoneId, @minWeight, @maxWeight, @minAmount, @maxAmount, @price);
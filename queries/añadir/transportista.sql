@@ -0,0 +1,3 @@
+-- Nuevo transportista
+INSERT INTO Transportista (nombre, codigo)
+VALUES (@name, @code);
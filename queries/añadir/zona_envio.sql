@@ -0,0 +1,3 @@
+-- Nueva zona de envio
+INSERT INTO ZonaEnvio (nombre)
+VALUES (@name);
@@ -0,0 +1,6 @@
+-- Actualizar nombre, prioridad o estado del almacen
+UPDATE Almacen
+SET nombre = @name,
+    prioridad = @priority,
+    activo = @active
+WHERE idAlmacen = @id;
@@ -0,0 +1,42 @@
+-- Guardar para despues: pasar una linea del carrito a una lista de deseos del
+-- mismo cliente como deseo de ese SKU. Si la lista ya lo tiene solo se quita
+-- la linea del carrito.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @skuId INT, @product INT, @cartUser INT;
+SELECT @skuId = d.idSKU, @product = s.idProducto, @cartUser = c.idUsuario
+FROM CarritoDetalle d WITH (UPDLOCK)
+INNER JOIN Carrito c ON c.idCarrito = d.idCarrito
+INNER JOIN SKU s ON s.idSKU = d.idSKU
+WHERE d.idDetalle = @detailId;
+
+IF @skuId IS NULL
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50114, 'la linea del carrito no existe', 1;
+END;
+
+IF NOT EXISTS (SELECT 1 FROM ListaDeseos WHERE idLista = @listId AND idUsuario = @cartUser)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50115, 'la lista de deseos no es del dueño del carrito', 1;
+END;
+
+IF NOT EXISTS (
+    SELECT 1 FROM ListaDeseosItem WITH (UPDLOCK, HOLDLOCK)
+    WHERE idLista = @listId AND idSKU = @skuId
+)
+    INSERT INTO ListaDeseosItem (idLista, idProducto, idSKU, ultimoPrecio, ultimoStock)
+    SELECT @listId, @product, idSKU, precio, stock
+    FROM SKU
+    WHERE idSKU = @skuId;
+
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = (SELECT idCarrito FROM CarritoDetalle WHERE idDetalle = @detailId);
+
+DELETE FROM CarritoDetalle
+WHERE idDetalle = @detailId;
+
+COMMIT TRANSACTION;
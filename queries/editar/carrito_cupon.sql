@@ -0,0 +1,4 @@
+-- Aplicar o quitar (NULL) el cupon del carrito
+UPDATE Carrito
+SET codigoCupon = @code
+WHERE idCarrito = @id;
@@ -1,6 +1,23 @@
--- Ajustar items en el carrito
+-- Ajustar items en el carrito, sin pasar de @maxQuantity por linea
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF @quantity > @maxQuantity
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50121, 'la cantidad supera el maximo por linea', 1;
+END;
+
 UPDATE CarritoDetalle
 SET idCarrito = @cartId,
     idSKU = @skuId,
-    cantidad = @quantity
+    cantidad = @quantity,
+    fechaActualizacion = SYSUTCDATETIME()
 WHERE idDetalle = @id;
+
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = @cartId;
+
+COMMIT TRANSACTION;
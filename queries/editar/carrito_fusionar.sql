@@ -0,0 +1,68 @@
+-- Pasar el carrito de invitado con @token al carrito del usuario (se crea si
+-- no tiene) y borrar el de invitado. Las cantidades del mismo SKU se suman
+-- hasta el stock y @maxQuantity, sin bajar lo que el cliente ya tenia; las
+-- lineas de SKUs borrados o sin stock se descartan. Devuelve las lineas que no
+-- entraron completas.
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @guestCartId INT = (SELECT idCarrito FROM Carrito WITH (UPDLOCK, HOLDLOCK) WHERE token = @token);
+
+IF @guestCartId IS NULL
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50122, 'el carrito de invitado no existe o vencio', 1;
+END;
+
+DECLARE @cartId INT = (SELECT idCarrito FROM Carrito WITH (UPDLOCK, HOLDLOCK) WHERE idUsuario = @userId);
+IF @cartId IS NULL
+BEGIN
+    INSERT INTO Carrito (idUsuario) VALUES (@userId);
+    SET @cartId = SCOPE_IDENTITY();
+END;
+
+DECLARE @lineas TABLE (idSKU INT PRIMARY KEY, actual INT NOT NULL, invitado INT NOT NULL, cantidad INT NOT NULL);
+INSERT INTO @lineas (idSKU, actual, invitado, cantidad)
+SELECT g.idSKU, ISNULL(c.cantidad, 0), g.cantidad,
+    CASE
+        WHEN ISNULL(c.cantidad, 0) + g.cantidad <= t.tope THEN ISNULL(c.cantidad, 0) + g.cantidad
+        WHEN ISNULL(c.cantidad, 0) >= t.tope THEN ISNULL(c.cantidad, 0)
+        ELSE t.tope
+    END
+FROM CarritoDetalle g
+INNER JOIN SKU s ON s.idSKU = g.idSKU
+CROSS APPLY (SELECT CASE WHEN s.stock < @maxQuantity THEN s.stock ELSE @maxQuantity END AS tope) t
+LEFT JOIN CarritoDetalle c WITH (UPDLOCK) ON c.idCarrito = @cartId AND c.idSKU = g.idSKU
+WHERE g.idCarrito = @guestCartId;
+
+UPDATE c
+SET cantidad = l.cantidad,
+    fechaActualizacion = SYSUTCDATETIME()
+FROM CarritoDetalle c
+INNER JOIN @lineas l ON l.idSKU = c.idSKU
+WHERE c.idCarrito = @cartId AND l.actual > 0;
+
+INSERT INTO CarritoDetalle (idCarrito, idSKU, cantidad)
+SELECT @cartId, idSKU, cantidad
+FROM @lineas
+WHERE actual = 0 AND cantidad > 0;
+
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = @cartId;
+
+DELETE FROM Carrito
+WHERE idCarrito = @guestCartId;
+
+COMMIT TRANSACTION;
+
+SELECT l.idSKU, l.actual + l.invitado AS solicitada, l.cantidad, s.codigo,
+    (SELECT STRING_AGG(pa.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY pa.orden, pa.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo pa ON pa.idAtributo = v.idAtributo
+     WHERE v.idSKU = l.idSKU) AS variante
+FROM @lineas l
+INNER JOIN SKU s ON s.idSKU = l.idSKU
+WHERE l.cantidad < l.actual + l.invitado
+ORDER BY l.idSKU;
@@ -0,0 +1,39 @@
+-- Fijar la cantidad de un SKU en el carrito del usuario o del invitado con
+-- @token (se crea la linea, y el carrito del usuario, si no estaban). Quitarlo
+-- es remover/carrito_item.sql.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (SELECT 1 FROM SKU WHERE idSKU = @skuId)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50120, 'el SKU no existe', 1;
+END;
+
+DECLARE @cartId INT = (
+    SELECT idCarrito FROM Carrito WITH (UPDLOCK, HOLDLOCK)
+    WHERE idUsuario = @userId OR token = @token);
+IF @cartId IS NULL AND @userId IS NULL
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50122, 'el carrito de invitado no existe o vencio', 1;
+END;
+IF @cartId IS NULL
+BEGIN
+    INSERT INTO Carrito (idUsuario) VALUES (@userId);
+    SET @cartId = SCOPE_IDENTITY();
+END;
+
+MERGE CarritoDetalle WITH (HOLDLOCK) AS destino
+USING (SELECT @cartId AS idCarrito, @skuId AS idSKU) AS origen
+ON destino.idCarrito = origen.idCarrito AND destino.idSKU = origen.idSKU
+WHEN MATCHED THEN
+    UPDATE SET cantidad = @quantity, fechaActualizacion = SYSUTCDATETIME()
+WHEN NOT MATCHED THEN
+    INSERT (idCarrito, idSKU, cantidad) VALUES (origen.idCarrito, origen.idSKU, @quantity);
+
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = @cartId;
+
+COMMIT TRANSACTION;
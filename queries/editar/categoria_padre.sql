@@ -0,0 +1,33 @@
+-- Mover una categoria (con toda su rama) bajo otro padre (@parentId NULL = raiz).
+-- El nuevo padre no puede ser la misma categoria ni una de sus descendientes.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+-- Bloquea la tabla para que otro movimiento no arme un ciclo en paralelo
+IF NOT EXISTS (SELECT 1 FROM Categoria WITH (TABLOCKX, HOLDLOCK) WHERE idCategoria = @id)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50080, 'la categoria no existe', 1;
+END;
+
+DECLARE @cycle BIT = 0;
+WITH rama AS (
+    SELECT idCategoria FROM Categoria WHERE idCategoria = @id
+    UNION ALL
+    SELECT c.idCategoria
+    FROM Categoria c
+    INNER JOIN rama r ON c.idPadre = r.idCategoria
+)
+SELECT @cycle = 1 FROM rama WHERE idCategoria = @parentId;
+
+IF @cycle = 1
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50081, 'no se puede mover una categoria dentro de su propia rama', 1;
+END;
+
+UPDATE Categoria
+SET idPadre = @parentId
+WHERE idCategoria = @id;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,6 @@
+-- Registrar un inicio de sesion correcto
+UPDATE Clientes
+SET intentosFallidos = 0,
+    bloqueadoHasta = NULL,
+    ultimoAcceso = SYSUTCDATETIME()
+WHERE idUsuario = @id;
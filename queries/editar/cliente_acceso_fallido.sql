@@ -0,0 +1,7 @@
+-- Registrar un intento fallido; al llegar a @maxAttempts la cuenta queda
+-- bloqueada hasta @lockedUntil y el conteo vuelve a cero. Con @id = 0 no
+-- cambia nada, pero el intento cuesta lo mismo que con una cuenta real.
+UPDATE Clientes
+SET intentosFallidos = CASE WHEN intentosFallidos + 1 >= @maxAttempts THEN 0 ELSE intentosFallidos + 1 END,
+    bloqueadoHasta = CASE WHEN intentosFallidos + 1 >= @maxAttempts THEN @lockedUntil ELSE bloqueadoHasta END
+WHERE idUsuario = @id;
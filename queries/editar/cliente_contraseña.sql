@@ -4,5 +4,8 @@ SET @SALT = CRYPT_GEN_RANDOM(32);
 
 UPDATE Clientes
 SET passwordHash = HASHBYTES('SHA2_256', @SALT + CONVERT(VARBINARY(MAX), @password )),
-    passwordSalt = @SALT
+    passwordSalt = @SALT,
+    -- Una contraseña nueva levanta el bloqueo
+    intentosFallidos = 0,
+    bloqueadoHasta = NULL
 WHERE idUsuario = @id
\ No newline at end of file
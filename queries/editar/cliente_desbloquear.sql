@@ -0,0 +1,5 @@
+-- Levantar el bloqueo de inicio de sesion de un cliente
+UPDATE Clientes
+SET intentosFallidos = 0,
+    bloqueadoHasta = NULL
+WHERE idUsuario = @id;
@@ -0,0 +1,13 @@
+-- Actualizar cupon
+UPDATE Cupon
+SET codigo = @code,
+    tipo = @type,
+    valor = @value,
+    montoMinimo = @minimum,
+    usosMaximos = @maxUses,
+    usosPorCliente = @maxUsesPerCustomer,
+    vigenteDesde = @validFrom,
+    vigenteHasta = @validUntil,
+    idCategoria = @categoryId,
+    activo = @active
+WHERE idCupon = @id;
@@ -1,8 +1,4 @@
--- Actualizar datos de una devolucion
+-- Actualizar la descripcion de una devolucion (el estado cambia con editar/devolucion_estado.sql)
 UPDATE Devolucion
-SET idPedido = @orderId,
-    fecha = @date,
-    estado = @status,
-    descripcion = @description,
-    resolucion = @resolution
+SET descripcion = @description
 WHERE idDevolucion = @id;
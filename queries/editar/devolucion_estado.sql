@@ -0,0 +1,112 @@
+-- Transicion de estado de una devolucion con su nota de resolucion.
+-- Al pasar a Recibida se reponen las lineas devueltas en el almacen activo de mayor prioridad.
+-- Al pasar a Reembolsada suma @refundAmount a lo reembolsado del pago @paymentId
+-- (NULL si el pedido no tiene pago capturado), y si ya se reembolso todo lo
+-- comprado el pedido pasa a Devuelto.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE Devolucion
+SET estado = @to,
+    resolucion = COALESCE(@note, resolucion)
+WHERE idDevolucion = @id AND estado = @from;
+
+IF @@ROWCOUNT = 0
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50020, 'la devolucion ya no esta en el estado esperado', 1;
+END;
+
+INSERT INTO DevolucionHistorial (idDevolucion, estadoAnterior, estadoNuevo, actor, nota)
+VALUES (@id, @from, @to, @actor, @note);
+
+IF @to = 'Recibida'
+BEGIN
+    DECLARE @restockWarehouse INT;
+    SELECT TOP 1 @restockWarehouse = idAlmacen
+    FROM Almacen
+    WHERE activo = 1
+    ORDER BY prioridad, idAlmacen;
+
+    IF @restockWarehouse IS NULL
+    BEGIN
+        ROLLBACK TRANSACTION;
+        THROW 50021, 'no hay almacenes activos para reponer la devolucion', 1;
+    END;
+
+    DECLARE @returned TABLE (idSKU INT PRIMARY KEY, cantidad INT NOT NULL);
+    INSERT INTO @returned (idSKU, cantidad)
+    SELECT pd.idSKU, SUM(dd.cantidad)
+    FROM DevolucionDetalle dd
+    INNER JOIN PedidoDetalle pd ON pd.idDetalle = dd.idPedidoDetalle
+    WHERE dd.idDevolucion = @id
+    GROUP BY pd.idSKU;
+
+    UPDATE sa
+    SET stock = sa.stock + r.cantidad
+    FROM SKUAlmacen sa
+    INNER JOIN @returned r ON r.idSKU = sa.idSKU
+    WHERE sa.idAlmacen = @restockWarehouse;
+
+    INSERT INTO SKUAlmacen (idSKU, idAlmacen, stock)
+    SELECT r.idSKU, @restockWarehouse, r.cantidad
+    FROM @returned r
+    WHERE NOT EXISTS (
+        SELECT 1 FROM SKUAlmacen sa
+        WHERE sa.idSKU = r.idSKU AND sa.idAlmacen = @restockWarehouse
+    );
+
+    UPDATE s
+    SET stock = (SELECT ISNULL(SUM(sa.stock), 0) FROM SKUAlmacen sa WHERE sa.idSKU = s.idSKU)
+    FROM SKU s
+    INNER JOIN @returned r ON r.idSKU = s.idSKU;
+END;
+
+IF @to = 'Reembolsada'
+BEGIN
+    DECLARE @orderId INT = (SELECT idPedido FROM Devolucion WHERE idDevolucion = @id);
+
+    IF @paymentId IS NOT NULL
+    BEGIN
+        UPDATE Pago
+        SET montoReembolsado = montoReembolsado + @refundAmount,
+            estado = CASE WHEN montoReembolsado + @refundAmount >= monto THEN 'Reembolsado' ELSE estado END,
+            fechaActualizacion = SYSUTCDATETIME()
+        WHERE idPago = @paymentId AND idPedido = @orderId AND estado = 'Capturado'
+          AND montoReembolsado + @refundAmount <= monto;
+
+        IF @@ROWCOUNT = 0
+        BEGIN
+            ROLLBACK TRANSACTION;
+            THROW 50030, 'el pago ya no esta en el estado esperado', 1;
+        END;
+    END;
+
+    IF NOT EXISTS (
+        SELECT 1
+        FROM PedidoDetalle pd
+        WHERE pd.idPedido = @orderId
+          AND pd.cantidad > ISNULL((
+              SELECT SUM(dd.cantidad)
+              FROM DevolucionDetalle dd
+              INNER JOIN Devolucion d ON d.idDevolucion = dd.idDevolucion AND d.estado = 'Reembolsada'
+              WHERE dd.idPedidoDetalle = pd.idDetalle
+          ), 0)
+    )
+    BEGIN
+        UPDATE Pedido
+        SET estado = 'Devuelto'
+        WHERE idPedido = @orderId AND estado = 'Entregado';
+
+        IF @@ROWCOUNT = 0
+        BEGIN
+            ROLLBACK TRANSACTION;
+            THROW 50010, 'el pedido ya no esta en el estado esperado', 1;
+        END;
+
+        INSERT INTO PedidoHistorial (idPedido, estadoAnterior, estadoNuevo, actor)
+        VALUES (@orderId, 'Entregado', 'Devuelto', @actor);
+    END;
+END;
+
+COMMIT TRANSACTION;
@@ -2,5 +2,6 @@
 UPDATE Direccion
 SET idUsuario = @userId,
     tipo = @type,
-    detalle = @detail
+    detalle = @detail,
+    region = @region
 WHERE idDirección = @id;
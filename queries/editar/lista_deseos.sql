@@ -0,0 +1,4 @@
+-- Renombrar lista de deseos
+UPDATE ListaDeseos
+SET nombre = @name
+WHERE idLista = @id;
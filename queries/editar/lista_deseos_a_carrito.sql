@@ -0,0 +1,54 @@
+-- Pasar un item de la lista de deseos al carrito del mismo cliente (se crea
+-- si no tiene). @skuId es la variante elegida y tiene que ser del producto del
+-- item; si el carrito ya tiene ese SKU se suma la cantidad, hasta @maxQuantity.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @userId INT, @product INT;
+SELECT @userId = l.idUsuario, @product = i.idProducto
+FROM ListaDeseosItem i WITH (UPDLOCK)
+INNER JOIN ListaDeseos l ON l.idLista = i.idLista
+WHERE i.idItem = @itemId;
+
+IF @userId IS NULL
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50112, 'el item de la lista no existe', 1;
+END;
+
+IF NOT EXISTS (SELECT 1 FROM SKU WHERE idSKU = @skuId AND idProducto = @product)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50113, 'el SKU no es del producto del item', 1;
+END;
+
+DECLARE @cartId INT = (SELECT idCarrito FROM Carrito WITH (UPDLOCK, HOLDLOCK) WHERE idUsuario = @userId);
+IF @cartId IS NULL
+BEGIN
+    INSERT INTO Carrito (idUsuario) VALUES (@userId);
+    SET @cartId = SCOPE_IDENTITY();
+END;
+
+IF ISNULL((SELECT cantidad FROM CarritoDetalle WITH (UPDLOCK, HOLDLOCK)
+           WHERE idCarrito = @cartId AND idSKU = @skuId), 0) + @quantity > @maxQuantity
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50121, 'la cantidad supera el maximo por linea', 1;
+END;
+
+MERGE CarritoDetalle WITH (HOLDLOCK) AS destino
+USING (SELECT @cartId AS idCarrito, @skuId AS idSKU) AS origen
+ON destino.idCarrito = origen.idCarrito AND destino.idSKU = origen.idSKU
+WHEN MATCHED THEN
+    UPDATE SET cantidad = destino.cantidad + @quantity, fechaActualizacion = SYSUTCDATETIME()
+WHEN NOT MATCHED THEN
+    INSERT (idCarrito, idSKU, cantidad) VALUES (origen.idCarrito, origen.idSKU, @quantity);
+
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = @cartId;
+
+DELETE FROM ListaDeseosItem
+WHERE idItem = @itemId;
+
+COMMIT TRANSACTION;
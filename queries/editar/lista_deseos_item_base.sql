@@ -0,0 +1,6 @@
+-- Guardar el precio (@setPrice = 1) y/o el stock (@setStock = 1) con que se
+-- evaluo el ultimo aviso de un item
+UPDATE ListaDeseosItem
+SET ultimoPrecio = CASE WHEN @setPrice = 1 THEN @price ELSE ultimoPrecio END,
+    ultimoStock = CASE WHEN @setStock = 1 THEN @stock ELSE ultimoStock END
+WHERE idItem = @id;
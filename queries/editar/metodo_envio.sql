@@ -0,0 +1,8 @@
+-- Actualizar metodo de envio
+UPDATE MetodoEnvio
+SET idTransportista = @carrierId,
+    nombre = @name,
+    diasEstimados = @days,
+    envioGratisDesde = @freeFrom,
+    activo = @active
+WHERE idMetodoEnvio = @id;
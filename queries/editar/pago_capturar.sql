@@ -0,0 +1,32 @@
+-- Registrar la captura de un pago autorizado y pasar su pedido de Pendiente a
+-- Pagado en la misma transaccion
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE Pago
+SET estado = 'Capturado',
+    fechaActualizacion = SYSUTCDATETIME()
+WHERE idPago = @id AND estado = 'Autorizado';
+
+IF @@ROWCOUNT = 0
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50030, 'el pago ya no esta en el estado esperado', 1;
+END;
+
+DECLARE @orderId INT = (SELECT idPedido FROM Pago WHERE idPago = @id);
+
+UPDATE Pedido
+SET estado = 'Pagado'
+WHERE idPedido = @orderId AND estado = 'Pendiente';
+
+IF @@ROWCOUNT = 0
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50010, 'el pedido ya no esta en el estado esperado', 1;
+END;
+
+INSERT INTO PedidoHistorial (idPedido, estadoAnterior, estadoNuevo, actor)
+VALUES (@orderId, 'Pendiente', 'Pagado', @actor);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,9 @@
+-- Cambiar el estado de un pago; solo aplica si el pago sigue en el estado esperado
+UPDATE Pago
+SET estado = @to,
+    montoReembolsado = @refunded,
+    fechaActualizacion = SYSUTCDATETIME()
+WHERE idPago = @id AND estado = @from;
+
+IF @@ROWCOUNT = 0
+    THROW 50030, 'el pago ya no esta en el estado esperado', 1;
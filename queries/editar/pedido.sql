@@ -1,5 +1,4 @@
--- Cambiar estado de entrega o usuario del pedido
+-- Cambiar el usuario del pedido (el estado solo cambia con editar/pedido_estado.sql)
 UPDATE Pedido
-SET idUsuario = @userId,
-    entregado = @delivered
+SET idUsuario = @userId
 WHERE idPedido = @id;
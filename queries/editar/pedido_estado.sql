@@ -0,0 +1,19 @@
+-- Transicion de estado del pedido; solo aplica si el pedido sigue en el estado esperado
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE Pedido
+SET estado = @to,
+    fechaEntrega = CASE WHEN @to = 'Entregado' THEN SYSUTCDATETIME() ELSE fechaEntrega END
+WHERE idPedido = @id AND estado = @from;
+
+IF @@ROWCOUNT = 0
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50010, 'el pedido ya no esta en el estado esperado', 1;
+END;
+
+INSERT INTO PedidoHistorial (idPedido, estadoAnterior, estadoNuevo, actor)
+VALUES (@id, @from, @to, @actor);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,5 @@
+-- Renombrar o reordenar un atributo de producto
+UPDATE ProductoAtributo
+SET nombre = @name,
+    orden = @order
+WHERE idAtributo = @id;
@@ -0,0 +1,10 @@
+-- Recalcular desde cero la calificacion de todos los productos
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+EXEC RecalcularCalificacion;
+
+COMMIT TRANSACTION;
+
+SELECT COUNT(*) AS productos FROM ProductoCalificacion;
@@ -0,0 +1,5 @@
+-- Cambiar texto alternativo y posicion de una imagen
+UPDATE ProductoImagen
+SET textoAlternativo = @altText,
+    orden = @order
+WHERE idImagen = @id;
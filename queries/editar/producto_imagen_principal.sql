@@ -0,0 +1,16 @@
+-- Marcar una imagen como la principal de su producto (desmarca la anterior)
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @productId INT;
+SELECT @productId = idProducto FROM ProductoImagen WHERE idImagen = @id;
+
+UPDATE ProductoImagen
+SET principal = 0
+WHERE idProducto = @productId AND principal = 1 AND idImagen <> @id;
+
+UPDATE ProductoImagen
+SET principal = 1
+WHERE idImagen = @id;
+
+COMMIT TRANSACTION;
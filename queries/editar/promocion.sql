@@ -0,0 +1,16 @@
+-- Actualizar promocion
+UPDATE Promocion
+SET nombre = @name,
+    tipo = @type,
+    prioridad = @priority,
+    acumulable = @stackable,
+    idSKU = @skuId,
+    idCategoria = @categoryId,
+    cantidadCompra = @buyQuantity,
+    cantidadGratis = @freeQuantity,
+    porcentaje = @percentage,
+    precioEspecial = @specialPrice,
+    vigenteDesde = @validFrom,
+    vigenteHasta = @validUntil,
+    activo = @active
+WHERE idPromocion = @id;
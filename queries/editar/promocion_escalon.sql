@@ -0,0 +1,17 @@
+-- Reemplazar los escalones de una promocion.
+-- @tiers es un arreglo JSON: [{"montoMinimo":500.00,"porcentaje":2.00}, ...]
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DELETE FROM PromocionEscalon
+WHERE idPromocion = @id;
+
+INSERT INTO PromocionEscalon (idPromocion, montoMinimo, porcentaje)
+SELECT @id, t.montoMinimo, t.porcentaje
+FROM OPENJSON(@tiers)
+WITH (
+    montoMinimo DECIMAL(10,2) '$.montoMinimo',
+    porcentaje DECIMAL(5,2) '$.porcentaje'
+) t;
+
+COMMIT TRANSACTION;
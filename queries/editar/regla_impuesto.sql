@@ -0,0 +1,8 @@
+-- Actualizar regla de impuesto
+UPDATE ReglaImpuesto
+SET nombre = @name,
+    tasa = @rate,
+    idCategoria = @categoryId,
+    region = @region,
+    activo = @active
+WHERE idRegla = @id;
@@ -1,7 +1,32 @@
--- Editar reseña de un producto
+-- Editar reseña de un producto; el contenido nuevo vuelve a moderacion, asi
+-- que deja de contar en la calificacion del producto (anterior y nuevo)
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @oldProductId INT = (SELECT idProducto FROM [Reseña] WITH (UPDLOCK) WHERE idReseña = @id);
+
+IF EXISTS (
+    SELECT 1 FROM [Reseña] WITH (UPDLOCK, HOLDLOCK)
+    WHERE idUsuario = @userId AND idProducto = @productId AND idReseña <> @id
+)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50100, 'el cliente ya reseño este producto', 1;
+END;
+
 UPDATE [Reseña]
 SET idUsuario = @userId,
     idProducto = @productId,
     [puntuación] = @rating,
-    comentario = @comment
+    comentario = @comment,
+    estado = 'Pendiente',
+    motivoRechazo = NULL,
+    moderador = NULL,
+    fechaModeracion = NULL
 WHERE idReseña = @id;
+
+EXEC RecalcularCalificacion @productId = @productId;
+IF @oldProductId <> @productId
+    EXEC RecalcularCalificacion @productId = @oldProductId;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,17 @@
+-- Moderar una reseña: aprobarla o rechazarla con un motivo, y recalcular la
+-- calificacion del producto
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE [Reseña]
+SET estado = @status,
+    motivoRechazo = @reason,
+    moderador = @moderator,
+    fechaModeracion = SYSUTCDATETIME()
+WHERE idReseña = @id;
+
+DECLARE @productId INT = (SELECT idProducto FROM [Reseña] WHERE idReseña = @id);
+IF @productId IS NOT NULL
+    EXEC RecalcularCalificacion @productId = @productId;
+
+COMMIT TRANSACTION;
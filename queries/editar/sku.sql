@@ -1,6 +1,41 @@
--- Ajustar datos del SKU (precio y stock)
+-- Ajustar datos del SKU (el stock se maneja por almacen). Si cambia de
+-- producto se quitan los valores de atributos que eran del producto anterior.
+-- Un precio distinto rige desde ahora y queda en el historial hasta el
+-- siguiente cambio programado.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @now DATETIME2 = SYSUTCDATETIME();
+IF EXISTS (SELECT 1 FROM SKU WITH (UPDLOCK) WHERE idSKU = @id AND precio <> @price)
+BEGIN
+    DECLARE @end DATETIME2 = (
+        SELECT MIN(vigenteDesde) FROM SKUPrecio WHERE idSKU = @id AND vigenteDesde > @now
+    );
+
+    UPDATE SKUPrecio
+    SET vigenteHasta = @now
+    WHERE idSKU = @id
+      AND vigenteDesde < @now
+      AND (vigenteHasta IS NULL OR vigenteHasta > @now);
+
+    INSERT INTO SKUPrecio (idSKU, precio, vigenteDesde, vigenteHasta)
+    VALUES (@id, @price, @now, @end);
+END;
+
 UPDATE SKU
 SET idProducto = @productId,
     precio = @price,
-    stock = @stock
+    codigo = @code,
+    codigoBarras = @barcode
 WHERE idSKU = @id;
+
+UPDATE ListaDeseosItem
+SET idProducto = @productId
+WHERE idSKU = @id;
+
+DELETE v
+FROM SKUAtributo v
+INNER JOIN ProductoAtributo a ON a.idAtributo = v.idAtributo
+WHERE v.idSKU = @id AND a.idProducto <> @productId;
+
+COMMIT TRANSACTION;
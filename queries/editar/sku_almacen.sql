@@ -0,0 +1,17 @@
+-- Fijar las existencias de un SKU en un almacen y recalcular el total del SKU
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE SKUAlmacen
+SET stock = @stock
+WHERE idSKU = @skuId AND idAlmacen = @warehouseId;
+
+IF @@ROWCOUNT = 0
+    INSERT INTO SKUAlmacen (idSKU, idAlmacen, stock)
+    VALUES (@skuId, @warehouseId, @stock);
+
+UPDATE SKU
+SET stock = (SELECT ISNULL(SUM(stock), 0) FROM SKUAlmacen WHERE idSKU = @skuId)
+WHERE idSKU = @skuId;
+
+COMMIT TRANSACTION;
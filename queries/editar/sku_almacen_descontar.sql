@@ -0,0 +1,15 @@
+-- Despachar @quantity unidades de un SKU: asigna almacenes y descuenta el
+-- stock de todos en una sola transaccion; si falta stock no se toca nada
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @plan TABLE (idAlmacen INT PRIMARY KEY, prioridad INT NOT NULL, cantidad INT NOT NULL);
+INSERT INTO @plan (idAlmacen, prioridad, cantidad)
+EXEC AsignarStockSKU @skuId = @skuId, @quantity = @quantity, @descontar = 1;
+
+COMMIT TRANSACTION;
+
+SELECT idAlmacen, cantidad
+FROM @plan
+ORDER BY prioridad, idAlmacen;
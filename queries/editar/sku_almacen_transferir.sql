@@ -0,0 +1,23 @@
+-- Mover existencias de un SKU entre almacenes (el total del SKU no cambia)
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE SKUAlmacen
+SET stock = stock - @quantity
+WHERE idSKU = @skuId AND idAlmacen = @fromId AND stock >= @quantity;
+
+IF @@ROWCOUNT = 0
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50001, 'stock insuficiente en el almacen de origen', 1;
+END;
+
+UPDATE SKUAlmacen
+SET stock = stock + @quantity
+WHERE idSKU = @skuId AND idAlmacen = @toId;
+
+IF @@ROWCOUNT = 0
+    INSERT INTO SKUAlmacen (idSKU, idAlmacen, stock)
+    VALUES (@skuId, @toId, @quantity);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,23 @@
+-- Fijar el valor de un atributo en un SKU; el atributo debe ser del producto del SKU
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+IF NOT EXISTS (
+    SELECT 1
+    FROM SKU s
+    INNER JOIN ProductoAtributo a ON a.idProducto = s.idProducto
+    WHERE s.idSKU = @skuId AND a.idAtributo = @attributeId)
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50070, 'el atributo no pertenece al producto del SKU', 1;
+END;
+
+MERGE SKUAtributo AS destino
+USING (SELECT @skuId AS idSKU, @attributeId AS idAtributo) AS origen
+ON destino.idSKU = origen.idSKU AND destino.idAtributo = origen.idAtributo
+WHEN MATCHED THEN
+    UPDATE SET valor = @value
+WHEN NOT MATCHED THEN
+    INSERT (idSKU, idAtributo, valor) VALUES (origen.idSKU, origen.idAtributo, @value);
+
+COMMIT TRANSACTION;
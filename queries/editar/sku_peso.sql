@@ -0,0 +1,4 @@
+-- Ajustar peso por unidad de un SKU
+UPDATE SKU
+SET peso = @weight
+WHERE idSKU = @id;
@@ -0,0 +1,12 @@
+-- Pasar a SKU.precio los precios programados que ya entraron en vigencia
+SET NOCOUNT ON;
+
+UPDATE s
+SET precio = p.precio
+FROM SKU s
+INNER JOIN SKUPrecio p ON p.idSKU = s.idSKU
+WHERE p.vigenteDesde <= SYSUTCDATETIME()
+  AND (p.vigenteHasta IS NULL OR p.vigenteHasta > SYSUTCDATETIME())
+  AND s.precio <> p.precio;
+
+SELECT @@ROWCOUNT AS actualizados;
@@ -0,0 +1,5 @@
+-- Ajustar punto y cantidad de reorden de un SKU
+UPDATE SKU
+SET puntoReorden = @reorderPoint,
+    cantidadReorden = @reorderQuantity
+WHERE idSKU = @id;
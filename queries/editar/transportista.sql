@@ -0,0 +1,6 @@
+-- Actualizar transportista
+UPDATE Transportista
+SET nombre = @name,
+    codigo = @code,
+    activo = @active
+WHERE idTransportista = @id;
@@ -0,0 +1,8 @@
+-- Asignar una region a una zona (si ya tenia zona, se mueve)
+MERGE ZonaEnvioRegion AS destino
+USING (SELECT @region AS region, @zoneId AS idZona) AS origen
+ON destino.region = origen.region
+WHEN MATCHED THEN
+    UPDATE SET idZona = origen.idZona
+WHEN NOT MATCHED THEN
+    INSERT (region, idZona) VALUES (origen.region, origen.idZona);
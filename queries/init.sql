@@ -40,6 +40,33 @@ USE Tienda
 GO
 
 -- Dropeamos las tablas que ya existen
+DROP TABLE IF EXISTS CarritoRecordatorio
+DROP TABLE IF EXISTS ListaDeseosItem
+DROP TABLE IF EXISTS ListaDeseos
+DROP TABLE IF EXISTS ProductoImagen
+DROP TABLE IF EXISTS SKUPrecio
+DROP TABLE IF EXISTS ProductoCalificacion
+DROP TABLE IF EXISTS SKUAtributo
+DROP TABLE IF EXISTS ProductoAtributo
+DROP TABLE IF EXISTS PedidoPromocion
+DROP TABLE IF EXISTS PromocionEscalon
+DROP TABLE IF EXISTS Promocion
+DROP TABLE IF EXISTS CuponUso
+DROP TABLE IF EXISTS Cupon
+DROP TABLE IF EXISTS Envio
+DROP TABLE IF EXISTS TarifaEnvio
+DROP TABLE IF EXISTS PedidoImpuesto
+DROP TABLE IF EXISTS ReglaImpuesto
+DROP TABLE IF EXISTS FacturaDetalle
+DROP TABLE IF EXISTS Factura
+DROP TABLE IF EXISTS FacturaSerie
+DROP TABLE IF EXISTS Pago
+DROP TABLE IF EXISTS DevolucionDetalle
+DROP TABLE IF EXISTS DevolucionHistorial
+DROP TABLE IF EXISTS PedidoDetalle
+DROP TABLE IF EXISTS PedidoHistorial
+DROP TABLE IF EXISTS SKUAlmacen
+DROP TABLE IF EXISTS Almacen
 DROP TABLE IF EXISTS Clientes
 DROP TABLE IF EXISTS Carrito
 DROP TABLE IF EXISTS Categoria
@@ -50,6 +77,11 @@ DROP TABLE IF EXISTS Reseña
 DROP TABLE IF EXISTS Direccion
 DROP TABLE IF EXISTS Pedido
 DROP TABLE IF EXISTS Devolucion
+-- Referenciadas por Pedido, van despues
+DROP TABLE IF EXISTS ZonaEnvioRegion
+DROP TABLE IF EXISTS ZonaEnvio
+DROP TABLE IF EXISTS MetodoEnvio
+DROP TABLE IF EXISTS Transportista
 
 CREATE TABLE Clientes
 (
@@ -67,21 +99,54 @@ CREATE TABLE Clientes
     -- Usaremos hashing y salting para crear las contraseñas, esto se hará desde golang
     passwordHash BINARY(32) NOT NULL,
     passwordSalt BINARY(32) NOT NULL,
+
+    -- Inicio de sesion: intentos fallidos seguidos, bloqueo temporal al pasar
+    -- el limite y ultimo acceso correcto (UTC)
+    intentosFallidos INT NOT NULL DEFAULT 0,
+    bloqueadoHasta DATETIME2 NULL,
+    ultimoAcceso DATETIME2 NULL,
 )
 
+-- El correo y el telefono sirven para iniciar sesion, no se pueden repetir
+CREATE UNIQUE INDEX UX_Clientes_Correo ON Clientes (correo) WHERE correo IS NOT NULL;
+CREATE UNIQUE INDEX UX_Clientes_Telefono ON Clientes (telefono);
+
 CREATE TABLE Carrito
 (
     idCarrito INT IDENTITY(1,1) PRIMARY KEY,
-    idUsuario INT UNIQUE,  -- un carrito por cliente
+    -- Un carrito por cliente; NULL en los carritos de invitado
+    idUsuario INT NULL,
+    -- Cupon aplicado; se valida de nuevo al cotizar y al crear el pedido
+    codigoCupon VARCHAR(30) NULL,
+    -- Token opaco que identifica al carrito de invitado; NULL en los de clientes
+    token CHAR(64) NULL,
+    -- Ultimo cambio; los carritos de invitado sin cambios recientes se borran
+    fechaActualizacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    CONSTRAINT CK_Carrito_UsuarioOToken CHECK (
+        (idUsuario IS NOT NULL AND token IS NULL) OR (idUsuario IS NULL AND token IS NOT NULL)),
+
     FOREIGN KEY (idUsuario) REFERENCES Clientes(idUsuario)
     -- Si se elimina el usuario, se elimina el carrito
         ON DELETE CASCADE
 );
 
+CREATE UNIQUE INDEX UX_Carrito_Usuario ON Carrito (idUsuario) WHERE idUsuario IS NOT NULL;
+CREATE UNIQUE INDEX UX_Carrito_Token ON Carrito (token) WHERE token IS NOT NULL;
+
 CREATE TABLE Categoria
 (
     idCategoria INT IDENTITY(1,1) PRIMARY KEY,
-    nombre VARCHAR(50) NOT NULL UNIQUE
+    nombre VARCHAR(50) NOT NULL,
+    -- NULL = categoria raiz
+    idPadre INT NULL,
+
+    -- El nombre se repite en ramas distintas (Hombre > Camisas, Mujer > Camisas)
+    CONSTRAINT UQ_Categoria_Nombre UNIQUE (idPadre, nombre),
+
+    -- Sin cascada (SQL Server no la permite en una auto referencia);
+    -- remover/categoria.sql sube los hijos al padre antes de borrar
+    FOREIGN KEY (idPadre) REFERENCES Categoria(idCategoria)
 );
 
 CREATE TABLE Producto
@@ -100,27 +165,164 @@ CREATE TABLE SKU
     idSKU INT IDENTITY(1,1) PRIMARY KEY,
     idProducto INT NOT NULL,
     precio DECIMAL(10,2) NOT NULL CHECK (precio > 0),
-    stock INT NOT NULL DEFAULT 0 CHECK (stock > 0),
+    -- Total agregado de todos los almacenes (se mantiene desde SKUAlmacen)
+    stock INT NOT NULL DEFAULT 0 CHECK (stock >= 0),
+
+    -- Cuando el stock baja a este nivel se alerta para reabastecer (0 = sin alerta)
+    puntoReorden INT NOT NULL DEFAULT 0 CHECK (puntoReorden >= 0),
+    cantidadReorden INT NOT NULL DEFAULT 0 CHECK (cantidadReorden >= 0),
+
+    -- Peso de una unidad en kg, para calcular el envio
+    peso DECIMAL(10,3) NOT NULL DEFAULT 0 CHECK (peso >= 0),
+
+    -- Codigo del comercio (en mayusculas) y codigo de barras EAN-8, UPC-A o EAN-13
+    codigo VARCHAR(40) NOT NULL UNIQUE,
+    codigoBarras VARCHAR(13) NULL,
 
     FOREIGN KEY (idProducto) REFERENCES Producto(idProducto)
     -- Si se borra el producto, tambien todos los productos minimos vnedibles
         ON DELETE CASCADE
 );
 
+-- El codigo de barras es opcional, pero no se puede repetir entre SKUs
+CREATE UNIQUE INDEX UX_SKU_codigoBarras ON SKU (codigoBarras) WHERE codigoBarras IS NOT NULL;
+
+-- Historial de precios del SKU. Los periodos de un SKU no se superponen: cada
+-- uno termina donde empieza el siguiente y el ultimo queda abierto (NULL).
+-- Los que empiezan en el futuro son cambios programados; SKU.precio guarda el
+-- precio del periodo vigente y se actualiza al llegar su fecha.
+CREATE TABLE SKUPrecio
+(
+    idPrecio INT IDENTITY(1,1) PRIMARY KEY,
+    idSKU INT NOT NULL,
+    precio DECIMAL(10,2) NOT NULL CHECK (precio > 0),
+    -- En UTC, igual que el resto de las fechas
+    vigenteDesde DATETIME2 NOT NULL,
+    vigenteHasta DATETIME2 NULL,
+    fechaCreacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    CONSTRAINT UQ_SKUPrecio UNIQUE (idSKU, vigenteDesde),
+    CHECK (vigenteHasta IS NULL OR vigenteHasta > vigenteDesde),
+
+    FOREIGN KEY (idSKU) REFERENCES SKU(idSKU)
+        ON DELETE CASCADE
+);
+
+-- Opciones que distinguen los SKUs de un producto (talla, color, ...)
+CREATE TABLE ProductoAtributo
+(
+    idAtributo INT IDENTITY(1,1) PRIMARY KEY,
+    idProducto INT NOT NULL,
+    nombre VARCHAR(30) NOT NULL,
+    -- Orden en que se muestran en la etiqueta de la variante
+    orden INT NOT NULL DEFAULT 0,
+
+    CONSTRAINT UQ_ProductoAtributo UNIQUE (idProducto, nombre),
+
+    FOREIGN KEY (idProducto) REFERENCES Producto(idProducto)
+        ON DELETE CASCADE
+);
+
+-- Valor de cada atributo del producto para un SKU
+CREATE TABLE SKUAtributo
+(
+    idSKU INT NOT NULL,
+    idAtributo INT NOT NULL,
+    valor VARCHAR(50) NOT NULL,
+
+    CONSTRAINT PK_SKUAtributo PRIMARY KEY (idSKU, idAtributo),
+
+    FOREIGN KEY (idSKU) REFERENCES SKU(idSKU)
+        ON DELETE CASCADE,
+
+    -- Sin cascada: Producto ya llega aqui por SKU (multiples rutas de cascada).
+    -- remover/producto_atributo.sql borra los valores antes que el atributo.
+    FOREIGN KEY (idAtributo) REFERENCES ProductoAtributo(idAtributo)
+);
+
 CREATE TABLE CarritoDetalle
 (
     idDetalle INT IDENTITY(1,1) PRIMARY KEY,
     idCarrito INT NOT NULL,
-    idSKU INT NOT NULL,
+    -- NULL si el SKU se borro: la linea queda para avisarle al cliente
+    idSKU INT NULL,
     cantidad INT NOT NULL CHECK (cantidad > 0),
+    fechaActualizacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
 
     FOREIGN KEY (idCarrito) REFERENCES Carrito(idCarrito)
         ON DELETE CASCADE,
 
     FOREIGN KEY (idSKU) REFERENCES SKU(idSKU)
+        ON DELETE SET NULL
+);
+
+-- Una linea por SKU en cada carrito: agregar el mismo SKU suma la cantidad
+CREATE UNIQUE INDEX UX_CarritoDetalle_SKU ON CarritoDetalle (idCarrito, idSKU) WHERE idSKU IS NOT NULL;
+
+-- Recordatorios enviados por carritos abandonados. Uno por cada periodo de
+-- inactividad del carrito (fechaCarrito es su fechaActualizacion al enviarlo).
+CREATE TABLE CarritoRecordatorio
+(
+    idRecordatorio INT IDENTITY(1,1) PRIMARY KEY,
+    idCarrito INT NOT NULL,
+    fechaCarrito DATETIME2 NOT NULL,
+    fechaEnvio DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+    -- Valor del carrito al enviar el recordatorio
+    importe DECIMAL(10,2) NOT NULL,
+    -- Pedido que hizo el cliente despues del recordatorio. Sin FK: Pedido y
+    -- Carrito ya llegan en cascada desde Clientes
+    idPedido INT NULL,
+
+    CONSTRAINT UQ_CarritoRecordatorio UNIQUE (idCarrito, fechaCarrito),
+
+    FOREIGN KEY (idCarrito) REFERENCES Carrito(idCarrito)
+        ON DELETE CASCADE
+);
+
+-- Listas de deseos de un cliente, para guardar articulos para despues
+CREATE TABLE ListaDeseos
+(
+    idLista INT IDENTITY(1,1) PRIMARY KEY,
+    idUsuario INT NOT NULL,
+    nombre VARCHAR(50) NOT NULL,
+    fechaCreacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    CONSTRAINT UQ_ListaDeseos UNIQUE (idUsuario, nombre),
+
+    FOREIGN KEY (idUsuario) REFERENCES Clientes(idUsuario)
         ON DELETE CASCADE
 );
 
+-- Un producto (cualquier variante) o un SKU puntual dentro de una lista.
+-- idProducto siempre se llena, tambien cuando hay idSKU.
+CREATE TABLE ListaDeseosItem
+(
+    idItem INT IDENTITY(1,1) PRIMARY KEY,
+    idLista INT NOT NULL,
+    idProducto INT NOT NULL,
+    idSKU INT NULL,
+    fechaAgregado DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    -- Precio (minimo, si es un producto) y stock con los que se avisó por
+    -- ultima vez; se comparan para avisar bajas de precio y reposiciones
+    ultimoPrecio DECIMAL(10,2) NULL,
+    ultimoStock INT NOT NULL DEFAULT 0,
+
+    FOREIGN KEY (idLista) REFERENCES ListaDeseos(idLista)
+        ON DELETE CASCADE,
+
+    FOREIGN KEY (idProducto) REFERENCES Producto(idProducto)
+        ON DELETE CASCADE,
+
+    -- Sin cascada: SKU ya se borra en cascada desde Producto y SQL Server no
+    -- admite dos caminos. remover/sku.sql pasa estos items a nivel producto.
+    FOREIGN KEY (idSKU) REFERENCES SKU(idSKU)
+);
+
+-- Cada producto o SKU aparece una sola vez por lista
+CREATE UNIQUE INDEX UX_ListaDeseosItem_Producto ON ListaDeseosItem (idLista, idProducto) WHERE idSKU IS NULL;
+CREATE UNIQUE INDEX UX_ListaDeseosItem_SKU ON ListaDeseosItem (idLista, idSKU) WHERE idSKU IS NOT NULL;
+
 CREATE TABLE Reseña
 (
     idReseña INT IDENTITY(1,1) PRIMARY KEY,
@@ -128,6 +330,19 @@ CREATE TABLE Reseña
     idProducto INT NOT NULL,
     puntuación TINYINT CHECK (puntuación BETWEEN 1 AND 5),
     comentario VARCHAR(300),
+    fechaCreacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    -- Solo las aprobadas se muestran y cuentan en la calificacion del producto
+    estado VARCHAR(20) NOT NULL DEFAULT 'Pendiente'
+        CHECK (estado IN ('Pendiente','Aprobada','Rechazada')),
+    motivoRechazo VARCHAR(200) NULL,
+    moderador VARCHAR(50) NULL,
+    fechaModeracion DATETIME2 NULL,
+
+    CHECK (estado <> 'Rechazada' OR motivoRechazo IS NOT NULL),
+
+    -- Una reseña por cliente y producto
+    CONSTRAINT UQ_Reseña_Cliente UNIQUE (idUsuario, idProducto),
 
     FOREIGN KEY (idUsuario) REFERENCES Clientes(idUsuario)
         ON DELETE CASCADE,
@@ -136,36 +351,612 @@ CREATE TABLE Reseña
         ON DELETE CASCADE
 );
 
+-- Resumen de las reseñas aprobadas de cada producto. Lo mantienen los scripts
+-- que cambian reseñas; editar/producto_calificacion_reconstruir.sql lo
+-- recalcula desde cero.
+CREATE TABLE ProductoCalificacion
+(
+    idProducto INT PRIMARY KEY,
+    total INT NOT NULL DEFAULT 0 CHECK (total >= 0),
+    -- NULL mientras no tenga reseñas aprobadas
+    promedio DECIMAL(3,2) NULL CHECK (promedio BETWEEN 1 AND 5),
+    estrellas1 INT NOT NULL DEFAULT 0,
+    estrellas2 INT NOT NULL DEFAULT 0,
+    estrellas3 INT NOT NULL DEFAULT 0,
+    estrellas4 INT NOT NULL DEFAULT 0,
+    estrellas5 INT NOT NULL DEFAULT 0,
+    fechaActualizacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    CHECK (total = estrellas1 + estrellas2 + estrellas3 + estrellas4 + estrellas5),
+
+    FOREIGN KEY (idProducto) REFERENCES Producto(idProducto)
+        ON DELETE CASCADE
+);
+
 CREATE TABLE Direccion
 (
     idDirección INT IDENTITY(1,1) PRIMARY KEY,
     idUsuario INT NOT NULL,
     tipo VARCHAR(20) CHECK (tipo IN ('Envío','Facturación')),
     detalle VARCHAR(200),
+    -- Provincia o estado; decide que reglas de impuesto aplican al envio
+    region VARCHAR(50),
 
     FOREIGN KEY (idUsuario) REFERENCES Clientes(idUsuario)
         ON DELETE CASCADE
 );
 
+CREATE TABLE Transportista
+(
+    idTransportista INT IDENTITY(1,1) PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL UNIQUE,
+    -- Implementacion de Carrier que genera las guias (ver models/transportista.go)
+    codigo VARCHAR(30) NOT NULL,
+    activo BIT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE MetodoEnvio
+(
+    idMetodoEnvio INT IDENTITY(1,1) PRIMARY KEY,
+    idTransportista INT NOT NULL,
+    nombre VARCHAR(50) NOT NULL,
+    diasEstimados INT NOT NULL CHECK (diasEstimados > 0),
+    -- Pedidos desde este monto (sin impuesto) no pagan envio; NULL = nunca gratis
+    envioGratisDesde DECIMAL(10,2) NULL CHECK (envioGratisDesde >= 0),
+    activo BIT NOT NULL DEFAULT 1,
+
+    CONSTRAINT UQ_MetodoEnvio UNIQUE (idTransportista, nombre),
+
+    -- Sin cascada: un transportista con metodos no se puede borrar
+    FOREIGN KEY (idTransportista) REFERENCES Transportista(idTransportista)
+);
+
+CREATE TABLE ZonaEnvio
+(
+    idZona INT IDENTITY(1,1) PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL UNIQUE
+);
+
+-- Cada region (Direccion.region) pertenece a lo sumo a una zona
+CREATE TABLE ZonaEnvioRegion
+(
+    region VARCHAR(50) NOT NULL CONSTRAINT PK_ZonaEnvioRegion PRIMARY KEY,
+    idZona INT NOT NULL,
+
+    FOREIGN KEY (idZona) REFERENCES ZonaEnvio(idZona)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE TarifaEnvio
+(
+    idTarifa INT IDENTITY(1,1) PRIMARY KEY,
+    idMetodoEnvio INT NOT NULL,
+    -- NULL = cualquier zona; una tarifa de la zona exacta le gana
+    idZona INT NULL,
+    -- Rangos [min, max) en kg y en monto del pedido; max NULL = sin limite
+    pesoMin DECIMAL(10,3) NOT NULL DEFAULT 0,
+    pesoMax DECIMAL(10,3) NULL,
+    montoMin DECIMAL(10,2) NOT NULL DEFAULT 0,
+    montoMax DECIMAL(10,2) NULL,
+    precio DECIMAL(10,2) NOT NULL CHECK (precio >= 0),
+
+    CHECK (pesoMax IS NULL OR pesoMax > pesoMin),
+    CHECK (montoMax IS NULL OR montoMax > montoMin),
+
+    FOREIGN KEY (idMetodoEnvio) REFERENCES MetodoEnvio(idMetodoEnvio)
+        ON DELETE CASCADE,
+    FOREIGN KEY (idZona) REFERENCES ZonaEnvio(idZona)
+        ON DELETE CASCADE
+);
+
 CREATE TABLE Pedido
 (
     idPedido INT IDENTITY(1,1) PRIMARY KEY,
     idUsuario INT NOT NULL,
-    entregado BIT DEFAULT 0,
+    -- Las transiciones permitidas se validan en models/pedido_estado.go
+    estado VARCHAR(20) NOT NULL DEFAULT 'Pendiente'
+        CHECK (estado IN ('Pendiente','Pagado','Enviado','Entregado','Cancelado','Devuelto')),
+
+    fechaCreacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+    -- Se llena al pasar a Entregado
+    fechaEntrega DATETIME2 NULL,
+
+    idDireccionEnvio INT NULL,
+    idDireccionFacturacion INT NULL,
+
+    -- Montos calculados al crear el pedido, se guardan para no depender de precios futuros
+    subtotal DECIMAL(10,2) NOT NULL DEFAULT 0,
+    descuento DECIMAL(10,2) NOT NULL DEFAULT 0,
+    impuesto DECIMAL(10,2) NOT NULL DEFAULT 0,
+    envio DECIMAL(10,2) NOT NULL DEFAULT 0,
+    total DECIMAL(10,2) NOT NULL DEFAULT 0,
+    -- 1 si los precios ya incluian el impuesto (no se suma al total)
+    preciosConImpuesto BIT NOT NULL DEFAULT 0,
+    idMetodoEnvio INT NULL,
 
     FOREIGN KEY(idUsuario) REFERENCES Clientes(idUsuario)
+        ON DELETE CASCADE,
+
+    -- Sin cascada (SQL Server no permite dos caminos desde Clientes); una direccion
+    -- usada en un pedido no se puede borrar
+    FOREIGN KEY (idDireccionEnvio) REFERENCES Direccion(idDirección),
+    FOREIGN KEY (idDireccionFacturacion) REFERENCES Direccion(idDirección),
+    -- Sin cascada: un metodo usado en pedidos se desactiva, no se borra
+    FOREIGN KEY (idMetodoEnvio) REFERENCES MetodoEnvio(idMetodoEnvio)
+);
+
+CREATE TABLE PedidoDetalle
+(
+    idDetalle INT IDENTITY(1,1) PRIMARY KEY,
+    idPedido INT NOT NULL,
+    idSKU INT NOT NULL,
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    -- Precio del SKU al momento de la compra
+    precioUnitario DECIMAL(10,2) NOT NULL,
+
+    FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
+        ON DELETE CASCADE,
+
+    -- Un SKU ya vendido no se puede borrar
+    FOREIGN KEY (idSKU) REFERENCES SKU(idSKU)
+);
+
+CREATE TABLE PedidoHistorial
+(
+    idHistorial INT IDENTITY(1,1) PRIMARY KEY,
+    idPedido INT NOT NULL,
+    -- NULL en el primer registro (creacion del pedido)
+    estadoAnterior VARCHAR(20),
+    estadoNuevo VARCHAR(20) NOT NULL,
+    fecha DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+    -- Quien hizo el cambio (usuario de consola, sistema, etc.)
+    actor VARCHAR(50) NOT NULL,
+
+    FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
         ON DELETE CASCADE
 );
 
 CREATE TABLE Devolucion
 (
     idDevolucion INT IDENTITY(1,1) PRIMARY KEY,
-    idPedido INT UNIQUE, -- un pedido solo puede tener 1 devolución
+    -- Un pedido puede tener varias devoluciones parciales (ver DevolucionDetalle)
+    idPedido INT NOT NULL,
     fecha DATE NOT NULL,
-    estado VARCHAR(30),
+    -- Las transiciones permitidas se validan en models/devolucion_estado.go
+    estado VARCHAR(30) NOT NULL DEFAULT 'Solicitada'
+        CHECK (estado IN ('Solicitada','Aprobada','Recibida','Reembolsada','Rechazada')),
     descripcion VARCHAR(300),
+    -- Ultima nota de resolucion (el detalle completo queda en DevolucionHistorial)
     resolucion VARCHAR(300),
 
     FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
         ON DELETE CASCADE
 );
+
+CREATE TABLE DevolucionDetalle
+(
+    idDetalle INT IDENTITY(1,1) PRIMARY KEY,
+    idDevolucion INT NOT NULL,
+    -- Linea del pedido que se devuelve
+    idPedidoDetalle INT NOT NULL,
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    -- Calculado al solicitar: parte proporcional de lo cobrado (con descuento e impuesto)
+    montoReembolso DECIMAL(10,2) NOT NULL CHECK (montoReembolso >= 0),
+
+    FOREIGN KEY (idDevolucion) REFERENCES Devolucion(idDevolucion)
+        ON DELETE CASCADE,
+
+    -- Sin cascada: Pedido ya llega aqui por Devolucion
+    FOREIGN KEY (idPedidoDetalle) REFERENCES PedidoDetalle(idDetalle)
+);
+
+CREATE TABLE DevolucionHistorial
+(
+    idHistorial INT IDENTITY(1,1) PRIMARY KEY,
+    idDevolucion INT NOT NULL,
+    estadoAnterior VARCHAR(30),
+    estadoNuevo VARCHAR(30) NOT NULL,
+    fecha DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+    actor VARCHAR(50) NOT NULL,
+    nota VARCHAR(300),
+
+    FOREIGN KEY (idDevolucion) REFERENCES Devolucion(idDevolucion)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE Almacen
+(
+    idAlmacen INT IDENTITY(1,1) PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL UNIQUE,
+    -- Menor numero = se despacha primero desde este almacen
+    prioridad INT NOT NULL DEFAULT 1 CHECK (prioridad > 0),
+    activo BIT NOT NULL DEFAULT 1
+);
+
+CREATE TABLE SKUAlmacen
+(
+    idSKU INT NOT NULL,
+    idAlmacen INT NOT NULL,
+    stock INT NOT NULL DEFAULT 0 CHECK (stock >= 0),
+
+    CONSTRAINT PK_SKUAlmacen PRIMARY KEY (idSKU, idAlmacen),
+
+    FOREIGN KEY (idSKU) REFERENCES SKU(idSKU)
+        ON DELETE CASCADE,
+
+    -- Sin cascada: un almacen con existencias no se puede borrar
+    FOREIGN KEY (idAlmacen) REFERENCES Almacen(idAlmacen)
+);
+
+-- Almacen por defecto, recibe el stock inicial de los SKUs nuevos
+INSERT INTO Almacen (nombre, prioridad) VALUES ('Principal', 1);
+
+CREATE TABLE Pago
+(
+    idPago INT IDENTITY(1,1) PRIMARY KEY,
+    idPedido INT NOT NULL,
+    -- Nombre de la pasarela que proceso el pago (ver models/pasarela_pago.go)
+    pasarela VARCHAR(30) NOT NULL,
+    -- Identificador de la transaccion en la pasarela (NULL si se rechazo sin crearla)
+    referencia VARCHAR(64),
+    monto DECIMAL(10,2) NOT NULL CHECK (monto > 0),
+    montoReembolsado DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (montoReembolsado >= 0),
+    estado VARCHAR(20) NOT NULL
+        CHECK (estado IN ('Autorizado','Capturado','Anulado','Reembolsado','Rechazado')),
+    -- Motivo de rechazo o de la ultima operacion fallida
+    motivo VARCHAR(200),
+    fechaCreacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+    fechaActualizacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    CHECK (montoReembolsado <= monto),
+
+    FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
+        ON DELETE CASCADE
+);
+
+-- Un pedido tiene a lo sumo un pago vivo (autorizado o capturado)
+CREATE UNIQUE INDEX UX_Pago_Vigente ON Pago (idPedido) WHERE estado IN ('Autorizado', 'Capturado');
+
+CREATE TABLE FacturaSerie
+(
+    serie VARCHAR(10) NOT NULL CONSTRAINT PK_FacturaSerie PRIMARY KEY,
+    -- Proximo numero a emitir; se toma y se incrementa en la misma transaccion
+    -- que crea la factura, asi un fallo no deja huecos
+    siguiente INT NOT NULL DEFAULT 1 CHECK (siguiente > 0)
+);
+
+CREATE TABLE Factura
+(
+    idFactura INT IDENTITY(1,1) PRIMARY KEY,
+    serie VARCHAR(10) NOT NULL,
+    numero INT NOT NULL,
+    -- Una factura por pedido; sin cascada, una factura emitida no se borra
+    idPedido INT NOT NULL UNIQUE,
+    fecha DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    -- Copia de los datos al momento de emitir, no cambian si el cliente los edita
+    nombreCliente VARCHAR(50) NOT NULL,
+    direccionFacturacion VARCHAR(200) NOT NULL,
+
+    subtotal DECIMAL(10,2) NOT NULL,
+    descuento DECIMAL(10,2) NOT NULL,
+    impuesto DECIMAL(10,2) NOT NULL,
+    envio DECIMAL(10,2) NOT NULL,
+    total DECIMAL(10,2) NOT NULL,
+
+    CONSTRAINT UQ_Factura_SerieNumero UNIQUE (serie, numero),
+
+    FOREIGN KEY (serie) REFERENCES FacturaSerie(serie),
+    FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
+);
+
+CREATE TABLE FacturaDetalle
+(
+    idDetalle INT IDENTITY(1,1) PRIMARY KEY,
+    idFactura INT NOT NULL,
+    idSKU INT NOT NULL,
+    -- Copia de Producto.descripcion y la variante del SKU al emitir
+    descripcion VARCHAR(200) NOT NULL,
+    cantidad INT NOT NULL CHECK (cantidad > 0),
+    precioUnitario DECIMAL(10,2) NOT NULL,
+    importe DECIMAL(10,2) NOT NULL,
+
+    FOREIGN KEY (idFactura) REFERENCES Factura(idFactura)
+        ON DELETE CASCADE
+);
+
+-- Serie por defecto
+INSERT INTO FacturaSerie (serie) VALUES ('A');
+
+CREATE TABLE ReglaImpuesto
+(
+    idRegla INT IDENTITY(1,1) PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL,
+    -- Fraccion: 0.1300 = 13%
+    tasa DECIMAL(6,4) NOT NULL CHECK (tasa >= 0 AND tasa < 1),
+    -- NULL = cualquier categoria / cualquier region. Gana la regla mas especifica
+    -- (ver models/impuesto.go)
+    idCategoria INT NULL,
+    region VARCHAR(50) NULL,
+    activo BIT NOT NULL DEFAULT 1,
+
+    CONSTRAINT UQ_ReglaImpuesto UNIQUE (idCategoria, region),
+
+    FOREIGN KEY (idCategoria) REFERENCES Categoria(idCategoria)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE PedidoImpuesto
+(
+    idImpuesto INT IDENTITY(1,1) PRIMARY KEY,
+    idPedido INT NOT NULL,
+    -- Copia de la regla al crear el pedido, no cambia si la regla se edita
+    nombre VARCHAR(50) NOT NULL,
+    tasa DECIMAL(6,4) NOT NULL,
+    -- Monto gravado sin impuesto
+    base DECIMAL(10,2) NOT NULL,
+    monto DECIMAL(10,2) NOT NULL,
+
+    FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE Envio
+(
+    idEnvio INT IDENTITY(1,1) PRIMARY KEY,
+    idPedido INT NOT NULL,
+    idMetodoEnvio INT NOT NULL,
+    -- Codigo del Carrier que emitio la guia
+    transportista VARCHAR(30) NOT NULL,
+    numeroSeguimiento VARCHAR(64) NOT NULL UNIQUE,
+    pesoKg DECIMAL(10,3) NOT NULL,
+    fechaCreacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
+        ON DELETE CASCADE,
+    -- Sin cascada: Pedido ya llega aqui
+    FOREIGN KEY (idMetodoEnvio) REFERENCES MetodoEnvio(idMetodoEnvio)
+);
+
+CREATE TABLE Cupon
+(
+    idCupon INT IDENTITY(1,1) PRIMARY KEY,
+    -- Se guarda en mayusculas
+    codigo VARCHAR(30) NOT NULL UNIQUE,
+    tipo VARCHAR(20) NOT NULL CHECK (tipo IN ('Porcentaje','Monto')),
+    valor DECIMAL(10,2) NOT NULL CHECK (valor > 0),
+    -- Subtotal minimo del carrito para poder usarlo
+    montoMinimo DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (montoMinimo >= 0),
+    -- NULL = sin limite
+    usosMaximos INT NULL CHECK (usosMaximos > 0),
+    usosPorCliente INT NULL CHECK (usosPorCliente > 0),
+    vigenteDesde DATETIME2 NULL,
+    vigenteHasta DATETIME2 NULL,
+    -- Si tiene categoria, el descuento solo se calcula sobre esas lineas
+    idCategoria INT NULL,
+    activo BIT NOT NULL DEFAULT 1,
+
+    CHECK (tipo <> 'Porcentaje' OR valor <= 100),
+    CHECK (vigenteHasta IS NULL OR vigenteDesde IS NULL OR vigenteHasta > vigenteDesde),
+
+    FOREIGN KEY (idCategoria) REFERENCES Categoria(idCategoria)
+        ON DELETE SET NULL
+);
+
+CREATE TABLE CuponUso
+(
+    idUso INT IDENTITY(1,1) PRIMARY KEY,
+    idCupon INT NOT NULL,
+    -- Un cupon por pedido
+    idPedido INT NOT NULL UNIQUE,
+    -- Copia de Pedido.idUsuario para contar usos por cliente (sin FK: ya llega por Pedido)
+    idUsuario INT NOT NULL,
+    monto DECIMAL(10,2) NOT NULL,
+    fecha DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    -- Sin cascada: un cupon usado se desactiva, no se borra
+    FOREIGN KEY (idCupon) REFERENCES Cupon(idCupon),
+    FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE Promocion
+(
+    idPromocion INT IDENTITY(1,1) PRIMARY KEY,
+    nombre VARCHAR(50) NOT NULL,
+    -- Los campos que usa cada tipo se validan en models/promocion.go
+    tipo VARCHAR(30) NOT NULL
+        CHECK (tipo IN ('CompraXLlevaY','PorcentajeCategoria','Escalonada','PrecioFlash')),
+    -- Menor numero = se evalua primero
+    prioridad INT NOT NULL DEFAULT 1 CHECK (prioridad > 0),
+    -- 0 = exclusiva: no se combina con otras promociones en la misma linea
+    acumulable BIT NOT NULL DEFAULT 0,
+
+    idSKU INT NULL,
+    idCategoria INT NULL,
+    cantidadCompra INT NULL CHECK (cantidadCompra > 0),
+    cantidadGratis INT NULL CHECK (cantidadGratis > 0),
+    porcentaje DECIMAL(5,2) NULL CHECK (porcentaje > 0 AND porcentaje <= 100),
+    precioEspecial DECIMAL(10,2) NULL CHECK (precioEspecial > 0),
+
+    vigenteDesde DATETIME2 NULL,
+    vigenteHasta DATETIME2 NULL,
+    activo BIT NOT NULL DEFAULT 1,
+
+    CHECK (vigenteHasta IS NULL OR vigenteDesde IS NULL OR vigenteHasta > vigenteDesde),
+
+    FOREIGN KEY (idSKU) REFERENCES SKU(idSKU)
+        ON DELETE CASCADE,
+    -- Sin cascada: Categoria ya llega a SKU por Producto; una categoria con
+    -- promociones no se puede borrar
+    FOREIGN KEY (idCategoria) REFERENCES Categoria(idCategoria)
+);
+
+-- Escalones de una promocion Escalonada: desde montoMinimo del carrito, porcentaje de descuento
+CREATE TABLE PromocionEscalon
+(
+    idPromocion INT NOT NULL,
+    montoMinimo DECIMAL(10,2) NOT NULL CHECK (montoMinimo >= 0),
+    porcentaje DECIMAL(5,2) NOT NULL CHECK (porcentaje > 0 AND porcentaje <= 100),
+
+    CONSTRAINT PK_PromocionEscalon PRIMARY KEY (idPromocion, montoMinimo),
+
+    FOREIGN KEY (idPromocion) REFERENCES Promocion(idPromocion)
+        ON DELETE CASCADE
+);
+
+-- Promociones que se aplicaron a un pedido y a que linea
+CREATE TABLE PedidoPromocion
+(
+    idAplicacion INT IDENTITY(1,1) PRIMARY KEY,
+    idPedido INT NOT NULL,
+    -- Copia de la promocion al crear el pedido (sin FK, la promocion se puede borrar despues)
+    idPromocion INT NOT NULL,
+    nombre VARCHAR(50) NOT NULL,
+    -- NULL = descuento sobre todo el carrito
+    idSKU INT NULL,
+    descuento DECIMAL(10,2) NOT NULL CHECK (descuento >= 0),
+    detalle VARCHAR(200) NOT NULL,
+
+    FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
+        ON DELETE CASCADE
+);
+
+-- Imagenes de producto; los archivos viven en el BlobStore bajo clave y claveMiniatura
+CREATE TABLE ProductoImagen
+(
+    idImagen INT IDENTITY(1,1) PRIMARY KEY,
+    idProducto INT NOT NULL,
+    clave VARCHAR(200) NOT NULL UNIQUE,
+    claveMiniatura VARCHAR(200) NOT NULL UNIQUE,
+    tipoContenido VARCHAR(30) NOT NULL CHECK (tipoContenido IN ('image/jpeg', 'image/png', 'image/gif')),
+    -- Tamaño del original en bytes
+    tamano INT NOT NULL CHECK (tamano > 0),
+    ancho INT NOT NULL CHECK (ancho > 0),
+    alto INT NOT NULL CHECK (alto > 0),
+    textoAlternativo VARCHAR(200) NULL,
+    -- Posicion en la galeria (menor primero)
+    orden INT NOT NULL DEFAULT 0,
+    principal BIT NOT NULL DEFAULT 0,
+    fechaCreacion DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+
+    FOREIGN KEY (idProducto) REFERENCES Producto(idProducto)
+        ON DELETE CASCADE
+);
+
+-- Un producto tiene a lo sumo una imagen principal
+CREATE UNIQUE INDEX UX_ProductoImagen_Principal ON ProductoImagen (idProducto) WHERE principal = 1;
+GO
+
+-- Reparte @quantity unidades de un SKU entre los almacenes activos: primero
+-- busca un solo almacen (por prioridad) que cubra todo para no partir el
+-- envio; si ninguno alcanza, reparte por prioridad. Con @descontar = 1 ademas
+-- descuenta el stock de cada almacen y recalcula SKU.stock; debe llamarse
+-- dentro de una transaccion con XACT_ABORT para que un faltante la deshaga
+-- entera. Devuelve el reparto (idAlmacen, prioridad, cantidad).
+CREATE OR ALTER PROCEDURE AsignarStockSKU
+    @skuId INT,
+    @quantity INT,
+    @descontar BIT
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    DECLARE @stock TABLE (idAlmacen INT PRIMARY KEY, prioridad INT NOT NULL, stock INT NOT NULL);
+    INSERT INTO @stock (idAlmacen, prioridad, stock)
+    SELECT sa.idAlmacen, a.prioridad, sa.stock
+    FROM SKUAlmacen sa WITH (UPDLOCK, HOLDLOCK)
+    INNER JOIN Almacen a ON a.idAlmacen = sa.idAlmacen
+    WHERE sa.idSKU = @skuId AND a.activo = 1;
+
+    DECLARE @plan TABLE (idAlmacen INT PRIMARY KEY, prioridad INT NOT NULL, cantidad INT NOT NULL);
+    DECLARE @unico INT = (
+        SELECT TOP 1 idAlmacen FROM @stock
+        WHERE stock >= @quantity
+        ORDER BY prioridad, idAlmacen);
+
+    IF @unico IS NOT NULL
+        INSERT INTO @plan (idAlmacen, prioridad, cantidad)
+        SELECT idAlmacen, prioridad, @quantity FROM @stock WHERE idAlmacen = @unico;
+    ELSE
+    BEGIN
+        DECLARE @faltan INT = @quantity - ISNULL((SELECT SUM(stock) FROM @stock), 0);
+        IF @faltan > 0
+        BEGIN
+            DECLARE @mensaje NVARCHAR(200) = CONCAT(N'stock insuficiente: faltan ', @faltan, N' unidades');
+            THROW 50002, @mensaje, 1;
+        END;
+
+        INSERT INTO @plan (idAlmacen, prioridad, cantidad)
+        SELECT idAlmacen, prioridad,
+            CASE WHEN acumulado <= @quantity THEN stock ELSE @quantity - (acumulado - stock) END
+        FROM (
+            SELECT idAlmacen, prioridad, stock,
+                SUM(stock) OVER (ORDER BY prioridad, idAlmacen ROWS UNBOUNDED PRECEDING) AS acumulado
+            FROM @stock
+            WHERE stock > 0
+        ) s
+        WHERE acumulado - stock < @quantity;
+    END;
+
+    IF @descontar = 1
+    BEGIN
+        UPDATE sa
+        SET stock = sa.stock - p.cantidad
+        FROM SKUAlmacen sa
+        INNER JOIN @plan p ON p.idAlmacen = sa.idAlmacen
+        WHERE sa.idSKU = @skuId;
+
+        UPDATE SKU
+        SET stock = (SELECT ISNULL(SUM(stock), 0) FROM SKUAlmacen WHERE idSKU = @skuId)
+        WHERE idSKU = @skuId;
+    END;
+
+    SELECT idAlmacen, prioridad, cantidad
+    FROM @plan
+    ORDER BY prioridad, idAlmacen;
+END;
+GO
+
+-- Recalcula ProductoCalificacion a partir de las reseñas aprobadas del
+-- producto @productId; sin producto recalcula todos y borra las filas de
+-- productos que ya no existen.
+CREATE OR ALTER PROCEDURE RecalcularCalificacion
+    @productId INT = NULL
+AS
+BEGIN
+    SET NOCOUNT ON;
+
+    MERGE ProductoCalificacion AS destino
+    USING (
+        SELECT p.idProducto,
+            COUNT(r.[puntuación]) AS total,
+            CAST(AVG(CAST(r.[puntuación] AS DECIMAL(4,2))) AS DECIMAL(3,2)) AS promedio,
+            COUNT(CASE WHEN r.[puntuación] = 1 THEN 1 END) AS estrellas1,
+            COUNT(CASE WHEN r.[puntuación] = 2 THEN 1 END) AS estrellas2,
+            COUNT(CASE WHEN r.[puntuación] = 3 THEN 1 END) AS estrellas3,
+            COUNT(CASE WHEN r.[puntuación] = 4 THEN 1 END) AS estrellas4,
+            COUNT(CASE WHEN r.[puntuación] = 5 THEN 1 END) AS estrellas5
+        FROM Producto p
+        LEFT JOIN [Reseña] r ON r.idProducto = p.idProducto AND r.estado = 'Aprobada'
+        WHERE @productId IS NULL OR p.idProducto = @productId
+        GROUP BY p.idProducto
+    ) AS origen
+    ON destino.idProducto = origen.idProducto
+    WHEN MATCHED THEN
+        UPDATE SET total = origen.total,
+            promedio = origen.promedio,
+            estrellas1 = origen.estrellas1,
+            estrellas2 = origen.estrellas2,
+            estrellas3 = origen.estrellas3,
+            estrellas4 = origen.estrellas4,
+            estrellas5 = origen.estrellas5,
+            fechaActualizacion = SYSUTCDATETIME()
+    WHEN NOT MATCHED THEN
+        INSERT (idProducto, total, promedio, estrellas1, estrellas2, estrellas3, estrellas4, estrellas5)
+        VALUES (origen.idProducto, origen.total, origen.promedio,
+            origen.estrellas1, origen.estrellas2, origen.estrellas3, origen.estrellas4, origen.estrellas5)
+    WHEN NOT MATCHED BY SOURCE AND @productId IS NULL THEN
+        DELETE;
+END;
+GO
@@ -40,23 +40,115 @@ INSERT INTO Categoria (nombre) VALUES ('Ropa');
 SET @catRopa = SCOPE_IDENTITY();
 INSERT INTO Categoria (nombre) VALUES ('Electrónica');
 SET @catElectro = SCOPE_IDENTITY();
+-- Subcategorias: Ropa > Hombre > Camisas
+DECLARE @catHombre INT, @catCamisas INT;
+INSERT INTO Categoria (nombre, idPadre) VALUES ('Hombre', @catRopa);
+SET @catHombre = SCOPE_IDENTITY();
+INSERT INTO Categoria (nombre, idPadre) VALUES ('Camisas', @catHombre);
+SET @catCamisas = SCOPE_IDENTITY();
+
+-- Impuestos: tasa general y una tasa reducida para ropa
+INSERT INTO ReglaImpuesto (nombre, tasa, idCategoria, region) VALUES ('IVA', 0.13, NULL, NULL);
+INSERT INTO ReglaImpuesto (nombre, tasa, idCategoria, region) VALUES ('IVA reducido', 0.04, @catRopa, NULL);
+
+-- Envios: zona metropolitana y tarifas generales para el resto del pais
+DECLARE @transLocal INT, @metEstandar INT, @metExpress INT, @zonaGAM INT;
+INSERT INTO Transportista (nombre, codigo) VALUES ('Mensajería local', 'fake');
+SET @transLocal = SCOPE_IDENTITY();
+INSERT INTO MetodoEnvio (idTransportista, nombre, diasEstimados, envioGratisDesde) VALUES (@transLocal, 'Estándar', 3, 100.00);
+SET @metEstandar = SCOPE_IDENTITY();
+INSERT INTO MetodoEnvio (idTransportista, nombre, diasEstimados, envioGratisDesde) VALUES (@transLocal, 'Express', 1, NULL);
+SET @metExpress = SCOPE_IDENTITY();
+INSERT INTO ZonaEnvio (nombre) VALUES ('GAM');
+SET @zonaGAM = SCOPE_IDENTITY();
+INSERT INTO ZonaEnvioRegion (region, idZona)
+VALUES ('San José', @zonaGAM), ('Heredia', @zonaGAM), ('Alajuela', @zonaGAM), ('Cartago', @zonaGAM);
+INSERT INTO TarifaEnvio (idMetodoEnvio, idZona, pesoMin, pesoMax, precio)
+VALUES
+    (@metEstandar, @zonaGAM, 0, 5, 3.50),
+    (@metEstandar, @zonaGAM, 5, NULL, 6.00),
+    (@metEstandar, NULL, 0, 5, 5.00),
+    (@metEstandar, NULL, 5, NULL, 9.00),
+    (@metExpress, @zonaGAM, 0, NULL, 8.00),
+    (@metExpress, NULL, 0, NULL, 12.00);
+
+-- Cupones
+INSERT INTO Cupon (codigo, tipo, valor, montoMinimo, usosMaximos, usosPorCliente)
+VALUES ('BIENVENIDA10', 'Porcentaje', 10, 0, NULL, 1);
+INSERT INTO Cupon (codigo, tipo, valor, montoMinimo, usosMaximos, usosPorCliente, idCategoria)
+VALUES ('ROPA5', 'Monto', 5, 20, 100, NULL, @catRopa);
 
 -- Productos
 DECLARE @prodCamisa INT, @prodLaptop INT;
-INSERT INTO Producto (descripcion, idCategoria) VALUES ('Camisa de algodón', @catRopa);
+INSERT INTO Producto (descripcion, idCategoria) VALUES ('Camisa de algodón', @catCamisas);
 SET @prodCamisa = SCOPE_IDENTITY();
 INSERT INTO Producto (descripcion, idCategoria) VALUES ('Laptop 14"', @catElectro);
 SET @prodLaptop = SCOPE_IDENTITY();
 
 -- SKUs
 DECLARE @skuCamisaS INT, @skuCamisaM INT, @skuLaptop INT;
-INSERT INTO SKU (idProducto, precio, stock) VALUES (@prodCamisa, 19.99, 20);
+INSERT INTO SKU (idProducto, precio, stock, codigo, codigoBarras) VALUES (@prodCamisa, 19.99, 20, 'CAM-AZ-S', '7501000000012');
 SET @skuCamisaS = SCOPE_IDENTITY();
-INSERT INTO SKU (idProducto, precio, stock) VALUES (@prodCamisa, 21.99, 15);
+INSERT INTO SKU (idProducto, precio, stock, codigo, codigoBarras) VALUES (@prodCamisa, 21.99, 15, 'CAM-AZ-M', '7501000000029');
 SET @skuCamisaM = SCOPE_IDENTITY();
-INSERT INTO SKU (idProducto, precio, stock) VALUES (@prodLaptop, 799.00, 5);
+INSERT INTO SKU (idProducto, precio, stock, codigo, codigoBarras) VALUES (@prodLaptop, 799.00, 5, 'LAP-14-16GB', '7501000000036');
 SET @skuLaptop = SCOPE_IDENTITY();
 
+-- Precio inicial de cada SKU y una rebaja programada de la laptop
+INSERT INTO SKUPrecio (idSKU, precio, vigenteDesde)
+SELECT idSKU, precio, SYSUTCDATETIME() FROM SKU;
+UPDATE SKUPrecio SET vigenteHasta = DATEADD(DAY, 7, vigenteDesde) WHERE idSKU = @skuLaptop;
+INSERT INTO SKUPrecio (idSKU, precio, vigenteDesde)
+SELECT @skuLaptop, 749.00, vigenteHasta FROM SKUPrecio WHERE idSKU = @skuLaptop;
+
+-- Atributos de variante y sus valores por SKU
+DECLARE @atrTalla INT, @atrColor INT, @atrMemoria INT;
+INSERT INTO ProductoAtributo (idProducto, nombre, orden) VALUES (@prodCamisa, 'Talla', 1);
+SET @atrTalla = SCOPE_IDENTITY();
+INSERT INTO ProductoAtributo (idProducto, nombre, orden) VALUES (@prodCamisa, 'Color', 2);
+SET @atrColor = SCOPE_IDENTITY();
+INSERT INTO ProductoAtributo (idProducto, nombre, orden) VALUES (@prodLaptop, 'Memoria', 1);
+SET @atrMemoria = SCOPE_IDENTITY();
+INSERT INTO SKUAtributo (idSKU, idAtributo, valor)
+VALUES
+    (@skuCamisaS, @atrTalla, 'S'), (@skuCamisaS, @atrColor, 'Azul'),
+    (@skuCamisaM, @atrTalla, 'M'), (@skuCamisaM, @atrColor, 'Azul'),
+    (@skuLaptop, @atrMemoria, '16 GB');
+
+-- Pesos en kg
+UPDATE SKU SET peso = 0.25 WHERE idSKU IN (@skuCamisaS, @skuCamisaM);
+UPDATE SKU SET peso = 1.8 WHERE idSKU = @skuLaptop;
+
+-- Promociones: 3x2 en camisas S, 5% en electronica y descuento escalonado por total
+DECLARE @promoEscalon INT;
+INSERT INTO Promocion (nombre, tipo, prioridad, acumulable, idSKU, cantidadCompra, cantidadGratis)
+VALUES ('3x2 camisas', 'CompraXLlevaY', 1, 0, @skuCamisaS, 2, 1);
+INSERT INTO Promocion (nombre, tipo, prioridad, acumulable, idCategoria, porcentaje)
+VALUES ('Semana de la electrónica', 'PorcentajeCategoria', 2, 0, @catElectro, 5);
+INSERT INTO Promocion (nombre, tipo, prioridad, acumulable)
+VALUES ('Descuento por volumen', 'Escalonada', 10, 1);
+SET @promoEscalon = SCOPE_IDENTITY();
+INSERT INTO PromocionEscalon (idPromocion, montoMinimo, porcentaje)
+VALUES (@promoEscalon, 500, 2), (@promoEscalon, 1000, 5);
+
+-- Puntos de reorden
+UPDATE SKU SET puntoReorden = 5, cantidadReorden = 20 WHERE idSKU IN (@skuCamisaS, @skuCamisaM);
+UPDATE SKU SET puntoReorden = 2, cantidadReorden = 5 WHERE idSKU = @skuLaptop;
+
+-- Existencias por almacen (Principal viene de init.sql)
+DECLARE @almPrincipal INT, @almSecundario INT;
+SELECT @almPrincipal = idAlmacen FROM Almacen WHERE nombre = 'Principal';
+INSERT INTO Almacen (nombre, prioridad) VALUES ('Secundario', 2);
+SET @almSecundario = SCOPE_IDENTITY();
+
+INSERT INTO SKUAlmacen (idSKU, idAlmacen, stock)
+VALUES
+    (@skuCamisaS, @almPrincipal, 12),
+    (@skuCamisaS, @almSecundario, 8),
+    (@skuCamisaM, @almPrincipal, 15),
+    (@skuLaptop, @almPrincipal, 2),
+    (@skuLaptop, @almSecundario, 3);
+
 -- Detalles de carrito
 INSERT INTO CarritoDetalle (idCarrito, idSKU, cantidad)
 VALUES
@@ -64,28 +156,65 @@ VALUES
     (@carrito1, @skuLaptop, 1),
     (@carrito2, @skuCamisaM, 1);
 
+-- Direcciones
+DECLARE @dirEnvio1 INT, @dirFact1 INT, @dirEnvio2 INT;
+INSERT INTO Direccion (idUsuario, tipo, detalle, region) VALUES (@cliente1, 'Envío', 'Calle 1 #123', 'San José');
+SET @dirEnvio1 = SCOPE_IDENTITY();
+INSERT INTO Direccion (idUsuario, tipo, detalle, region) VALUES (@cliente1, 'Facturación', 'Calle 1 #123', 'San José');
+SET @dirFact1 = SCOPE_IDENTITY();
+INSERT INTO Direccion (idUsuario, tipo, detalle, region) VALUES (@cliente2, 'Envío', 'Av. Central 456', 'Heredia');
+SET @dirEnvio2 = SCOPE_IDENTITY();
+INSERT INTO Direccion (idUsuario, tipo, detalle, region) VALUES (@cliente3, 'Envío', 'Boulevard Norte 789', 'Alajuela');
+
 -- Pedidos
 DECLARE @pedido1 INT, @pedido2 INT;
-INSERT INTO Pedido (idUsuario, entregado) VALUES (@cliente1, 0);
+INSERT INTO Pedido (idUsuario, estado, fechaCreacion, idDireccionEnvio, idDireccionFacturacion,
+    subtotal, descuento, impuesto, envio, total)
+VALUES (@cliente1, 'Pendiente', '2024-02-01T10:00:00', @dirEnvio1, @dirFact1, 19.99, 0, 0, 0, 19.99);
 SET @pedido1 = SCOPE_IDENTITY();
-INSERT INTO Pedido (idUsuario, entregado) VALUES (@cliente2, 1);
+INSERT INTO Pedido (idUsuario, estado, fechaCreacion, fechaEntrega, idDireccionEnvio, idDireccionFacturacion,
+    subtotal, descuento, impuesto, envio, total)
+VALUES (@cliente2, 'Entregado', '2024-01-05T09:30:00', '2024-01-10T16:00:00', @dirEnvio2, NULL, 799.00, 0, 0, 0, 799.00);
 SET @pedido2 = SCOPE_IDENTITY();
 
+INSERT INTO PedidoDetalle (idPedido, idSKU, cantidad, precioUnitario)
+VALUES
+    (@pedido1, @skuCamisaS, 1, 19.99),
+    (@pedido2, @skuLaptop, 1, 799.00);
+
+INSERT INTO PedidoHistorial (idPedido, estadoAnterior, estadoNuevo, actor)
+VALUES
+    (@pedido1, NULL, 'Pendiente', 'datos de prueba'),
+    (@pedido2, NULL, 'Pendiente', 'datos de prueba'),
+    (@pedido2, 'Pendiente', 'Pagado', 'datos de prueba'),
+    (@pedido2, 'Pagado', 'Enviado', 'datos de prueba'),
+    (@pedido2, 'Enviado', 'Entregado', 'datos de prueba');
+
+-- Pagos (el pedido entregado ya fue cobrado)
+INSERT INTO Pago (idPedido, pasarela, referencia, monto, estado, fechaCreacion, fechaActualizacion)
+VALUES (@pedido2, 'fake', 'fake_seed_0001', 799.00, 'Capturado', '2024-01-05T09:31:00', '2024-01-05T09:35:00');
+
 -- Devoluciones (solo sobre el pedido entregado)
+DECLARE @devolucion1 INT;
 INSERT INTO Devolucion (idPedido, fecha, estado, descripcion, resolucion)
-VALUES (@pedido2, '2024-01-15', 'Pendiente', 'Teclado defectuoso', NULL);
+VALUES (@pedido2, '2024-01-15', 'Solicitada', 'Teclado defectuoso', NULL);
+SET @devolucion1 = SCOPE_IDENTITY();
 
--- Reseñas
-INSERT INTO [Reseña] (idUsuario, idProducto, [puntuación], comentario)
-VALUES
-    (@cliente1, @prodCamisa, 5, 'Muy cómoda'),
-    (@cliente2, @prodLaptop, 3, 'Buena pero con ruido'),
-    (@cliente3, @prodCamisa, 4, 'Color agradable');
+INSERT INTO DevolucionDetalle (idDevolucion, idPedidoDetalle, cantidad, montoReembolso)
+SELECT @devolucion1, idDetalle, 1, 799.00
+FROM PedidoDetalle
+WHERE idPedido = @pedido2 AND idSKU = @skuLaptop;
 
--- Direcciones
-INSERT INTO Direccion (idUsuario, tipo, detalle)
+INSERT INTO DevolucionHistorial (idDevolucion, estadoAnterior, estadoNuevo, fecha, actor, nota)
+VALUES (@devolucion1, NULL, 'Solicitada', '2024-01-15', 'datos de prueba', 'Teclado defectuoso');
+
+-- Reseñas: dos ya moderadas y una esperando en la cola
+INSERT INTO [Reseña] (idUsuario, idProducto, [puntuación], comentario, estado, moderador, fechaModeracion)
 VALUES
-    (@cliente1, 'Envío', 'Calle 1 #123'),
-    (@cliente1, 'Facturación', 'Calle 1 #123'),
-    (@cliente2, 'Envío', 'Av. Central 456'),
-    (@cliente3, 'Envío', 'Boulevard Norte 789');
+    (@cliente1, @prodCamisa, 5, 'Muy cómoda', 'Aprobada', 'datos de prueba', SYSUTCDATETIME()),
+    (@cliente2, @prodLaptop, 3, 'Buena pero con ruido', 'Aprobada', 'datos de prueba', SYSUTCDATETIME()),
+    (@cliente3, @prodCamisa, 4, 'Color agradable', 'Pendiente', NULL, NULL);
+
+-- Calificacion de cada producto a partir de las reseñas aprobadas
+EXEC RecalcularCalificacion;
+
@@ -0,0 +1,2 @@
+-- Listar almacenes por prioridad de despacho
+SELECT * FROM Almacen ORDER BY prioridad, idAlmacen;
@@ -0,0 +1,2 @@
+-- Obtener almacen por ID
+SELECT * FROM Almacen WHERE idAlmacen = @id;
@@ -0,0 +1,22 @@
+-- Carritos de clientes con correo, con lineas y sin cambios desde @idleSince,
+-- que todavia no recibieron recordatorio por este periodo de inactividad ni
+-- otro desde @remindedSince
+SELECT c.idCarrito, c.idUsuario, cl.nombre, cl.correo, c.fechaActualizacion,
+    lineas.unidades, lineas.importe
+FROM Carrito c
+INNER JOIN Clientes cl ON cl.idUsuario = c.idUsuario
+CROSS APPLY (
+    SELECT SUM(d.cantidad) AS unidades, CAST(SUM(d.cantidad * s.precio) AS DECIMAL(10,2)) AS importe
+    FROM CarritoDetalle d
+    INNER JOIN SKU s ON s.idSKU = d.idSKU
+    WHERE d.idCarrito = c.idCarrito
+) lineas
+WHERE c.fechaActualizacion < @idleSince
+  AND lineas.unidades > 0
+  AND cl.correo IS NOT NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM CarritoRecordatorio r
+    WHERE r.idCarrito = c.idCarrito
+      AND (r.fechaCarrito = c.fechaActualizacion OR r.fechaEnvio >= @remindedSince)
+  )
+ORDER BY c.fechaActualizacion;
@@ -0,0 +1,13 @@
+-- Lineas de un carrito con el precio y stock actuales de su SKU. Si el SKU se
+-- borro (idSKU NULL) la linea sale con los datos del SKU en NULL.
+SELECT cd.idDetalle, cd.idSKU, s.codigo,
+    (SELECT STRING_AGG(a.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY a.orden, a.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo a ON a.idAtributo = v.idAtributo
+     WHERE v.idSKU = s.idSKU) AS variante,
+    p.descripcion, cd.cantidad, s.stock, s.precio
+FROM CarritoDetalle cd
+LEFT JOIN SKU s ON s.idSKU = cd.idSKU
+LEFT JOIN Producto p ON p.idProducto = s.idProducto
+WHERE cd.idCarrito = @cartId
+ORDER BY cd.idDetalle;
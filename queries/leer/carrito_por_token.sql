@@ -0,0 +1,2 @@
+-- Obtener el carrito de invitado con ese token
+SELECT * FROM Carrito WHERE token = @token;
@@ -0,0 +1,2 @@
+-- Obtener el carrito de un usuario (a lo sumo uno por la restriccion UNIQUE)
+SELECT * FROM Carrito WHERE idUsuario = @userId;
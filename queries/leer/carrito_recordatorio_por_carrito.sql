@@ -0,0 +1,4 @@
+-- Recordatorios enviados por un carrito, los mas nuevos primero
+SELECT * FROM CarritoRecordatorio
+WHERE idCarrito = @cartId
+ORDER BY fechaEnvio DESC;
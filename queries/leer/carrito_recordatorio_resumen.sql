@@ -0,0 +1,10 @@
+-- Recordatorios enviados entre @from y @to (UTC) y cuantos terminaron en
+-- pedido, con el valor recordado y el total de los pedidos recuperados
+SELECT @from AS desde, @to AS hasta,
+    COUNT(*) AS enviados,
+    COUNT(r.idPedido) AS recuperados,
+    ISNULL(SUM(r.importe), 0) AS importeRecordado,
+    ISNULL(SUM(p.total), 0) AS importeRecuperado
+FROM CarritoRecordatorio r
+LEFT JOIN Pedido p ON p.idPedido = r.idPedido
+WHERE r.fechaEnvio >= @from AND r.fechaEnvio < @to;
@@ -0,0 +1,13 @@
+-- Ruta de una categoria desde la raiz (migas de pan: Ropa > Hombre > Camisas)
+WITH ruta AS (
+    SELECT idCategoria, nombre, idPadre, 0 AS nivel
+    FROM Categoria
+    WHERE idCategoria = @id
+    UNION ALL
+    SELECT c.idCategoria, c.nombre, c.idPadre, r.nivel + 1
+    FROM Categoria c
+    INNER JOIN ruta r ON c.idCategoria = r.idPadre
+)
+SELECT idCategoria, nombre, idPadre
+FROM ruta
+ORDER BY nivel DESC;
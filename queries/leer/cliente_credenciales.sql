@@ -0,0 +1,13 @@
+-- Datos para verificar el inicio de sesion por correo (@byEmail = 1) o
+-- telefono. El hash del intento se calcula igual que al guardar la contraseña
+-- (añadir/cliente.sql) y la comparacion se hace en la aplicacion. Siempre
+-- devuelve una fila: sin cuenta el id es 0 y el hash se calcula con una sal
+-- vacia, para que la respuesta tarde lo mismo exista o no.
+SELECT ISNULL(c.idUsuario, 0) AS idUsuario,
+    ISNULL(c.passwordHash, CAST(0x00 AS BINARY(32))) AS passwordHash,
+    HASHBYTES('SHA2_256', ISNULL(c.passwordSalt, CAST(0x00 AS BINARY(32))) + CONVERT(VARBINARY(MAX), @password)) AS intento,
+    c.bloqueadoHasta
+FROM (SELECT 1 AS fila) f
+LEFT JOIN Clientes c
+    ON (@byEmail = 1 AND c.correo = @login)
+    OR (@byEmail = 0 AND c.telefono = @login);
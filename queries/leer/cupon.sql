@@ -0,0 +1,2 @@
+-- Listar cupones
+SELECT * FROM Cupon;
@@ -0,0 +1,2 @@
+-- Obtener cupon por codigo
+SELECT * FROM Cupon WHERE codigo = @code;
@@ -0,0 +1,2 @@
+-- Obtener cupon por ID
+SELECT * FROM Cupon WHERE idCupon = @id;
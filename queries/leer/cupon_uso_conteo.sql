@@ -0,0 +1,7 @@
+-- Usos de un cupon en total y por un cliente; los pedidos cancelados no cuentan
+SELECT
+    COUNT(*) AS total,
+    ISNULL(SUM(CASE WHEN u.idUsuario = @userId THEN 1 ELSE 0 END), 0) AS cliente
+FROM CuponUso u
+INNER JOIN Pedido p ON p.idPedido = u.idPedido
+WHERE u.idCupon = @couponId AND p.estado <> 'Cancelado';
@@ -0,0 +1,4 @@
+-- Historial de canjes de un cupon
+SELECT * FROM CuponUso
+WHERE idCupon = @couponId
+ORDER BY fecha DESC;
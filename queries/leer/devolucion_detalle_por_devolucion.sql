@@ -0,0 +1,2 @@
+-- Lineas de una devolucion
+SELECT * FROM DevolucionDetalle WHERE idDevolucion = @returnId;
@@ -0,0 +1,2 @@
+-- Historial de estados de una devolucion, del mas antiguo al mas reciente
+SELECT * FROM DevolucionHistorial WHERE idDevolucion = @returnId ORDER BY fecha, idHistorial;
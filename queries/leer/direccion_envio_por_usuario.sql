@@ -0,0 +1,5 @@
+-- Primera direccion de envio de un cliente, para estimar impuestos
+SELECT TOP 1 *
+FROM Direccion
+WHERE idUsuario = @userId AND tipo = 'Envío'
+ORDER BY idDirección;
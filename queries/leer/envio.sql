@@ -0,0 +1,2 @@
+-- Listar envios
+SELECT * FROM Envio;
@@ -0,0 +1,2 @@
+-- Obtener envio por ID
+SELECT * FROM Envio WHERE idEnvio = @id;
@@ -0,0 +1,2 @@
+-- Envios de un pedido
+SELECT * FROM Envio WHERE idPedido = @orderId;
@@ -0,0 +1,2 @@
+-- Listar facturas por serie y numero
+SELECT * FROM Factura ORDER BY serie, numero;
@@ -0,0 +1,2 @@
+-- Lineas de una factura
+SELECT * FROM FacturaDetalle WHERE idFactura = @invoiceId ORDER BY idDetalle;
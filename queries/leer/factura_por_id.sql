@@ -0,0 +1,2 @@
+-- Obtener factura por ID
+SELECT * FROM Factura WHERE idFactura = @id;
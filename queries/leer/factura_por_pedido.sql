@@ -0,0 +1,2 @@
+-- Obtener la factura de un pedido
+SELECT * FROM Factura WHERE idPedido = @orderId;
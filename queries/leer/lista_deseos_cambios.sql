@@ -0,0 +1,17 @@
+-- Items cuyo precio o stock actual difiere del ultimo con que se aviso, con
+-- los datos del cliente para notificarle. La aplicacion decide si el cambio
+-- merece aviso y despues mueve la base (editar/lista_deseos_item_base.sql).
+SELECT i.idItem, l.idLista, l.idUsuario, c.nombre, c.correo, i.idProducto, i.idSKU, p.descripcion,
+    i.ultimoPrecio, actual.precio, i.ultimoStock, actual.stock
+FROM ListaDeseosItem i
+INNER JOIN ListaDeseos l ON l.idLista = i.idLista
+INNER JOIN Clientes c ON c.idUsuario = l.idUsuario
+INNER JOIN Producto p ON p.idProducto = i.idProducto
+CROSS APPLY (
+    SELECT MIN(k.precio) AS precio, ISNULL(SUM(k.stock), 0) AS stock
+    FROM SKU k
+    WHERE k.idProducto = i.idProducto AND (i.idSKU IS NULL OR k.idSKU = i.idSKU)
+) actual
+WHERE ISNULL(actual.precio, -1) <> ISNULL(i.ultimoPrecio, -1)
+   OR actual.stock <> i.ultimoStock
+ORDER BY i.idItem;
@@ -0,0 +1,18 @@
+-- Items de una lista con su precio y stock actuales. Para un producto sin SKU
+-- puntual se muestra el precio mas bajo y el stock de todas sus variantes.
+SELECT i.idItem, i.idLista, i.idProducto, i.idSKU, i.fechaAgregado, p.descripcion, s.codigo,
+    (SELECT STRING_AGG(a.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY a.orden, a.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo a ON a.idAtributo = v.idAtributo
+     WHERE v.idSKU = i.idSKU) AS variante,
+    actual.precio, actual.stock
+FROM ListaDeseosItem i
+INNER JOIN Producto p ON p.idProducto = i.idProducto
+LEFT JOIN SKU s ON s.idSKU = i.idSKU
+CROSS APPLY (
+    SELECT MIN(k.precio) AS precio, ISNULL(SUM(k.stock), 0) AS stock
+    FROM SKU k
+    WHERE k.idProducto = i.idProducto AND (i.idSKU IS NULL OR k.idSKU = i.idSKU)
+) actual
+WHERE i.idLista = @listId
+ORDER BY i.fechaAgregado DESC, i.idItem DESC;
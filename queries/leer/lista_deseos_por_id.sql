@@ -0,0 +1,4 @@
+-- Obtener lista de deseos por ID
+SELECT idLista, idUsuario, nombre, fechaCreacion
+FROM ListaDeseos
+WHERE idLista = @id;
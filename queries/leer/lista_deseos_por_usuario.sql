@@ -0,0 +1,5 @@
+-- Listas de deseos de un cliente
+SELECT idLista, idUsuario, nombre, fechaCreacion
+FROM ListaDeseos
+WHERE idUsuario = @userId
+ORDER BY nombre;
@@ -0,0 +1,2 @@
+-- Listar metodos de envio
+SELECT * FROM MetodoEnvio;
@@ -0,0 +1,2 @@
+-- Obtener metodo de envio por ID
+SELECT * FROM MetodoEnvio WHERE idMetodoEnvio = @id;
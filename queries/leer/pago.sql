@@ -0,0 +1,2 @@
+-- Listar pagos, los mas recientes primero
+SELECT * FROM Pago ORDER BY idPago DESC;
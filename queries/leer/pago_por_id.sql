@@ -0,0 +1,2 @@
+-- Obtener pago por ID
+SELECT * FROM Pago WHERE idPago = @id;
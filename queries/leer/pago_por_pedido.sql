@@ -0,0 +1,2 @@
+-- Pagos de un pedido en orden de creacion
+SELECT * FROM Pago WHERE idPedido = @orderId ORDER BY idPago;
@@ -1,2 +1,4 @@
--- Listar pedidos
-SELECT * FROM Pedido;
+-- Listar pedidos con el estado de su ultimo pago
+SELECT p.*,
+    (SELECT TOP 1 pg.estado FROM Pago pg WHERE pg.idPedido = p.idPedido ORDER BY pg.idPago DESC) AS estadoPago
+FROM Pedido p;
@@ -0,0 +1,18 @@
+-- Lineas de un pedido con la cantidad ya devuelta (sin contar devoluciones rechazadas).
+SELECT pd.idDetalle, pd.idSKU, pd.cantidad, pd.precioUnitario,
+    ISNULL((
+        SELECT SUM(dd.cantidad)
+        FROM DevolucionDetalle dd
+        INNER JOIN Devolucion d ON d.idDevolucion = dd.idDevolucion
+        WHERE dd.idPedidoDetalle = pd.idDetalle
+          AND d.estado <> 'Rechazada'
+    ), 0) AS devuelto,
+    s.codigo,
+    (SELECT STRING_AGG(pa.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY pa.orden, pa.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo pa ON pa.idAtributo = v.idAtributo
+     WHERE v.idSKU = pd.idSKU) AS variante
+FROM PedidoDetalle pd
+INNER JOIN SKU s ON s.idSKU = pd.idSKU
+WHERE pd.idPedido = @orderId
+ORDER BY pd.idDetalle;
@@ -0,0 +1,9 @@
+-- Lineas de un pedido, con el codigo y la variante de cada SKU
+SELECT pd.idDetalle, pd.idPedido, pd.idSKU, pd.cantidad, pd.precioUnitario, s.codigo,
+    (SELECT STRING_AGG(pa.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY pa.orden, pa.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo pa ON pa.idAtributo = v.idAtributo
+     WHERE v.idSKU = pd.idSKU) AS variante
+FROM PedidoDetalle pd
+INNER JOIN SKU s ON s.idSKU = pd.idSKU
+WHERE pd.idPedido = @orderId;
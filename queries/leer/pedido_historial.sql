@@ -0,0 +1,2 @@
+-- Historial de estados de un pedido, del mas antiguo al mas reciente
+SELECT * FROM PedidoHistorial WHERE idPedido = @orderId ORDER BY fecha, idHistorial;
@@ -0,0 +1,4 @@
+-- Desglose de impuestos de un pedido por tasa
+SELECT * FROM PedidoImpuesto
+WHERE idPedido = @orderId
+ORDER BY tasa DESC, nombre;
@@ -1,2 +1,5 @@
--- Obtener pedido por ID
-SELECT * FROM Pedido WHERE idPedido = @id;
+-- Obtener pedido por ID con el estado de su ultimo pago
+SELECT p.*,
+    (SELECT TOP 1 pg.estado FROM Pago pg WHERE pg.idPedido = p.idPedido ORDER BY pg.idPago DESC) AS estadoPago
+FROM Pedido p
+WHERE p.idPedido = @id;
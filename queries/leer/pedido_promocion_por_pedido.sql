@@ -0,0 +1,10 @@
+-- Promociones aplicadas a un pedido, con el codigo y la variante del SKU si
+-- todavia existe
+SELECT pp.idAplicacion, pp.idPedido, pp.idPromocion, pp.nombre, pp.idSKU, pp.descuento, pp.detalle, s.codigo,
+    (SELECT STRING_AGG(pa.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY pa.orden, pa.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo pa ON pa.idAtributo = v.idAtributo
+     WHERE v.idSKU = s.idSKU) AS variante
+FROM PedidoPromocion pp
+LEFT JOIN SKU s ON s.idSKU = pp.idSKU
+WHERE pp.idPedido = @orderId;
@@ -1,2 +1,5 @@
--- Listar productos
-SELECT * FROM Producto;
+-- Listar productos con su calificacion (solo reseñas aprobadas)
+SELECT p.idProducto, p.descripcion, p.idCategoria,
+    CAST(c.promedio AS FLOAT) AS calificacion, ISNULL(c.total, 0) AS resenas
+FROM Producto p
+LEFT JOIN ProductoCalificacion c ON c.idProducto = p.idProducto;
@@ -0,0 +1,2 @@
+-- Obtener atributo de producto por ID
+SELECT * FROM ProductoAtributo WHERE idAtributo = @id;
@@ -0,0 +1,2 @@
+-- Atributos de variante de un producto en el orden en que se muestran
+SELECT * FROM ProductoAtributo WHERE idProducto = @productId ORDER BY orden, idAtributo;
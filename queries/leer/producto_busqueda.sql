@@ -0,0 +1,34 @@
+-- Busqueda de productos: el texto se compara sin acentos ni mayusculas y cada
+-- termino de @terms (arreglo JSON, con % _ [ ya escapados) tiene que aparecer
+-- en la descripcion. Los filtros en NULL no se aplican. Solo salen productos
+-- con al menos un SKU dentro del rango de precio (y con stock si @inStock = 1);
+-- precio y stock se resumen sobre esos SKUs. El orden lo decide la aplicacion.
+WITH rama AS (
+    SELECT idCategoria FROM Categoria WHERE idCategoria = @categoryId
+    UNION ALL
+    SELECT c.idCategoria
+    FROM Categoria c
+    INNER JOIN rama r ON c.idPadre = r.idCategoria
+),
+precios AS (
+    SELECT s.idProducto, MIN(s.precio) AS precioMin, MAX(s.precio) AS precioMax, SUM(s.stock) AS stock
+    FROM SKU s
+    WHERE (@minPrice IS NULL OR s.precio >= @minPrice)
+        AND (@maxPrice IS NULL OR s.precio <= @maxPrice)
+        AND (@inStock = 0 OR s.stock > 0)
+    GROUP BY s.idProducto
+)
+SELECT p.idProducto, p.descripcion, p.idCategoria, pr.precioMin, pr.precioMax, pr.stock,
+    CAST(c.promedio AS FLOAT) AS promedio, ISNULL(c.total, 0) AS resenas,
+    -- La relevancia la calcula la aplicacion con los terminos sin escapar
+    CAST(0 AS FLOAT) AS relevancia
+FROM Producto p
+INNER JOIN precios pr ON pr.idProducto = p.idProducto
+LEFT JOIN ProductoCalificacion c ON c.idProducto = p.idProducto
+WHERE (@categoryId IS NULL OR p.idCategoria IN (SELECT idCategoria FROM rama))
+    AND (@minRating IS NULL OR c.promedio >= @minRating)
+    AND NOT EXISTS (
+        SELECT 1
+        FROM OPENJSON(@terms) t
+        WHERE p.descripcion COLLATE Latin1_General_CI_AI NOT LIKE '%' + t.value + '%' ESCAPE '\'
+    );
@@ -0,0 +1,13 @@
+-- Calificacion de un producto con la distribucion de estrellas
+SELECT p.idProducto,
+    ISNULL(c.total, 0) AS total,
+    CAST(c.promedio AS FLOAT) AS promedio,
+    ISNULL(c.estrellas1, 0) AS estrellas1,
+    ISNULL(c.estrellas2, 0) AS estrellas2,
+    ISNULL(c.estrellas3, 0) AS estrellas3,
+    ISNULL(c.estrellas4, 0) AS estrellas4,
+    ISNULL(c.estrellas5, 0) AS estrellas5,
+    c.fechaActualizacion
+FROM Producto p
+LEFT JOIN ProductoCalificacion c ON c.idProducto = p.idProducto
+WHERE p.idProducto = @productId;
@@ -0,0 +1,4 @@
+-- Claves de todos los archivos que alguna imagen usa (para limpiar huerfanos)
+SELECT clave FROM ProductoImagen
+UNION ALL
+SELECT claveMiniatura FROM ProductoImagen;
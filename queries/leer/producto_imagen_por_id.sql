@@ -0,0 +1,2 @@
+-- Obtener imagen de producto por ID
+SELECT * FROM ProductoImagen WHERE idImagen = @id;
@@ -0,0 +1,4 @@
+-- Imagenes de un producto: la principal primero y luego en orden de galeria
+SELECT * FROM ProductoImagen
+WHERE idProducto = @productId
+ORDER BY principal DESC, orden, idImagen;
@@ -0,0 +1,14 @@
+-- Productos de una categoria y de todas sus subcategorias
+WITH rama AS (
+    SELECT idCategoria FROM Categoria WHERE idCategoria = @categoryId
+    UNION ALL
+    SELECT c.idCategoria
+    FROM Categoria c
+    INNER JOIN rama r ON c.idPadre = r.idCategoria
+)
+SELECT p.idProducto, p.descripcion, p.idCategoria,
+    CAST(c.promedio AS FLOAT) AS calificacion, ISNULL(c.total, 0) AS resenas
+FROM Producto p
+INNER JOIN rama r ON r.idCategoria = p.idCategoria
+LEFT JOIN ProductoCalificacion c ON c.idProducto = p.idProducto
+ORDER BY p.idProducto;
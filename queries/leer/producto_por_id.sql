@@ -1,2 +1,6 @@
--- Obtener producto por ID
-SELECT * FROM Producto WHERE idProducto = @id;
+-- Obtener producto por ID con su calificacion
+SELECT p.idProducto, p.descripcion, p.idCategoria,
+    CAST(c.promedio AS FLOAT) AS calificacion, ISNULL(c.total, 0) AS resenas
+FROM Producto p
+LEFT JOIN ProductoCalificacion c ON c.idProducto = p.idProducto
+WHERE p.idProducto = @id;
@@ -0,0 +1,9 @@
+-- Listar promociones en orden de evaluacion, con el codigo y la variante del SKU
+SELECT p.*, s.codigo,
+    (SELECT STRING_AGG(pa.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY pa.orden, pa.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo pa ON pa.idAtributo = v.idAtributo
+     WHERE v.idSKU = p.idSKU) AS variante
+FROM Promocion p
+LEFT JOIN SKU s ON s.idSKU = p.idSKU
+ORDER BY p.prioridad, p.idPromocion;
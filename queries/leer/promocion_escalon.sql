@@ -0,0 +1,3 @@
+-- Escalones de todas las promociones
+SELECT * FROM PromocionEscalon
+ORDER BY idPromocion, montoMinimo;
@@ -0,0 +1,3 @@
+-- Listar reglas de impuesto
+SELECT * FROM ReglaImpuesto
+ORDER BY idCategoria, region;
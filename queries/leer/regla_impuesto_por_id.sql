@@ -0,0 +1,2 @@
+-- Obtener regla de impuesto por ID
+SELECT * FROM ReglaImpuesto WHERE idRegla = @id;
@@ -1,2 +1,5 @@
--- Listar reseñas de productos
-SELECT * FROM [Reseña];
+-- Listar reseñas de productos en cualquier estado
+SELECT idReseña, idUsuario, idProducto, [puntuación], comentario, fechaCreacion,
+    estado, motivoRechazo, moderador, fechaModeracion
+FROM [Reseña]
+ORDER BY idReseña;
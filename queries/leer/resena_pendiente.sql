@@ -0,0 +1,6 @@
+-- Cola de moderacion: reseñas pendientes, las mas antiguas primero
+SELECT idReseña, idUsuario, idProducto, [puntuación], comentario, fechaCreacion,
+    estado, motivoRechazo, moderador, fechaModeracion
+FROM [Reseña]
+WHERE estado = 'Pendiente'
+ORDER BY fechaCreacion, idReseña;
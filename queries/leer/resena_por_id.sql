@@ -1,2 +1,5 @@
 -- Obtener reseña por ID
-SELECT * FROM [Reseña] WHERE idReseña = @id;
+SELECT idReseña, idUsuario, idProducto, [puntuación], comentario, fechaCreacion,
+    estado, motivoRechazo, moderador, fechaModeracion
+FROM [Reseña]
+WHERE idReseña = @id;
@@ -0,0 +1,6 @@
+-- Reseñas publicas de un producto (solo aprobadas), las mas nuevas primero
+SELECT idReseña, idUsuario, idProducto, [puntuación], comentario, fechaCreacion,
+    estado, motivoRechazo, moderador, fechaModeracion
+FROM [Reseña]
+WHERE idProducto = @productId AND estado = 'Aprobada'
+ORDER BY fechaCreacion DESC, idReseña DESC;
@@ -1,2 +1,7 @@
--- Listar SKUs
-SELECT * FROM SKU;
+-- Listar SKUs con la etiqueta de su variante ("Talla: S, Color: Azul")
+SELECT s.*,
+    (SELECT STRING_AGG(a.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY a.orden, a.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo a ON a.idAtributo = v.idAtributo
+     WHERE v.idSKU = s.idSKU) AS variante
+FROM SKU s;
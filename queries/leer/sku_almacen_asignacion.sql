@@ -0,0 +1,9 @@
+-- De que almacenes saldrian @quantity unidades de un SKU, sin tocar el stock
+SET NOCOUNT ON;
+DECLARE @plan TABLE (idAlmacen INT PRIMARY KEY, prioridad INT NOT NULL, cantidad INT NOT NULL);
+INSERT INTO @plan (idAlmacen, prioridad, cantidad)
+EXEC AsignarStockSKU @skuId = @skuId, @quantity = @quantity, @descontar = 0;
+
+SELECT idAlmacen, cantidad
+FROM @plan
+ORDER BY prioridad, idAlmacen;
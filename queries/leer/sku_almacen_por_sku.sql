@@ -0,0 +1,11 @@
+-- Existencias de un SKU en cada almacen activo, en orden de prioridad
+SELECT sa.idSKU, sa.idAlmacen, a.nombre, a.prioridad, sa.stock, s.codigo,
+    (SELECT STRING_AGG(pa.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY pa.orden, pa.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo pa ON pa.idAtributo = v.idAtributo
+     WHERE v.idSKU = sa.idSKU) AS variante
+FROM SKUAlmacen sa
+INNER JOIN Almacen a ON a.idAlmacen = sa.idAlmacen
+INNER JOIN SKU s ON s.idSKU = sa.idSKU
+WHERE sa.idSKU = @skuId AND a.activo = 1
+ORDER BY a.prioridad, a.idAlmacen;
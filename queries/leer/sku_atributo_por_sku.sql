@@ -0,0 +1,6 @@
+-- Valores de atributos de un SKU con el nombre del atributo
+SELECT v.idSKU, v.idAtributo, a.nombre, v.valor
+FROM SKUAtributo v
+INNER JOIN ProductoAtributo a ON a.idAtributo = v.idAtributo
+WHERE v.idSKU = @skuId
+ORDER BY a.orden, a.idAtributo;
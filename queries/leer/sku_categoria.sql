@@ -0,0 +1,16 @@
+-- Categoria del producto de un SKU y sus ancestros, de la mas cercana a la raiz
+-- (sin filas si el producto no tiene categoria)
+WITH ancestros AS (
+    SELECT c.idCategoria, c.idPadre, 0 AS nivel
+    FROM SKU s
+    INNER JOIN Producto p ON p.idProducto = s.idProducto
+    INNER JOIN Categoria c ON c.idCategoria = p.idCategoria
+    WHERE s.idSKU = @skuId
+    UNION ALL
+    SELECT c.idCategoria, c.idPadre, a.nivel + 1
+    FROM Categoria c
+    INNER JOIN ancestros a ON c.idCategoria = a.idPadre
+)
+SELECT idCategoria
+FROM ancestros
+ORDER BY nivel;
@@ -1,2 +1,8 @@
--- Obtener SKU por ID
-SELECT * FROM SKU WHERE idSKU = @id;
+-- Obtener SKU por ID, con la etiqueta de su variante
+SELECT s.*,
+    (SELECT STRING_AGG(a.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY a.orden, a.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo a ON a.idAtributo = v.idAtributo
+     WHERE v.idSKU = s.idSKU) AS variante
+FROM SKU s
+WHERE s.idSKU = @id;
@@ -0,0 +1,10 @@
+-- Historial de precios de un SKU, los programados a futuro primero
+SELECT sp.idPrecio, sp.idSKU, sp.precio, sp.vigenteDesde, sp.vigenteHasta, sp.fechaCreacion, s.codigo,
+    (SELECT STRING_AGG(pa.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY pa.orden, pa.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo pa ON pa.idAtributo = v.idAtributo
+     WHERE v.idSKU = sp.idSKU) AS variante
+FROM SKUPrecio sp
+INNER JOIN SKU s ON s.idSKU = sp.idSKU
+WHERE sp.idSKU = @skuId
+ORDER BY sp.vigenteDesde DESC;
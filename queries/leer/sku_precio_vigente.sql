@@ -0,0 +1,11 @@
+-- Precio de un SKU vigente en el instante @at (UTC)
+SELECT sp.idPrecio, sp.idSKU, sp.precio, sp.vigenteDesde, sp.vigenteHasta, sp.fechaCreacion, s.codigo,
+    (SELECT STRING_AGG(pa.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY pa.orden, pa.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo pa ON pa.idAtributo = v.idAtributo
+     WHERE v.idSKU = sp.idSKU) AS variante
+FROM SKUPrecio sp
+INNER JOIN SKU s ON s.idSKU = sp.idSKU
+WHERE sp.idSKU = @skuId
+  AND sp.vigenteDesde <= @at
+  AND (sp.vigenteHasta IS NULL OR sp.vigenteHasta > @at);
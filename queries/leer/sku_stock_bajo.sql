@@ -0,0 +1,10 @@
+-- SKUs en o por debajo de su punto de reorden, con la descripcion del producto
+SELECT s.idSKU, s.idProducto, p.descripcion, s.stock, s.puntoReorden, s.cantidadReorden, s.codigo,
+    (SELECT STRING_AGG(a.nombre + ': ' + v.valor, ', ') WITHIN GROUP (ORDER BY a.orden, a.idAtributo)
+     FROM SKUAtributo v
+     INNER JOIN ProductoAtributo a ON a.idAtributo = v.idAtributo
+     WHERE v.idSKU = s.idSKU) AS variante
+FROM SKU s
+INNER JOIN Producto p ON p.idProducto = s.idProducto
+WHERE s.puntoReorden > 0 AND s.stock <= s.puntoReorden
+ORDER BY s.stock - s.puntoReorden, s.idSKU;
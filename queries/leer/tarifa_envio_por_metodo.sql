@@ -0,0 +1,4 @@
+-- Tarifas de un metodo de envio
+SELECT * FROM TarifaEnvio
+WHERE idMetodoEnvio = @methodId
+ORDER BY idZona, pesoMin, montoMin;
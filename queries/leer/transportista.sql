@@ -0,0 +1,2 @@
+-- Listar transportistas
+SELECT * FROM Transportista;
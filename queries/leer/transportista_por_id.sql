@@ -0,0 +1,2 @@
+-- Obtener transportista por ID
+SELECT * FROM Transportista WHERE idTransportista = @id;
@@ -0,0 +1,2 @@
+-- Listar zonas de envio
+SELECT * FROM ZonaEnvio;
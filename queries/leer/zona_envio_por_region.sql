@@ -0,0 +1,2 @@
+-- Zona a la que pertenece una region
+SELECT * FROM ZonaEnvioRegion WHERE region = @region;
@@ -0,0 +1,3 @@
+-- Regiones asignadas a zonas
+SELECT * FROM ZonaEnvioRegion
+ORDER BY idZona, region;
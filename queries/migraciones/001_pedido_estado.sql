@@ -0,0 +1,57 @@
+/*
+  Migra Pedido.entregado (BIT) a Pedido.estado con historial.
+  Solo hace falta en bases creadas antes del cambio; es seguro correrla varias veces.
+  Las sentencias que usan columnas nuevas van en sp_executesql para que el lote compile.
+*/
+IF OBJECT_ID(N'PedidoHistorial') IS NULL
+    CREATE TABLE PedidoHistorial
+    (
+        idHistorial INT IDENTITY(1,1) PRIMARY KEY,
+        idPedido INT NOT NULL,
+        estadoAnterior VARCHAR(20),
+        estadoNuevo VARCHAR(20) NOT NULL,
+        fecha DATETIME2 NOT NULL DEFAULT SYSUTCDATETIME(),
+        actor VARCHAR(50) NOT NULL,
+
+        FOREIGN KEY (idPedido) REFERENCES Pedido(idPedido)
+            ON DELETE CASCADE
+    );
+
+IF COL_LENGTH(N'Pedido', N'entregado') IS NOT NULL
+BEGIN
+    SET XACT_ABORT ON;
+    BEGIN TRANSACTION;
+
+    IF COL_LENGTH(N'Pedido', N'estado') IS NULL
+        ALTER TABLE Pedido ADD estado VARCHAR(20) NOT NULL
+            CONSTRAINT DF_Pedido_estado DEFAULT 'Pendiente'
+            CONSTRAINT CK_Pedido_estado
+                CHECK (estado IN ('Pendiente','Pagado','Enviado','Entregado','Cancelado','Devuelto'));
+
+    -- Los entregados pasan directo a Entregado, el resto queda Pendiente
+    EXEC sp_executesql N'
+        UPDATE Pedido
+        SET estado = CASE WHEN entregado = 1 THEN ''Entregado'' ELSE ''Pendiente'' END;
+
+        INSERT INTO PedidoHistorial (idPedido, estadoAnterior, estadoNuevo, actor)
+        SELECT idPedido, NULL, estado, ''migracion''
+        FROM Pedido;';
+
+    -- El BIT tiene un DEFAULT con nombre generado, hay que soltarlo antes de la columna
+    DECLARE @defaultName SYSNAME;
+    SELECT @defaultName = dc.name
+    FROM sys.default_constraints dc
+    INNER JOIN sys.columns c
+        ON c.object_id = dc.parent_object_id AND c.column_id = dc.parent_column_id
+    WHERE dc.parent_object_id = OBJECT_ID(N'Pedido') AND c.name = N'entregado';
+
+    IF @defaultName IS NOT NULL
+    BEGIN
+        DECLARE @dropDefault NVARCHAR(300) = N'ALTER TABLE Pedido DROP CONSTRAINT ' + QUOTENAME(@defaultName);
+        EXEC sp_executesql @dropDefault;
+    END;
+
+    ALTER TABLE Pedido DROP COLUMN entregado;
+
+    COMMIT TRANSACTION;
+END;
@@ -0,0 +1,12 @@
+-- Eliminar almacen por ID; las filas en cero se limpian, si queda stock la FK
+-- lo impide y no se borra nada
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DELETE FROM SKUAlmacen
+WHERE idAlmacen = @id AND stock = 0;
+
+DELETE FROM Almacen
+WHERE idAlmacen = @id;
+
+COMMIT TRANSACTION;
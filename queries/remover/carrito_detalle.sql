@@ -1,3 +1,12 @@
 -- Eliminar item de carrito por ID
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = (SELECT idCarrito FROM CarritoDetalle WITH (UPDLOCK) WHERE idDetalle = @id);
+
 DELETE FROM CarritoDetalle
 WHERE idDetalle = @id;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,7 @@
+-- Borrar los carritos de invitado sin cambios desde @before (sus lineas se
+-- borran en cascada)
+SET NOCOUNT ON;
+DELETE FROM Carrito
+WHERE token IS NOT NULL AND fechaActualizacion < @before;
+
+SELECT @@ROWCOUNT AS eliminados;
@@ -0,0 +1,18 @@
+-- Quitar un SKU del carrito del usuario o del invitado con @token
+SET NOCOUNT ON;
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @cartId INT = (SELECT idCarrito FROM Carrito WITH (UPDLOCK) WHERE idUsuario = @userId OR token = @token);
+
+DELETE FROM CarritoDetalle
+WHERE idCarrito = @cartId AND idSKU = @skuId;
+
+DECLARE @eliminados INT = @@ROWCOUNT;
+UPDATE Carrito
+SET fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = @cartId AND @eliminados > 0;
+
+COMMIT TRANSACTION;
+
+SELECT @eliminados AS eliminados;
@@ -0,0 +1,3 @@
+-- Liberar un recordatorio reservado que no se pudo enviar, para reintentarlo
+DELETE FROM CarritoRecordatorio
+WHERE idRecordatorio = @id;
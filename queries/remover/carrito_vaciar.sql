@@ -0,0 +1,17 @@
+-- Vaciar el carrito del usuario o del invitado con @token: quita todas las
+-- lineas (tambien las de SKUs borrados) y el cupon. El carrito queda para la
+-- proxima compra.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @cartId INT = (SELECT idCarrito FROM Carrito WITH (UPDLOCK) WHERE idUsuario = @userId OR token = @token);
+
+DELETE FROM CarritoDetalle
+WHERE idCarrito = @cartId;
+
+UPDATE Carrito
+SET codigoCupon = NULL,
+    fechaActualizacion = SYSUTCDATETIME()
+WHERE idCarrito = @cartId;
+
+COMMIT TRANSACTION;
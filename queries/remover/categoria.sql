@@ -1,3 +1,15 @@
--- Eliminar categoria por ID
+-- Eliminar categoria por ID; sus subcategorias pasan a su padre y sus
+-- productos quedan sin categoria
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+UPDATE hijo
+SET idPadre = borrada.idPadre
+FROM Categoria hijo
+INNER JOIN Categoria borrada ON borrada.idCategoria = hijo.idPadre
+WHERE borrada.idCategoria = @id;
+
 DELETE FROM Categoria
 WHERE idCategoria = @id;
+
+COMMIT TRANSACTION;
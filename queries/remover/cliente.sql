@@ -1,3 +1,20 @@
--- Script para remover un cliente basado en su ID
+-- Script para remover un cliente basado en su ID. Sus reseñas se borran en
+-- cascada, asi que se recalcula la calificacion de los productos que reseño.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @productos TABLE (idProducto INT PRIMARY KEY);
+INSERT INTO @productos (idProducto)
+SELECT DISTINCT idProducto FROM [Reseña] WHERE idUsuario = @id;
+
 DELETE FROM Clientes
-WHERE idUsuario = @id
\ No newline at end of file
+WHERE idUsuario = @id;
+
+DECLARE @productId INT = (SELECT MIN(idProducto) FROM @productos);
+WHILE @productId IS NOT NULL
+BEGIN
+    EXEC RecalcularCalificacion @productId = @productId;
+    SET @productId = (SELECT MIN(idProducto) FROM @productos WHERE idProducto > @productId);
+END;
+
+COMMIT TRANSACTION;
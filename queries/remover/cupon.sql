@@ -0,0 +1,2 @@
+-- Eliminar cupon por ID; si ya se uso la FK lo impide (desactivarlo en su lugar)
+DELETE FROM Cupon WHERE idCupon = @id;
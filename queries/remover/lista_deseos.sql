@@ -0,0 +1,3 @@
+-- Eliminar lista de deseos (sus items se borran en cascada)
+DELETE FROM ListaDeseos
+WHERE idLista = @id;
@@ -0,0 +1,3 @@
+-- Quitar un item de una lista de deseos
+DELETE FROM ListaDeseosItem
+WHERE idItem = @id;
@@ -0,0 +1,2 @@
+-- Eliminar metodo de envio por ID (con sus tarifas); si ya se uso en pedidos la FK lo impide
+DELETE FROM MetodoEnvio WHERE idMetodoEnvio = @id;
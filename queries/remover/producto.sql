@@ -1,3 +1,18 @@
--- Eliminar producto por ID
+-- Eliminar producto por ID; los valores de sus atributos y los items de
+-- listas de deseos se borran primero porque SKUAtributo y ListaDeseosItem no
+-- tienen cascada desde ProductoAtributo y SKU
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DELETE FROM ListaDeseosItem
+WHERE idProducto = @id;
+
+DELETE v
+FROM SKUAtributo v
+INNER JOIN ProductoAtributo a ON a.idAtributo = v.idAtributo
+WHERE a.idProducto = @id;
+
 DELETE FROM Producto
 WHERE idProducto = @id;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,11 @@
+-- Eliminar atributo de producto junto con sus valores en los SKUs
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DELETE FROM SKUAtributo
+WHERE idAtributo = @id;
+
+DELETE FROM ProductoAtributo
+WHERE idAtributo = @id;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,21 @@
+-- Eliminar imagen por ID; si era la principal, la siguiente en la galeria pasa a serlo
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @productId INT, @wasPrimary BIT;
+SELECT @productId = idProducto, @wasPrimary = principal
+FROM ProductoImagen
+WHERE idImagen = @id;
+
+DELETE FROM ProductoImagen
+WHERE idImagen = @id;
+
+IF @wasPrimary = 1
+    UPDATE ProductoImagen
+    SET principal = 1
+    WHERE idImagen = (
+        SELECT TOP 1 idImagen FROM ProductoImagen
+        WHERE idProducto = @productId
+        ORDER BY orden, idImagen);
+
+COMMIT TRANSACTION;
@@ -0,0 +1,2 @@
+-- Eliminar promocion por ID (con sus escalones)
+DELETE FROM Promocion WHERE idPromocion = @id;
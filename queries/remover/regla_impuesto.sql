@@ -0,0 +1,2 @@
+-- Eliminar regla de impuesto (los pedidos guardan su propia copia)
+DELETE FROM ReglaImpuesto WHERE idRegla = @id;
@@ -1,3 +1,13 @@
--- Eliminar reseña por ID
+-- Eliminar reseña por ID y recalcular la calificacion de su producto
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @productId INT = (SELECT idProducto FROM [Reseña] WITH (UPDLOCK) WHERE idReseña = @id);
+
 DELETE FROM [Reseña]
 WHERE idReseña = @id;
+
+IF @productId IS NOT NULL
+    EXEC RecalcularCalificacion @productId = @productId;
+
+COMMIT TRANSACTION;
@@ -1,3 +1,21 @@
--- Eliminar SKU por ID
+-- Eliminar SKU por ID. En las listas de deseos el SKU pasa a ser un deseo del
+-- producto, salvo que la lista ya tenga el producto.
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DELETE i
+FROM ListaDeseosItem i
+WHERE i.idSKU = @id
+  AND EXISTS (
+      SELECT 1 FROM ListaDeseosItem p
+      WHERE p.idLista = i.idLista AND p.idProducto = i.idProducto AND p.idSKU IS NULL
+  );
+
+UPDATE ListaDeseosItem
+SET idSKU = NULL
+WHERE idSKU = @id;
+
 DELETE FROM SKU
 WHERE idSKU = @id;
+
+COMMIT TRANSACTION;
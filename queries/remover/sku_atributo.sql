@@ -0,0 +1,3 @@
+-- Quitar el valor de un atributo de un SKU
+DELETE FROM SKUAtributo
+WHERE idSKU = @skuId AND idAtributo = @attributeId;
@@ -0,0 +1,29 @@
+-- Cancelar un cambio de precio programado: el periodo anterior se extiende
+-- hasta donde terminaba el cancelado
+SET XACT_ABORT ON;
+BEGIN TRANSACTION;
+
+DECLARE @skuId INT, @start DATETIME2, @end DATETIME2;
+SELECT @skuId = idSKU, @start = vigenteDesde, @end = vigenteHasta
+FROM SKUPrecio WITH (UPDLOCK, HOLDLOCK)
+WHERE idPrecio = @id;
+
+IF @skuId IS NULL
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50092, 'el precio no existe', 1;
+END;
+
+IF @start <= SYSUTCDATETIME()
+BEGIN
+    ROLLBACK TRANSACTION;
+    THROW 50093, 'solo se pueden cancelar precios que todavia no rigen', 1;
+END;
+
+DELETE FROM SKUPrecio WHERE idPrecio = @id;
+
+UPDATE SKUPrecio
+SET vigenteHasta = @end
+WHERE idSKU = @skuId AND vigenteHasta = @start;
+
+COMMIT TRANSACTION;
@@ -0,0 +1,2 @@
+-- Eliminar tarifa de envio por ID
+DELETE FROM TarifaEnvio WHERE idTarifa = @id;
@@ -0,0 +1,2 @@
+-- Eliminar transportista por ID; si tiene metodos de envio la FK lo impide
+DELETE FROM Transportista WHERE idTransportista = @id;
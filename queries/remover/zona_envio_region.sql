@@ -0,0 +1,2 @@
+-- Quitar una region de su zona
+DELETE FROM ZonaEnvioRegion WHERE region = @region;